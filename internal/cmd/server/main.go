@@ -7,7 +7,7 @@ import (
 )
 
 func main() {
-	if err := cmd.RunServer(); err != nil {
+	if err := cmd.Run(os.Args[1:], os.Stdout); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
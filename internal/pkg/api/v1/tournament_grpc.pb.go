@@ -1,4 +1,8 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: tournament.proto
 
 package v1
 
@@ -7,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -14,12 +19,44 @@ import (
 // Requires gRPC-Go v1.32.0 or later.
 const _ = grpc.SupportPackageIsVersion7
 
+const (
+	Tournament_CreateUser_FullMethodName       = "/main.Tournament/CreateUser"
+	Tournament_GetUser_FullMethodName          = "/main.Tournament/GetUser"
+	Tournament_DeleteUser_FullMethodName       = "/main.Tournament/DeleteUser"
+	Tournament_TakeUserBalance_FullMethodName  = "/main.Tournament/TakeUserBalance"
+	Tournament_FundUserBalance_FullMethodName  = "/main.Tournament/FundUserBalance"
+	Tournament_SetUserRole_FullMethodName      = "/main.Tournament/SetUserRole"
+	Tournament_CreateTournament_FullMethodName = "/main.Tournament/CreateTournament"
+	Tournament_GetTournament_FullMethodName    = "/main.Tournament/GetTournament"
+	Tournament_ListTournaments_FullMethodName  = "/main.Tournament/ListTournaments"
+	Tournament_JoinTournament_FullMethodName   = "/main.Tournament/JoinTournament"
+	Tournament_LeaveTournament_FullMethodName  = "/main.Tournament/LeaveTournament"
+	Tournament_StartTournament_FullMethodName  = "/main.Tournament/StartTournament"
+	Tournament_FinishTournament_FullMethodName = "/main.Tournament/FinishTournament"
+	Tournament_CancelTournament_FullMethodName = "/main.Tournament/CancelTournament"
+	Tournament_WatchTournament_FullMethodName  = "/main.Tournament/WatchTournament"
+)
+
 // TournamentClient is the client API for Tournament service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type TournamentClient interface {
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
-	UserList(ctx context.Context, in *GetUserListRequest, opts ...grpc.CallOption) (*GetUserListResponse, error)
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	TakeUserBalance(ctx context.Context, in *UserPointsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	FundUserBalance(ctx context.Context, in *UserPointsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CreateTournament(ctx context.Context, in *CreateTournamentRequest, opts ...grpc.CallOption) (*CreateTournamentResponse, error)
+	GetTournament(ctx context.Context, in *GetTournamentRequest, opts ...grpc.CallOption) (*TournamentInfo, error)
+	ListTournaments(ctx context.Context, in *ListTournamentsRequest, opts ...grpc.CallOption) (*ListTournamentsResponse, error)
+	JoinTournament(ctx context.Context, in *TournamentMemberRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	LeaveTournament(ctx context.Context, in *TournamentMemberRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	StartTournament(ctx context.Context, in *StartTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	FinishTournament(ctx context.Context, in *FinishTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CancelTournament(ctx context.Context, in *CancelTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// WatchTournament is available over gRPC only.
+	WatchTournament(ctx context.Context, in *WatchTournamentRequest, opts ...grpc.CallOption) (Tournament_WatchTournamentClient, error)
 }
 
 type tournamentClient struct {
@@ -32,28 +69,182 @@ func NewTournamentClient(cc grpc.ClientConnInterface) TournamentClient {
 
 func (c *tournamentClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error) {
 	out := new(CreateUserResponse)
-	err := c.cc.Invoke(ctx, "/main.Tournament/CreateUser", in, out, opts...)
+	err := c.cc.Invoke(ctx, Tournament_CreateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, Tournament_GetUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_DeleteUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) TakeUserBalance(ctx context.Context, in *UserPointsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_TakeUserBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) FundUserBalance(ctx context.Context, in *UserPointsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_FundUserBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_SetUserRole_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) CreateTournament(ctx context.Context, in *CreateTournamentRequest, opts ...grpc.CallOption) (*CreateTournamentResponse, error) {
+	out := new(CreateTournamentResponse)
+	err := c.cc.Invoke(ctx, Tournament_CreateTournament_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) GetTournament(ctx context.Context, in *GetTournamentRequest, opts ...grpc.CallOption) (*TournamentInfo, error) {
+	out := new(TournamentInfo)
+	err := c.cc.Invoke(ctx, Tournament_GetTournament_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) ListTournaments(ctx context.Context, in *ListTournamentsRequest, opts ...grpc.CallOption) (*ListTournamentsResponse, error) {
+	out := new(ListTournamentsResponse)
+	err := c.cc.Invoke(ctx, Tournament_ListTournaments_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) JoinTournament(ctx context.Context, in *TournamentMemberRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_JoinTournament_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) LeaveTournament(ctx context.Context, in *TournamentMemberRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_LeaveTournament_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) StartTournament(ctx context.Context, in *StartTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_StartTournament_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tournamentClient) FinishTournament(ctx context.Context, in *FinishTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_FinishTournament_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *tournamentClient) UserList(ctx context.Context, in *GetUserListRequest, opts ...grpc.CallOption) (*GetUserListResponse, error) {
-	out := new(GetUserListResponse)
-	err := c.cc.Invoke(ctx, "/main.Tournament/UserList", in, out, opts...)
+func (c *tournamentClient) CancelTournament(ctx context.Context, in *CancelTournamentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Tournament_CancelTournament_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
+func (c *tournamentClient) WatchTournament(ctx context.Context, in *WatchTournamentRequest, opts ...grpc.CallOption) (Tournament_WatchTournamentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Tournament_ServiceDesc.Streams[0], Tournament_WatchTournament_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &tournamentWatchTournamentClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Tournament_WatchTournamentClient interface {
+	Recv() (*TournamentEvent, error)
+	grpc.ClientStream
+}
+
+type tournamentWatchTournamentClient struct {
+	grpc.ClientStream
+}
+
+func (x *tournamentWatchTournamentClient) Recv() (*TournamentEvent, error) {
+	m := new(TournamentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // TournamentServer is the server API for Tournament service.
 // All implementations must embed UnimplementedTournamentServer
 // for forward compatibility
 type TournamentServer interface {
 	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
-	UserList(context.Context, *GetUserListRequest) (*GetUserListResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
+	TakeUserBalance(context.Context, *UserPointsRequest) (*emptypb.Empty, error)
+	FundUserBalance(context.Context, *UserPointsRequest) (*emptypb.Empty, error)
+	SetUserRole(context.Context, *SetUserRoleRequest) (*emptypb.Empty, error)
+	CreateTournament(context.Context, *CreateTournamentRequest) (*CreateTournamentResponse, error)
+	GetTournament(context.Context, *GetTournamentRequest) (*TournamentInfo, error)
+	ListTournaments(context.Context, *ListTournamentsRequest) (*ListTournamentsResponse, error)
+	JoinTournament(context.Context, *TournamentMemberRequest) (*emptypb.Empty, error)
+	LeaveTournament(context.Context, *TournamentMemberRequest) (*emptypb.Empty, error)
+	StartTournament(context.Context, *StartTournamentRequest) (*emptypb.Empty, error)
+	FinishTournament(context.Context, *FinishTournamentRequest) (*emptypb.Empty, error)
+	CancelTournament(context.Context, *CancelTournamentRequest) (*emptypb.Empty, error)
+	// WatchTournament is available over gRPC only.
+	WatchTournament(*WatchTournamentRequest, Tournament_WatchTournamentServer) error
 	mustEmbedUnimplementedTournamentServer()
 }
 
@@ -64,8 +255,47 @@ type UnimplementedTournamentServer struct {
 func (UnimplementedTournamentServer) CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
 }
-func (UnimplementedTournamentServer) UserList(context.Context, *GetUserListRequest) (*GetUserListResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UserList not implemented")
+func (UnimplementedTournamentServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedTournamentServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedTournamentServer) TakeUserBalance(context.Context, *UserPointsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TakeUserBalance not implemented")
+}
+func (UnimplementedTournamentServer) FundUserBalance(context.Context, *UserPointsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FundUserBalance not implemented")
+}
+func (UnimplementedTournamentServer) SetUserRole(context.Context, *SetUserRoleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserRole not implemented")
+}
+func (UnimplementedTournamentServer) CreateTournament(context.Context, *CreateTournamentRequest) (*CreateTournamentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTournament not implemented")
+}
+func (UnimplementedTournamentServer) GetTournament(context.Context, *GetTournamentRequest) (*TournamentInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTournament not implemented")
+}
+func (UnimplementedTournamentServer) ListTournaments(context.Context, *ListTournamentsRequest) (*ListTournamentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTournaments not implemented")
+}
+func (UnimplementedTournamentServer) JoinTournament(context.Context, *TournamentMemberRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinTournament not implemented")
+}
+func (UnimplementedTournamentServer) LeaveTournament(context.Context, *TournamentMemberRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaveTournament not implemented")
+}
+func (UnimplementedTournamentServer) StartTournament(context.Context, *StartTournamentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartTournament not implemented")
+}
+func (UnimplementedTournamentServer) FinishTournament(context.Context, *FinishTournamentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishTournament not implemented")
+}
+func (UnimplementedTournamentServer) CancelTournament(context.Context, *CancelTournamentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelTournament not implemented")
+}
+func (UnimplementedTournamentServer) WatchTournament(*WatchTournamentRequest, Tournament_WatchTournamentServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTournament not implemented")
 }
 func (UnimplementedTournamentServer) mustEmbedUnimplementedTournamentServer() {}
 
@@ -90,7 +320,7 @@ func _Tournament_CreateUser_Handler(srv interface{}, ctx context.Context, dec fu
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/main.Tournament/CreateUser",
+		FullMethod: Tournament_CreateUser_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(TournamentServer).CreateUser(ctx, req.(*CreateUserRequest))
@@ -98,24 +328,261 @@ func _Tournament_CreateUser_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Tournament_UserList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUserListRequest)
+func _Tournament_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_TakeUserBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserPointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).TakeUserBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_TakeUserBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).TakeUserBalance(ctx, req.(*UserPointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_FundUserBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserPointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).FundUserBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_FundUserBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).FundUserBalance(ctx, req.(*UserPointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_SetUserRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).SetUserRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_SetUserRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).SetUserRole(ctx, req.(*SetUserRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_CreateTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTournamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).CreateTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_CreateTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).CreateTournament(ctx, req.(*CreateTournamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_GetTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTournamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).GetTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_GetTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).GetTournament(ctx, req.(*GetTournamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_ListTournaments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTournamentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).ListTournaments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_ListTournaments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).ListTournaments(ctx, req.(*ListTournamentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_JoinTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TournamentMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).JoinTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_JoinTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).JoinTournament(ctx, req.(*TournamentMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_LeaveTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TournamentMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).LeaveTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_LeaveTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).LeaveTournament(ctx, req.(*TournamentMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_StartTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartTournamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).StartTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_StartTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).StartTournament(ctx, req.(*StartTournamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_FinishTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishTournamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TournamentServer).FinishTournament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tournament_FinishTournament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TournamentServer).FinishTournament(ctx, req.(*FinishTournamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tournament_CancelTournament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelTournamentRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TournamentServer).UserList(ctx, in)
+		return srv.(TournamentServer).CancelTournament(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/main.Tournament/UserList",
+		FullMethod: Tournament_CancelTournament_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TournamentServer).UserList(ctx, req.(*GetUserListRequest))
+		return srv.(TournamentServer).CancelTournament(ctx, req.(*CancelTournamentRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Tournament_WatchTournament_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTournamentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TournamentServer).WatchTournament(m, &tournamentWatchTournamentServer{stream})
+}
+
+type Tournament_WatchTournamentServer interface {
+	Send(*TournamentEvent) error
+	grpc.ServerStream
+}
+
+type tournamentWatchTournamentServer struct {
+	grpc.ServerStream
+}
+
+func (x *tournamentWatchTournamentServer) Send(m *TournamentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Tournament_ServiceDesc is the grpc.ServiceDesc for Tournament service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -128,10 +595,64 @@ var Tournament_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Tournament_CreateUser_Handler,
 		},
 		{
-			MethodName: "UserList",
-			Handler:    _Tournament_UserList_Handler,
+			MethodName: "GetUser",
+			Handler:    _Tournament_GetUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _Tournament_DeleteUser_Handler,
+		},
+		{
+			MethodName: "TakeUserBalance",
+			Handler:    _Tournament_TakeUserBalance_Handler,
+		},
+		{
+			MethodName: "FundUserBalance",
+			Handler:    _Tournament_FundUserBalance_Handler,
+		},
+		{
+			MethodName: "SetUserRole",
+			Handler:    _Tournament_SetUserRole_Handler,
+		},
+		{
+			MethodName: "CreateTournament",
+			Handler:    _Tournament_CreateTournament_Handler,
+		},
+		{
+			MethodName: "GetTournament",
+			Handler:    _Tournament_GetTournament_Handler,
+		},
+		{
+			MethodName: "ListTournaments",
+			Handler:    _Tournament_ListTournaments_Handler,
+		},
+		{
+			MethodName: "JoinTournament",
+			Handler:    _Tournament_JoinTournament_Handler,
+		},
+		{
+			MethodName: "LeaveTournament",
+			Handler:    _Tournament_LeaveTournament_Handler,
+		},
+		{
+			MethodName: "StartTournament",
+			Handler:    _Tournament_StartTournament_Handler,
+		},
+		{
+			MethodName: "FinishTournament",
+			Handler:    _Tournament_FinishTournament_Handler,
+		},
+		{
+			MethodName: "CancelTournament",
+			Handler:    _Tournament_CancelTournament_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTournament",
+			Handler:       _Tournament_WatchTournament_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "tournament.proto",
 }
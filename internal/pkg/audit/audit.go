@@ -0,0 +1,119 @@
+// Package audit records who performed privileged mutations of users and
+// tournaments, what they looked like before and after and where request
+// came from.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// Store is part of storage.DB audit log is read from.
+type Store interface {
+	ListAuditEntries(ctx context.Context, f storage.AuditFilter) ([]storage.AuditEntry, error)
+}
+
+var _ storage.Service = (*RecordingService)(nil)
+
+// RecordingService is storage.Service decorator which passes actor and
+// request details to privileged mutations, so storage records audit entry
+// in the same transaction as mutation. Other methods are passed through.
+type RecordingService struct {
+	storage.Service
+}
+
+// Record wraps db so that its privileged mutations are recorded in audit log.
+func Record(db storage.Service) *RecordingService {
+	return &RecordingService{Service: db}
+}
+
+// DeleteUser implements storage.Service.
+func (s *RecordingService) DeleteUser(ctx context.Context, id string, opts storage.DeleteUserOptions) error {
+	return s.Service.DeleteUser(withDetails(ctx), id, opts)
+}
+
+// TakeUserBalance implements storage.Service.
+func (s *RecordingService) TakeUserBalance(ctx context.Context, id string, points float64) error {
+	return s.Service.TakeUserBalance(withDetails(ctx), id, points)
+}
+
+// FundUserBalance implements storage.Service.
+func (s *RecordingService) FundUserBalance(ctx context.Context, id string, points float64) error {
+	return s.Service.FundUserBalance(withDetails(ctx), id, points)
+}
+
+// SetUserRole implements storage.Service.
+func (s *RecordingService) SetUserRole(ctx context.Context, id string, role storage.Role) error {
+	return s.Service.SetUserRole(withDetails(ctx), id, role)
+}
+
+// SetTournamentStatus implements storage.Service. Only start of tournament is recorded.
+func (s *RecordingService) SetTournamentStatus(ctx context.Context, tournamentID string,
+	status storage.TournamentStatus) error {
+	return s.Service.SetTournamentStatus(withDetails(ctx), tournamentID, status)
+}
+
+// FinishTournament implements storage.Service.
+func (s *RecordingService) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) error {
+	return s.Service.FinishTournament(withDetails(ctx), tournamentID, winnerUserID)
+}
+
+// DeleteTournament implements storage.Service. Deleting tournament cancels it.
+func (s *RecordingService) DeleteTournament(ctx context.Context, id string) error {
+	return s.Service.DeleteTournament(withDetails(ctx), id)
+}
+
+// withDetails returns copy of ctx carrying actor and request details of ctx for audit entry.
+func withDetails(ctx context.Context) context.Context {
+	var e storage.AuditEntry
+	if p, err := auth.FromContext(ctx); err == nil {
+		e.ActorID, e.ActorRole = p.UserID, p.Role
+	}
+	e.RequestID = logging.RequestID(ctx)
+	e.ClientIP = ClientIP(ctx)
+
+	return storage.WithAudit(ctx, e)
+}
+
+type clientIPKey struct{}
+
+// NewContext returns copy of ctx that carries IP of client.
+func NewContext(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, clientIP)
+}
+
+// ClientIP returns IP of HTTP client stored in ctx or address of gRPC peer.
+func ClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		return hostOf(p.Addr.String())
+	}
+
+	return ""
+}
+
+// Middleware stores IP of HTTP client in request context. REST gateway
+// calls service in-process, so there is no gRPC peer to take it from.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), hostOf(req.RemoteAddr))))
+	})
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// details returns gomock action which stores audit details of mutation context.
+func details(e *storage.AuditEntry, ok *bool) func(ctx context.Context, _ string, _ float64) error {
+	return func(ctx context.Context, _ string, _ float64) error {
+		*e, *ok = storage.AuditFromContext(ctx)
+		return nil
+	}
+}
+
+func TestRecordingService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID().Hex()
+	adminID := primitive.NewObjectID().Hex()
+	var e storage.AuditEntry
+	var ok bool
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Eq(userID), gomock.Eq(50.0)).Times(1).
+		DoAndReturn(details(&e, &ok))
+
+	db := Record(mock)
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserID: adminID, Role: storage.RoleAdmin})
+	ctx = logging.NewContext(ctx, "req-1")
+	ctx = NewContext(ctx, "10.0.0.1")
+	require := require.New(t)
+
+	require.NoError(db.FundUserBalance(ctx, userID, 50))
+	require.True(ok, "Privileged mutation should get audit details")
+	require.Equal(adminID, e.ActorID)
+	require.Equal(storage.RoleAdmin, e.ActorRole)
+	require.Equal("req-1", e.RequestID)
+	require.Equal("10.0.0.1", e.ClientIP)
+}
+
+func TestRecordingService_Unauthenticated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID().Hex()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).
+		DoAndReturn(func(ctx context.Context, _ string) error {
+			e, ok := storage.AuditFromContext(ctx)
+			require.True(t, ok, "Privileged mutation should get audit details")
+			require.Empty(t, e.ActorID, "Unauthenticated mutation should have no actor")
+			return errors.New("not found")
+		})
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).
+		DoAndReturn(func(ctx context.Context, _ string) (*storage.Tournament, error) {
+			_, ok := storage.AuditFromContext(ctx)
+			require.False(t, ok, "Other methods should be passed through")
+			return &storage.Tournament{}, nil
+		})
+
+	db := Record(mock)
+	require.Error(t, db.DeleteTournament(context.TODO(), tournamentID))
+	_, err := db.GetTournament(context.TODO(), tournamentID)
+	require.NoError(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	var clientIP string
+	handler := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		clientIP = ClientIP(req.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.7:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "192.0.2.7", clientIP)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+var (
+	// ErrUnauthenticated is returned when request has no authenticated principal.
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrPermissionDenied is returned when principal is not allowed to perform operation.
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Principal is an authenticated caller with his user id and role.
+type Principal struct {
+	UserID string
+	Role   storage.Role
+}
+
+// Authenticator resolves principal from provided access token.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// NewContext returns copy of ctx that carries provided principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns principal stored in ctx and ErrUnauthenticated if there is none.
+func FromContext(ctx context.Context) (*Principal, error) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	if !ok || p == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return p, nil
+}
+
+// IsAdmin reports whether principal has admin role.
+func (p *Principal) IsAdmin() bool {
+	return p.Role == storage.RoleAdmin
+}
+
+// RequireAdmin allows only admins to proceed.
+func RequireAdmin(ctx context.Context) (*Principal, error) {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !p.IsAdmin() {
+		return nil, ErrPermissionDenied
+	}
+
+	return p, nil
+}
+
+// RequireOrganizer allows organizers and admins to proceed.
+func RequireOrganizer(ctx context.Context) (*Principal, error) {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.Role != storage.RoleOrganizer && !p.IsAdmin() {
+		return nil, ErrPermissionDenied
+	}
+
+	return p, nil
+}
+
+// RequireSelf allows user with provided id or admins to proceed.
+func RequireSelf(ctx context.Context, userID string) (*Principal, error) {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID && !p.IsAdmin() {
+		return nil, ErrPermissionDenied
+	}
+
+	return p, nil
+}
+
+// RequireTournamentOrganizer allows organizer of provided tournament or admins to proceed.
+func RequireTournamentOrganizer(ctx context.Context, tournament *storage.Tournament) (*Principal, error) {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.Organizer.Hex() != p.UserID && !p.IsAdmin() {
+		return nil, ErrPermissionDenied
+	}
+
+	return p, nil
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+type staticAuthenticator map[string]*Principal
+
+func (a staticAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	p, ok := a[token]
+	if !ok {
+		return nil, errors.New("unknown token")
+	}
+
+	return p, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	admin := &Principal{UserID: primitive.NewObjectID().Hex(), Role: storage.RoleAdmin}
+	authn := staticAuthenticator{"admin-token": admin}
+
+	var actual *Principal
+	handler := Middleware(authn)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		actual, _ = FromContext(req.Context())
+	}))
+
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+	require.Equal(admin, actual, "The two principals should be the same")
+
+	actual = nil
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+	require.Nil(actual, "Anonymous request should not have principal")
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer bad-token")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(http.StatusUnauthorized, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestRequireTournamentOrganizer(t *testing.T) {
+	organizerID := primitive.NewObjectID()
+	tournament := &storage.Tournament{Organizer: organizerID}
+	require := require.New(t)
+
+	_, err := RequireTournamentOrganizer(context.Background(), tournament)
+	require.Equal(ErrUnauthenticated, err, "The two errors should be the same")
+
+	ctx := NewContext(context.Background(), &Principal{UserID: organizerID.Hex(), Role: storage.RoleOrganizer})
+	_, err = RequireTournamentOrganizer(ctx, tournament)
+	require.NoError(err)
+
+	ctx = NewContext(context.Background(), &Principal{UserID: primitive.NewObjectID().Hex(), Role: storage.RoleOrganizer})
+	_, err = RequireTournamentOrganizer(ctx, tournament)
+	require.Equal(ErrPermissionDenied, err, "The two errors should be the same")
+
+	ctx = NewContext(context.Background(), &Principal{UserID: primitive.NewObjectID().Hex(), Role: storage.RoleAdmin})
+	_, err = RequireTournamentOrganizer(ctx, tournament)
+	require.NoError(err)
+}
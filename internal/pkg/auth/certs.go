@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// CertPrincipals maps common names of verified client certificates to
+// principals, so other services can call API over mutual TLS without tokens.
+type CertPrincipals map[string]*Principal
+
+// FromTLS returns principal for peer certificate of state. Only certificates
+// verified against configured client CAs are trusted.
+func (c CertPrincipals) FromTLS(state *tls.ConnectionState) (*Principal, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	p, ok := c[state.VerifiedChains[0][0].Subject.CommonName]
+	return p, ok
+}
+
+// CertMiddleware stores principal mapped from client certificate in request
+// context. It should precede Middleware, so bearer token still takes precedence.
+func CertMiddleware(c CertPrincipals) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if p, ok := c.FromTLS(req.TLS); ok {
+				req = req.WithContext(NewContext(req.Context(), p))
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
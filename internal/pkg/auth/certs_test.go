@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func verifiedState(commonName string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
+
+func TestCertMiddleware(t *testing.T) {
+	billing := &Principal{UserID: "billing", Role: storage.RoleAdmin}
+	certs := CertPrincipals{"billing": billing}
+
+	var got *Principal
+	handler := CertMiddleware(certs)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, _ = FromContext(req.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		expected *Principal
+	}{
+		{"plain", nil, nil},
+		{"unverified", &tls.ConnectionState{}, nil},
+		{"unknown", verifiedState("reporting"), nil},
+		{"mapped", verifiedState("billing"), billing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest("GET", "/user", nil)
+			req.TLS = tt.state
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor_Cert(t *testing.T) {
+	billing := &Principal{UserID: "billing", Role: storage.RoleAdmin}
+	interceptor := UnaryServerInterceptor(staticAuthenticator{}, CertPrincipals{"billing": billing})
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: *verifiedState("billing")},
+	})
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return FromContext(ctx)
+		})
+	require := require.New(t)
+	require.NoError(err)
+	require.Equal(billing, resp)
+}
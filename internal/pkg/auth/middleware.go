@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const bearerPrefix = "Bearer "
+
+// Middleware authenticates requests carrying "Authorization: Bearer <token>" header
+// and stores resolved principal in request context. Requests without token
+// are passed through anonymously, so handlers decide if principal is required.
+func Middleware(authn Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token := bearerToken(req.Header.Get("Authorization"))
+			if token == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			p, err := authn.Authenticate(req.Context(), token)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				slog.WarnContext(req.Context(), "authentication failed", "err", err)
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), p)))
+		})
+	}
+}
+
+// UnaryServerInterceptor is gRPC counterpart of CertMiddleware and Middleware.
+// Principal is taken from verified client certificate mapped in certs, if any,
+// and then from token in "authorization" metadata key. Both are optional.
+func UnaryServerInterceptor(authn Authenticator, certs CertPrincipals) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGRPC(ctx, authn, certs)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is stream counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(authn Authenticator, certs CertPrincipals) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), authn, certs)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticateGRPC(ctx context.Context, authn Authenticator, certs CertPrincipals) (context.Context, error) {
+	if pr, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := pr.AuthInfo.(credentials.TLSInfo); ok {
+			if p, ok := certs.FromTLS(&tlsInfo.State); ok {
+				ctx = NewContext(ctx, p)
+			}
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token = bearerToken(values[0])
+	}
+	if token == "" || authn == nil {
+		return ctx, nil
+	}
+
+	p, err := authn.Authenticate(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return NewContext(ctx, p), nil
+}
+
+// serverStream overrides context of wrapped stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// HTTPStatus maps authorization error to http status code.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCStatus maps authorization error to gRPC status error.
+func GRPCStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func bearerToken(header string) string {
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+var (
+	// ErrInvalidCredentials is returned when login or password is wrong.
+	ErrInvalidCredentials = errors.New("invalid login or password")
+
+	// ErrInvalidToken is returned when access or refresh token is malformed, expired or revoked.
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+const (
+	// DefaultAccessTTL is lifetime of access token.
+	DefaultAccessTTL = 15 * time.Minute
+
+	// DefaultRefreshTTL is lifetime of refresh token.
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+
+	refreshTokenSize = 32
+)
+
+// Tokens is a pair of tokens issued on login and refresh.
+type Tokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+type accessClaims struct {
+	UserID    string       `json:"sub"`
+	Role      storage.Role `json:"role"`
+	ExpiresAt int64        `json:"exp"`
+}
+
+// Sessions manages user accounts: registration, login, token refresh,
+// logout and password change. Access tokens are stateless and signed
+// with HMAC-SHA256, refresh tokens are stored hashed and can be revoked.
+type Sessions struct {
+	db         storage.Service
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// NewSessions creates Sessions which sign access tokens with provided secret.
+func NewSessions(db storage.Service, secret []byte, accessTTL, refreshTTL time.Duration) *Sessions {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTTL
+	}
+
+	return &Sessions{
+		db:         db,
+		secret:     secret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// Register creates new user with provided name and credentials. Returns userID if succeed.
+func (s *Sessions) Register(ctx context.Context, name, login, password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "hash password")
+	}
+
+	return s.db.RegisterUser(ctx, name, login, hash)
+}
+
+// Login checks provided credentials and issues new pair of tokens.
+func (s *Sessions) Login(ctx context.Context, login, password string) (*Tokens, error) {
+	creds, err := s.db.GetCredentialsByLogin(ctx, login)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err = bcrypt.CompareHashAndPassword(creds.PasswordHash, []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issue(ctx, creds.UserID.Hex())
+}
+
+// Refresh exchanges valid refresh token for new pair of tokens.
+// Provided refresh token is revoked, so it can be used only once.
+func (s *Sessions) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	tokenHash := hashToken(refreshToken)
+	session, err := s.db.GetSession(ctx, tokenHash)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if session.Revoked || s.now().After(session.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	if err = s.db.RevokeSession(ctx, tokenHash); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return s.issue(ctx, session.UserID.Hex())
+}
+
+// Logout revokes provided refresh token.
+func (s *Sessions) Logout(ctx context.Context, refreshToken string) error {
+	if err := s.db.RevokeSession(ctx, hashToken(refreshToken)); err != nil {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// ChangePassword replaces password of user with provided id if old password
+// matches and revokes all his refresh tokens.
+func (s *Sessions) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) error {
+	creds, err := s.db.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "GetCredentialsByUserID")
+	}
+	if err = bcrypt.CompareHashAndPassword(creds.PasswordHash, []byte(oldPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "hash password")
+	}
+	if err = s.db.SetPasswordHash(ctx, userID, hash); err != nil {
+		return errors.Wrap(err, "SetPasswordHash")
+	}
+
+	return errors.Wrap(s.db.RevokeUserSessions(ctx, userID), "RevokeUserSessions")
+}
+
+// Authenticate verifies access token and returns principal it was issued for.
+func (s *Sessions) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims accessClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+
+	return &Principal{UserID: claims.UserID, Role: claims.Role}, nil
+}
+
+func (s *Sessions) issue(ctx context.Context, userID string) (*Tokens, error) {
+	user, err := s.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetUser")
+	}
+
+	payload, err := json.Marshal(accessClaims{
+		UserID:    userID,
+		Role:      user.Role,
+		ExpiresAt: s.now().Add(s.accessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode access token")
+	}
+	accessToken := base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+
+	raw := make([]byte, refreshTokenSize)
+	if _, err = rand.Read(raw); err != nil {
+		return nil, errors.Wrap(err, "generate refresh token")
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err = s.db.AddSession(ctx, userID, hashToken(refreshToken), s.now().Add(s.refreshTTL)); err != nil {
+		return nil, errors.Wrap(err, "AddSession")
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+	}, nil
+}
+
+func (s *Sessions) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
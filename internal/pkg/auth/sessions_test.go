@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestSessions_LoginAndAuthenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require := require.New(t)
+	require.NoError(err)
+
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().GetCredentialsByLogin(gomock.Any(), gomock.Eq("vasya")).AnyTimes().
+		Return(&storage.Credentials{Login: "vasya", UserID: userID, PasswordHash: hash}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).
+		Return(&storage.User{ID: userID, Role: storage.RoleOrganizer}, nil)
+	mock.EXPECT().AddSession(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	sessions := NewSessions(mock, []byte("key"), time.Minute, time.Hour)
+
+	_, err = sessions.Login(context.TODO(), "vasya", "wrong")
+	require.Equal(ErrInvalidCredentials, err, "The two errors should be the same")
+
+	tokens, err := sessions.Login(context.TODO(), "vasya", "secret")
+	require.NoError(err)
+	require.NotEmpty(tokens.RefreshToken)
+	require.Equal(int64(60), tokens.ExpiresIn)
+
+	p, err := sessions.Authenticate(context.TODO(), tokens.AccessToken)
+	require.NoError(err)
+	require.Equal(&Principal{UserID: userID.Hex(), Role: storage.RoleOrganizer}, p, "The two principals should be the same")
+
+	_, err = sessions.Authenticate(context.TODO(), tokens.AccessToken+"x")
+	require.Equal(ErrInvalidToken, err, "The two errors should be the same")
+
+	other := NewSessions(mock, []byte("other key"), time.Minute, time.Hour)
+	_, err = other.Authenticate(context.TODO(), tokens.AccessToken)
+	require.Equal(ErrInvalidToken, err, "Token signed with other key should be rejected")
+
+	sessions.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = sessions.Authenticate(context.TODO(), tokens.AccessToken)
+	require.Equal(ErrInvalidToken, err, "Expired token should be rejected")
+}
+
+func TestSessions_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID()
+	mock := storage.NewMockService(ctrl)
+	sessions := NewSessions(mock, []byte("key"), time.Minute, time.Hour)
+	require := require.New(t)
+
+	mock.EXPECT().GetSession(gomock.Any(), gomock.Eq(hashToken("revoked"))).Times(1).
+		Return(&storage.Session{UserID: userID, ExpiresAt: time.Now().Add(time.Hour), Revoked: true}, nil)
+	_, err := sessions.Refresh(context.TODO(), "revoked")
+	require.Equal(ErrInvalidToken, err, "The two errors should be the same")
+
+	mock.EXPECT().GetSession(gomock.Any(), gomock.Eq(hashToken("expired"))).Times(1).
+		Return(&storage.Session{UserID: userID, ExpiresAt: time.Now().Add(-time.Hour)}, nil)
+	_, err = sessions.Refresh(context.TODO(), "expired")
+	require.Equal(ErrInvalidToken, err, "The two errors should be the same")
+
+	mock.EXPECT().GetSession(gomock.Any(), gomock.Eq(hashToken("valid"))).Times(1).
+		Return(&storage.Session{UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mock.EXPECT().RevokeSession(gomock.Any(), gomock.Eq(hashToken("valid"))).Times(1).Return(nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).
+		Return(&storage.User{ID: userID, Role: storage.RolePlayer}, nil)
+	mock.EXPECT().AddSession(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	tokens, err := sessions.Refresh(context.TODO(), "valid")
+	require.NoError(err)
+	require.NotEqual("valid", tokens.RefreshToken, "Refresh token should be rotated")
+}
+
+func TestSessions_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID()
+	hash, err := bcrypt.GenerateFromPassword([]byte("old"), bcrypt.MinCost)
+	require := require.New(t)
+	require.NoError(err)
+
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().GetCredentialsByUserID(gomock.Any(), gomock.Eq(userID.Hex())).Times(2).
+		Return(&storage.Credentials{Login: "vasya", UserID: userID, PasswordHash: hash}, nil)
+	mock.EXPECT().SetPasswordHash(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Any()).Times(1).Return(nil)
+	mock.EXPECT().RevokeUserSessions(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).Return(nil)
+
+	sessions := NewSessions(mock, []byte("key"), time.Minute, time.Hour)
+
+	err = sessions.ChangePassword(context.TODO(), userID.Hex(), "wrong", "new")
+	require.Equal(ErrInvalidCredentials, err, "The two errors should be the same")
+
+	err = sessions.ChangePassword(context.TODO(), userID.Hex(), "old", "new")
+	require.NoError(err)
+}
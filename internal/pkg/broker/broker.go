@@ -0,0 +1,114 @@
+// Package broker publishes tournament and balance domain events to message
+// bus for consumers outside of the service, like analytics.
+package broker
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	eventsv1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/events/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// SchemaVersion is version of payload schema. It's part of every subject,
+// so consumers of incompatible version don't receive messages they can't decode.
+const SchemaVersion = "v1"
+
+// Config configures publisher.
+type Config struct {
+	// URL is NATS server URL. Events aren't published if it's empty.
+	URL string `yaml:"url"`
+
+	// Stream is JetStream stream storing published events.
+	// It's created or updated on start to capture all subjects.
+	Stream string `yaml:"stream"`
+
+	// SubjectPrefix is prepended to subjects, e.g. sts.events.v1.tournament.finished.
+	SubjectPrefix string `yaml:"subject_prefix"`
+
+	// Timeout limits connecting and single publish.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Duplicates is window in which JetStream drops event
+	// published again with the same id.
+	Duplicates time.Duration `yaml:"duplicates"`
+}
+
+// DefaultConfig returns config used when broker section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		Stream:        "STS_EVENTS",
+		SubjectPrefix: "sts.events",
+		Timeout:       5 * time.Second,
+		Duplicates:    2 * time.Minute,
+	}
+}
+
+// EventPublisher publishes domain events to message bus. Publish has
+// outbox.Handler signature, so events are published in order they happened
+// and published again if publishing fails.
+type EventPublisher interface {
+	// Publish publishes event. Events not meant for message bus are skipped.
+	Publish(ctx context.Context, e storage.OutboxEvent) error
+
+	// Close releases connection to message bus.
+	Close() error
+}
+
+// New returns JetStream publisher or no-op publisher if URL is empty.
+func New(ctx context.Context, conf Config) (EventPublisher, error) {
+	if conf.URL == "" {
+		return Nop{}, nil
+	}
+
+	return NewJetStream(ctx, conf)
+}
+
+// Nop is publisher which drops all events.
+type Nop struct{}
+
+// Publish does nothing.
+func (Nop) Publish(context.Context, storage.OutboxEvent) error { return nil }
+
+// Close does nothing.
+func (Nop) Close() error { return nil }
+
+// subjects maps published event types to subjects without prefix and version.
+var subjects = map[storage.EventType]string{
+	storage.EventPlayerJoined:        "tournament.joined",
+	storage.EventTournamentFinished:  "tournament.finished",
+	storage.EventTournamentCancelled: "tournament.cancelled",
+	storage.EventBalanceFunded:       "balance.funded",
+	storage.EventBalanceTaken:        "balance.taken",
+}
+
+// Envelope converts event to message payload and returns its subject without
+// prefix. It returns false for events which aren't published.
+func Envelope(e storage.OutboxEvent) (string, *eventsv1.Envelope, bool) {
+	subject, ok := subjects[e.Type]
+	if !ok {
+		return "", nil, false
+	}
+
+	env := &eventsv1.Envelope{
+		Id:         e.ID.Hex(),
+		Type:       string(e.Type),
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+	switch e.Type {
+	case storage.EventBalanceFunded:
+		env.Payload = &eventsv1.Envelope_Balance{Balance: &eventsv1.BalanceEvent{UserId: e.UserID, Amount: e.Amount}}
+	case storage.EventBalanceTaken:
+		env.Payload = &eventsv1.Envelope_Balance{Balance: &eventsv1.BalanceEvent{UserId: e.UserID, Amount: -e.Amount}}
+	default:
+		env.Payload = &eventsv1.Envelope_Tournament{Tournament: &eventsv1.TournamentEvent{
+			TournamentId: e.TournamentID,
+			UserId:       e.UserID,
+			Amount:       e.Amount,
+		}}
+	}
+
+	return SchemaVersion + "." + subject, env, true
+}
@@ -0,0 +1,105 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+
+	eventsv1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/events/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// runServer starts embedded NATS server with JetStream enabled.
+func runServer(t *testing.T) *server.Server {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(5*time.Second), "NATS server should start")
+
+	return srv
+}
+
+func TestNew_Nop(t *testing.T) {
+	p, err := New(context.TODO(), Config{})
+	require.NoError(t, err)
+	require.Equal(t, Nop{}, p, "Publisher without URL should drop events")
+	require.NoError(t, p.Publish(context.TODO(), storage.OutboxEvent{Type: storage.EventBalanceFunded}))
+	require.NoError(t, p.Close())
+}
+
+func TestJetStream(t *testing.T) {
+	srv := runServer(t)
+	require := require.New(t)
+
+	conf := DefaultConfig()
+	conf.URL = srv.ClientURL()
+	p, err := NewJetStream(context.TODO(), conf)
+	require.NoError(err)
+	defer p.Close()
+
+	tournamentID, userID := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	occurredAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	published := []storage.OutboxEvent{
+		{ID: primitive.NewObjectID(), Type: storage.EventUserCreated, UserID: userID, Name: "Vasya"},
+		{ID: primitive.NewObjectID(), Type: storage.EventBalanceTaken, UserID: userID, Amount: 30},
+		{ID: primitive.NewObjectID(), Type: storage.EventPlayerJoined, TournamentID: tournamentID,
+			UserID: userID, Amount: 30, OccurredAt: occurredAt},
+		{ID: primitive.NewObjectID(), Type: storage.EventTournamentFinished, TournamentID: tournamentID,
+			UserID: userID, Amount: 30},
+	}
+	for _, e := range published {
+		require.NoError(p.Publish(context.TODO(), e))
+	}
+	require.NoError(p.Publish(context.TODO(), published[3]), "Republished event should be accepted")
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(err)
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	require.NoError(err)
+	consumer, err := js.CreateOrUpdateConsumer(context.TODO(), conf.Stream, jetstream.ConsumerConfig{})
+	require.NoError(err)
+
+	batch, err := consumer.Fetch(10, jetstream.FetchMaxWait(time.Second))
+	require.NoError(err)
+	var subjects []string
+	var envelopes []*eventsv1.Envelope
+	for msg := range batch.Messages() {
+		subjects = append(subjects, msg.Subject())
+		require.Equal("sts.events.v1.Envelope", msg.Headers().Get(SchemaHeader))
+
+		env := &eventsv1.Envelope{}
+		require.NoError(proto.Unmarshal(msg.Data(), env))
+		envelopes = append(envelopes, env)
+	}
+	require.NoError(batch.Error())
+
+	require.Equal([]string{
+		"sts.events.v1.balance.taken",
+		"sts.events.v1.tournament.joined",
+		"sts.events.v1.tournament.finished",
+	}, subjects, "Only tournament and balance events should be published once each")
+
+	require.Equal(published[1].ID.Hex(), envelopes[0].Id)
+	require.Equal(-30.0, envelopes[0].GetBalance().Amount, "Taken points should be negative")
+	require.Equal(string(storage.EventPlayerJoined), envelopes[1].Type)
+	require.Equal(occurredAt, envelopes[1].OccurredAt.AsTime())
+	require.Equal(tournamentID, envelopes[1].GetTournament().TournamentId)
+	require.Equal(userID, envelopes[2].GetTournament().UserId)
+}
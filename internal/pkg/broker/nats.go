@@ -0,0 +1,98 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// SchemaHeader carries full name of payload message, e.g. sts.events.v1.Envelope.
+	SchemaHeader = "Sts-Schema"
+
+	contentType = "application/protobuf"
+)
+
+// JetStream publishes events to NATS JetStream stream. Event id is used as
+// message id, so event published again within Duplicates window is dropped.
+type JetStream struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	conf Config
+}
+
+// NewJetStream connects to NATS server and creates or updates stream
+// capturing all subjects of current schema version.
+func NewJetStream(ctx context.Context, conf Config) (*JetStream, error) {
+	defaults := DefaultConfig()
+	if conf.Stream == "" {
+		conf.Stream = defaults.Stream
+	}
+	if conf.SubjectPrefix == "" {
+		conf.SubjectPrefix = defaults.SubjectPrefix
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaults.Timeout
+	}
+
+	conn, err := nats.Connect(conf.URL, nats.Name("social-tournament-service"), nats.Timeout(conf.Timeout))
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to NATS")
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "create JetStream context")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       conf.Stream,
+		Subjects:   []string{conf.SubjectPrefix + "." + SchemaVersion + ".>"},
+		Duplicates: conf.Duplicates,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "create stream %s", conf.Stream)
+	}
+
+	return &JetStream{conn: conn, js: js, conf: conf}, nil
+}
+
+// Publish publishes tournament and balance events and waits for stream
+// to acknowledge them. Other events are skipped.
+func (p *JetStream) Publish(ctx context.Context, e storage.OutboxEvent) error {
+	subject, env, ok := Envelope(e)
+	if !ok {
+		return nil
+	}
+
+	data, err := proto.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "encode event")
+	}
+	msg := nats.NewMsg(p.conf.SubjectPrefix + "." + subject)
+	msg.Data = data
+	msg.Header.Set("Content-Type", contentType)
+	msg.Header.Set(SchemaHeader, string(proto.MessageName(env)))
+
+	ctx, cancel := context.WithTimeout(ctx, p.conf.Timeout)
+	defer cancel()
+	if _, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(env.Id)); err != nil {
+		return errors.Wrapf(err, "publish event %s", env.Id)
+	}
+
+	return nil
+}
+
+// Close closes connection. Published events are already acknowledged by stream.
+func (p *JetStream) Close() error {
+	p.conn.Close()
+	return nil
+}
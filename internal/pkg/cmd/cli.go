@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/audit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/config"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/protocol/grpc"
+	service "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tlsconfig"
+)
+
+const (
+	// tokenEnv holds access token used with -addr if -token flag is not provided.
+	tokenEnv = "STS_TOKEN"
+
+	localBufferSize = 1 << 20
+)
+
+const usage = `usage: sts <command> [flags] [args]
+
+commands:
+  serve        run REST API and gRPC servers, default if command is omitted
+  migrate      create collections and apply pending schema migrations
+  user         create, get, fund, take, delete users
+  tournament   create, list, get, start, finish, cancel tournaments
+
+Run "sts <command> -h" for flags of command.
+`
+
+// Run runs command named by first of args. Servers are run if command
+// is omitted, so that "sts -config config.yaml" keeps serving.
+func Run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && !isHelp(args[0]) {
+		return runServer(args)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "serve":
+		return runServer(args[1:])
+	case "migrate":
+		return runMigrate(ctx, args[1:])
+	case "user":
+		return runGroup(ctx, "user", userCommands(), args[1:], stdout)
+	case "tournament":
+		return runGroup(ctx, "tournament", tournamentCommands(), args[1:], stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	return errors.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// command is subcommand of user or tournament command group.
+type command struct {
+	// usage lists positional arguments of command.
+	usage string
+	nargs int
+	// mutates is set for privileged changes, they need -as without -addr,
+	// so that audit log records who performed them.
+	mutates bool
+	// flags registers command specific flags, it's optional.
+	flags func(fs *flag.FlagSet)
+	// run performs command with positional args. Returned message is printed,
+	// nothing is printed if it is nil.
+	run func(ctx context.Context, c *client, args []string) (proto.Message, error)
+}
+
+// client calls API either remotely or in process.
+type client struct {
+	v1.TournamentClient
+	// local is set if API is served in process against storage.
+	local bool
+	// as is id of user local calls are made on behalf of, it's empty only for reads.
+	as string
+}
+
+// runGroup parses flags of command named by first of args and runs it.
+func runGroup(ctx context.Context, group string, commands map[string]*command, args []string,
+	stdout io.Writer) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		return errors.Errorf("usage: sts %s <command> [flags] [args]\ncommands: %s",
+			group, strings.Join(commandNames(commands), ", "))
+	}
+	sub, ok := commands[args[0]]
+	if !ok {
+		return errors.Errorf("unknown %s command %q, known are %s",
+			group, args[0], strings.Join(commandNames(commands), ", "))
+	}
+
+	name := group + " " + args[0]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: sts %s [flags] %s\n", name, sub.usage)
+		fs.PrintDefaults()
+	}
+	var cf clientFlags
+	cf.register(fs)
+	if sub.flags != nil {
+		sub.flags(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != sub.nargs {
+		fs.Usage()
+		return errors.Errorf("%s expects %d arguments, got %d", name, sub.nargs, fs.NArg())
+	}
+	if sub.mutates && cf.addr == "" && cf.as == "" {
+		return errors.Errorf("-as is required to %s without -addr", name)
+	}
+	if cf.output != outputTable && cf.output != outputJSON {
+		return errors.Errorf("-output must be %s or %s", outputTable, outputJSON)
+	}
+	// token isn't flag default, so that usage doesn't print it
+	if cf.token == "" {
+		cf.token = os.Getenv(tokenEnv)
+	}
+
+	c, closeClient, err := cf.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	msg, err := sub.run(ctx, c, fs.Args())
+	if err != nil {
+		return errors.Wrap(err, name)
+	}
+	if msg == nil {
+		return nil
+	}
+
+	return printMessage(stdout, cf.output, msg)
+}
+
+func commandNames(commands map[string]*command) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// clientFlags select API commands are performed against.
+type clientFlags struct {
+	config string
+	as     string
+	addr   string
+	token  string
+	tls    bool
+	caFile string
+	output string
+}
+
+func (cf *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&cf.config, "config", config.DefaultPath,
+		"path to YAML config file, storage it configures is used unless -addr is set")
+	fs.StringVar(&cf.as, "as", "",
+		"id of admin recorded in audit log as performing local commands and organizing created tournaments, "+
+			"required for local changes")
+	fs.StringVar(&cf.addr, "addr", "", "address of gRPC API to call instead of using storage directly")
+	fs.StringVar(&cf.token, "token", "", "access token used with -addr, defaults to $"+tokenEnv)
+	fs.BoolVar(&cf.tls, "tls", false, "connect to -addr over TLS")
+	fs.StringVar(&cf.caFile, "ca-file", "", "PEM bundle of CAs server certificate is verified against")
+	fs.StringVar(&cf.output, "output", outputTable, "output format, table or json")
+}
+
+// connect dials remote API if -addr is set. Otherwise it serves API
+// in process against storage configured by -config.
+func (cf *clientFlags) connect(ctx context.Context) (*client, func(), error) {
+	if cf.addr != "" {
+		return cf.dial(ctx)
+	}
+	if cf.as != "" && !primitive.IsValidObjectID(cf.as) {
+		return nil, nil, errors.New("-as must be user id")
+	}
+
+	conf, err := config.Load([]string{"-config", cf.config}, os.Getenv)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error loading config")
+	}
+	mongoClient, err := connectMongo(ctx, conf.Mongo)
+	if err != nil {
+		return nil, nil, err
+	}
+	mongoDB := storage.CreateNew(mongoClient.Database(conf.Mongo.Database))
+
+	principal, err := localPrincipal(ctx, mongoDB, cf.as)
+	if err != nil {
+		disconnectMongo(mongoClient, conf.Mongo.ConnectTimeout)
+		return nil, nil, err
+	}
+	c, closeLocal, err := serveLocal(ctx, audit.Record(mongoDB), principal)
+	if err != nil {
+		disconnectMongo(mongoClient, conf.Mongo.ConnectTimeout)
+		return nil, nil, err
+	}
+
+	return c, func() {
+		closeLocal()
+		disconnectMongo(mongoClient, conf.Mongo.ConnectTimeout)
+	}, nil
+}
+
+// localPrincipal returns principal local calls are made on behalf of. User
+// given by -as must be existing admin, so that audit log records real actor.
+// Reads without -as are made by anonymous admin.
+func localPrincipal(ctx context.Context, db storage.Service, as string) (*auth.Principal, error) {
+	if as == "" {
+		return &auth.Principal{Role: storage.RoleAdmin}, nil
+	}
+
+	user, err := db.GetUser(ctx, as)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errors.Errorf("-as user %s doesn't exist", as)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading -as user")
+	}
+	if user.DeletedAt != nil {
+		return nil, errors.Errorf("-as user %s is deleted", as)
+	}
+	if user.Role != storage.RoleAdmin {
+		return nil, errors.Errorf("-as user %s must be admin, not %s", as, user.Role)
+	}
+
+	return &auth.Principal{UserID: user.ID.Hex(), Role: user.Role}, nil
+}
+
+// dial connects to remote API passing access token with every call.
+func (cf *clientFlags) dial(ctx context.Context) (*client, func(), error) {
+	clientTLS, err := tlsconfig.NewClient(tlsconfig.ClientConfig{Enabled: cf.tls, CAFile: cf.caFile})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error configuring TLS")
+	}
+	creds := insecure.NewCredentials()
+	if clientTLS != nil {
+		creds = credentials.NewTLS(clientTLS)
+	}
+
+	opts := []ggrpc.DialOption{ggrpc.WithTransportCredentials(creds)}
+	if cf.token != "" {
+		opts = append(opts, ggrpc.WithUnaryInterceptor(bearer(cf.token)))
+	}
+	conn, err := ggrpc.DialContext(ctx, cf.addr, opts...)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error connecting to %s", cf.addr)
+	}
+
+	return &client{TournamentClient: v1.NewTournamentClient(conn)}, func() { _ = conn.Close() }, nil
+}
+
+// bearer adds access token to metadata of unary calls.
+func bearer(token string) ggrpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *ggrpc.ClientConn,
+		invoker ggrpc.UnaryInvoker, opts ...ggrpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// serveLocal serves API against db over in-memory connection, so that local
+// commands are validated and authorized by the same service remote calls are.
+// Every call is made on behalf of principal.
+func serveLocal(ctx context.Context, db storage.Service, principal *auth.Principal) (*client, func(), error) {
+	errs := grpc.Errors(service.Status)
+	srv := ggrpc.NewServer(ggrpc.ChainUnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo,
+			handler ggrpc.UnaryHandler) (interface{}, error) {
+			return handler(auth.NewContext(ctx, principal), req)
+		},
+		errs.Unary,
+	))
+	v1.RegisterTournamentServer(srv, service.NewToDoServiceServer(db))
+
+	l := bufconn.Listen(localBufferSize)
+	go func() { _ = srv.Serve(l) }()
+
+	conn, err := ggrpc.DialContext(ctx, "bufconn",
+		ggrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		srv.Stop()
+		return nil, nil, errors.Wrap(err, "error connecting to local API")
+	}
+
+	return &client{TournamentClient: v1.NewTournamentClient(conn), local: true, as: principal.UserID}, func() {
+		_ = conn.Close()
+		srv.Stop()
+	}, nil
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	service "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestUserCommands(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), userID.Hex(), 10.5).Return(nil)
+	mock.EXPECT().GetUser(gomock.Any(), userID.Hex()).
+		Return(&storage2.User{ID: userID, Name: "Gena", Balance: 110.5, Role: storage2.RolePlayer}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Any()).Return(nil, mongo.ErrNoDocuments)
+
+	c, closeClient, err := serveLocal(context.TODO(), mock, &auth.Principal{Role: storage2.RoleAdmin})
+	require := require.New(t)
+	require.NoError(err)
+	defer closeClient()
+
+	msg, err := userCommands()["fund"].run(context.TODO(), c, []string{userID.Hex(), "10.5"})
+	require.NoError(err)
+	var out bytes.Buffer
+	require.NoError(printMessage(&out, outputTable, msg))
+	require.Equal("ID                        NAME  BALANCE  ROLE    DELETED\n"+
+		userID.Hex()+"  Gena  110.5    player  -\n", out.String())
+
+	_, err = userCommands()["get"].run(context.TODO(), c, []string{primitive.NewObjectID().Hex()})
+	require.Equal(codes.NotFound, status.Code(err), "Storage errors should be translated as for remote calls")
+	_, err = userCommands()["take"].run(context.TODO(), c, []string{userID.Hex(), "ten"})
+	require.Error(err)
+}
+
+func TestTournamentCommands(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	organizerID := primitive.NewObjectID()
+	tournament := storage2.Tournament{ID: primitive.NewObjectID(), Name: "Cup", Deposit: 100,
+		Status: storage2.StatusStarted, Organizer: organizerID}
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().ListTournaments(gomock.Any(), storage2.StatusStarted, int64(5)).
+		Return([]storage2.Tournament{tournament}, nil)
+	mock.EXPECT().AddTournament(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	c, closeClient, err := serveLocal(context.TODO(), mock, &auth.Principal{Role: storage2.RoleAdmin})
+	require := require.New(t)
+	require.NoError(err)
+	defer closeClient()
+
+	commands := tournamentCommands()
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	commands["list"].flags(fs)
+	require.NoError(fs.Parse([]string{"-status", "started", "-limit", "5"}))
+	msg, err := commands["list"].run(context.TODO(), c, nil)
+	require.NoError(err)
+	var out bytes.Buffer
+	require.NoError(printMessage(&out, outputJSON, msg))
+	var list struct {
+		Tournaments []struct {
+			ID        string `json:"id"`
+			Organizer string `json:"organizer"`
+		} `json:"tournaments"`
+	}
+	require.NoError(json.Unmarshal(out.Bytes(), &list))
+	require.Len(list.Tournaments, 1)
+	require.Equal(tournament.ID.Hex(), list.Tournaments[0].ID)
+	require.Equal(organizerID.Hex(), list.Tournaments[0].Organizer)
+
+	_, err = commands["create"].run(context.TODO(), c, []string{"Cup", "100"})
+	require.EqualError(err, "-as is required to create tournament without -addr",
+		"Local tournament should not be created without organizer")
+}
+
+func TestRun_Usage(t *testing.T) {
+	var out bytes.Buffer
+	require := require.New(t)
+
+	require.NoError(Run([]string{"help"}, &out))
+	require.Contains(out.String(), "tournament")
+	require.ErrorContains(Run([]string{"player"}, &out), `unknown command "player"`)
+	require.ErrorContains(Run([]string{"user", "promote"}, &out), `unknown user command "promote"`)
+	require.ErrorContains(Run([]string{"user", "fund", primitive.NewObjectID().Hex()}, &out),
+		"user fund expects 2 arguments, got 1")
+	require.ErrorContains(Run([]string{"tournament", "list", "-output", "yaml"}, &out), "-output must be")
+	require.EqualError(Run([]string{"user", "fund", primitive.NewObjectID().Hex(), "10"}, &out),
+		"-as is required to user fund without -addr", "Local change should not be made without actor")
+
+	t.Setenv(tokenEnv, "secret")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	(&clientFlags{}).register(fs)
+	require.Empty(fs.Lookup("token").DefValue, "Usage should not print access token")
+}
+
+func TestLocalPrincipal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	adminID, playerID, deletedID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	deletedAt := time.Now()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetUser(gomock.Any(), adminID.Hex()).
+		Return(&storage2.User{ID: adminID, Role: storage2.RoleAdmin}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), playerID.Hex()).
+		Return(&storage2.User{ID: playerID, Role: storage2.RolePlayer}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), deletedID.Hex()).
+		Return(&storage2.User{ID: deletedID, Role: storage2.RoleAdmin, DeletedAt: &deletedAt}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Any()).Return(nil, mongo.ErrNoDocuments)
+	require := require.New(t)
+
+	p, err := localPrincipal(context.TODO(), mock, adminID.Hex())
+	require.NoError(err)
+	require.Equal(&auth.Principal{UserID: adminID.Hex(), Role: storage2.RoleAdmin}, p)
+	p, err = localPrincipal(context.TODO(), mock, "")
+	require.NoError(err)
+	require.Empty(p.UserID, "Reads without -as should have no actor")
+
+	_, err = localPrincipal(context.TODO(), mock, playerID.Hex())
+	require.EqualError(err, "-as user "+playerID.Hex()+" must be admin, not player")
+	_, err = localPrincipal(context.TODO(), mock, deletedID.Hex())
+	require.EqualError(err, "-as user "+deletedID.Hex()+" is deleted")
+	unknownID := primitive.NewObjectID().Hex()
+	_, err = localPrincipal(context.TODO(), mock, unknownID)
+	require.EqualError(err, "-as user "+unknownID+" doesn't exist")
+}
+
+func TestClientFlags_Dial(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), tournamentID.Hex()).
+		Return(&storage2.Tournament{ID: tournamentID, Name: "Cup"}, nil)
+
+	var token []string
+	srv := ggrpc.NewServer(ggrpc.UnaryInterceptor(func(ctx context.Context, req interface{},
+		_ *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		token = md.Get("authorization")
+		return handler(ctx, req)
+	}))
+	v1.RegisterTournamentServer(srv, service.NewToDoServiceServer(mock))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require := require.New(t)
+	require.NoError(err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	cf := clientFlags{addr: l.Addr().String(), token: "secret"}
+	c, closeClient, err := cf.connect(context.TODO())
+	require.NoError(err)
+	defer closeClient()
+
+	msg, err := tournamentCommands()["get"].run(context.TODO(), c, []string{tournamentID.Hex()})
+	require.NoError(err)
+	require.Equal("Cup", msg.(*v1.TournamentInfo).GetName())
+	require.Equal([]string{"Bearer secret"}, token, "Access token should be passed with calls")
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"strconv"
+
+	"github.com/pkg/errors"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+)
+
+// userCommands manage users. Changed user is printed after every change.
+func userCommands() map[string]*command {
+	var force, anonymize bool
+
+	return map[string]*command{
+		"create": {usage: "NAME", nargs: 1, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			resp, err := c.CreateUser(ctx, &v1.CreateUserRequest{Name: args[0]})
+			if err != nil {
+				return nil, err
+			}
+			return c.GetUser(ctx, &v1.GetUserRequest{Id: resp.GetId()})
+		}},
+		"get": {usage: "ID", nargs: 1, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			return c.GetUser(ctx, &v1.GetUserRequest{Id: args[0]})
+		}},
+		"fund": {usage: "ID POINTS", nargs: 2, mutates: true, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			return changeBalance(ctx, c, c.FundUserBalance, args)
+		}},
+		"take": {usage: "ID POINTS", nargs: 2, mutates: true, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			return changeBalance(ctx, c, c.TakeUserBalance, args)
+		}},
+		"delete": {usage: "ID", nargs: 1, mutates: true,
+			flags: func(fs *flag.FlagSet) {
+				fs.BoolVar(&force, "force", false,
+					"remove user from tournaments which aren't started and forfeit his balance")
+				fs.BoolVar(&anonymize, "anonymize", false, "scrub personal data of user")
+			},
+			run: func(ctx context.Context, c *client, args []string) (proto.Message, error) {
+				_, err := c.DeleteUser(ctx, &v1.DeleteUserRequest{Id: args[0], Force: force, Anonymize: anonymize})
+				if err != nil {
+					return nil, err
+				}
+				return c.GetUser(ctx, &v1.GetUserRequest{Id: args[0]})
+			}},
+	}
+}
+
+// changeBalance funds or takes points given by args from user balance.
+func changeBalance(ctx context.Context, c *client,
+	change func(context.Context, *v1.UserPointsRequest, ...ggrpc.CallOption) (*emptypb.Empty, error),
+	args []string) (proto.Message, error) {
+	points, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return nil, errors.Errorf("points must be number, got %q", args[1])
+	}
+	if _, err = change(ctx, &v1.UserPointsRequest{Id: args[0], Points: points}); err != nil {
+		return nil, err
+	}
+
+	return c.GetUser(ctx, &v1.GetUserRequest{Id: args[0]})
+}
+
+// tournamentCommands manage tournaments. Changed tournament is printed
+// after every change except cancel, which deletes it.
+func tournamentCommands() map[string]*command {
+	var (
+		status string
+		limit  int64
+	)
+
+	return map[string]*command{
+		"create": {usage: "NAME DEPOSIT", nargs: 2, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			deposit, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return nil, errors.Errorf("deposit must be number, got %q", args[1])
+			}
+			// organizer of remotely created tournament is owner of access token
+			if c.local && c.as == "" {
+				return nil, errors.New("-as is required to create tournament without -addr")
+			}
+			resp, err := c.CreateTournament(ctx, &v1.CreateTournamentRequest{Name: args[0], Deposit: deposit})
+			if err != nil {
+				return nil, err
+			}
+			return c.GetTournament(ctx, &v1.GetTournamentRequest{Id: resp.GetId()})
+		}},
+		"list": {nargs: 0,
+			flags: func(fs *flag.FlagSet) {
+				fs.StringVar(&status, "status", "", "list only tournaments with status signIn, started or finished")
+				fs.Int64Var(&limit, "limit", 0, "maximum number of newest tournaments to list")
+			},
+			run: func(ctx context.Context, c *client, args []string) (proto.Message, error) {
+				return c.ListTournaments(ctx, &v1.ListTournamentsRequest{Status: status, Limit: limit})
+			}},
+		"get": {usage: "ID", nargs: 1, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			return c.GetTournament(ctx, &v1.GetTournamentRequest{Id: args[0]})
+		}},
+		"start": {usage: "ID", nargs: 1, mutates: true, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			if _, err := c.StartTournament(ctx, &v1.StartTournamentRequest{Id: args[0]}); err != nil {
+				return nil, err
+			}
+			return c.GetTournament(ctx, &v1.GetTournamentRequest{Id: args[0]})
+		}},
+		"finish": {usage: "ID WINNER_ID", nargs: 2, mutates: true, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			_, err := c.FinishTournament(ctx, &v1.FinishTournamentRequest{Id: args[0], WinnerUserId: args[1]})
+			if err != nil {
+				return nil, err
+			}
+			return c.GetTournament(ctx, &v1.GetTournamentRequest{Id: args[0]})
+		}},
+		"cancel": {usage: "ID", nargs: 1, mutates: true, run: func(ctx context.Context, c *client,
+			args []string) (proto.Message, error) {
+			_, err := c.CancelTournament(ctx, &v1.CancelTournamentRequest{Id: args[0]})
+			return nil, err
+		}},
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/config"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/migrate"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// runMigrate creates collections and applies pending schema migrations,
+// so that database can be prepared before rollout. Migrations are applied
+// even if servers don't apply them on startup.
+func runMigrate(ctx context.Context, args []string) error {
+	conf, err := config.Load(args, os.Getenv)
+	if err != nil {
+		return errors.Wrap(err, "error loading config")
+	}
+
+	logger, err := logging.New(os.Stderr, conf.Log)
+	if err != nil {
+		return errors.Wrap(err, "error configuring logger")
+	}
+	slog.SetDefault(logger)
+
+	client, err := connectMongo(ctx, conf.Mongo)
+	if err != nil {
+		return err
+	}
+	defer disconnectMongo(client, conf.Mongo.ConnectTimeout)
+
+	mongoDB := storage.CreateNew(client.Database(conf.Mongo.Database))
+	if err = mongoDB.CreateCollections(ctx); err != nil {
+		return errors.Wrap(err, "error creating collections")
+	}
+
+	return applyMigrations(ctx, mongoDB, conf.Migrations)
+}
+
+// applyMigrations applies schema migrations which aren't recorded in db yet.
+// It waits if other instance is applying them.
+func applyMigrations(ctx context.Context, db *storage.DB, conf migrate.Config) error {
+	applied, err := migrate.New(db, storage.Migrations, conf).Run(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error applying migrations")
+	}
+	slog.InfoContext(ctx, "database is migrated", "applied", applied)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printMessage writes msg as JSON, or as table if msg is user or tournament.
+func printMessage(w io.Writer, format string, msg proto.Message) error {
+	if format == outputJSON {
+		data, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(msg)
+		if err != nil {
+			return errors.Wrap(err, "encode output")
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch m := msg.(type) {
+	case *v1.User:
+		fmt.Fprintln(tw, "ID\tNAME\tBALANCE\tROLE\tDELETED")
+		deleted := "-"
+		if m.GetDeletedAt() != nil {
+			deleted = m.GetDeletedAt().AsTime().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.GetId(), m.GetName(), formatPoints(m.GetBalance()),
+			m.GetRole(), deleted)
+	case *v1.TournamentInfo:
+		printTournaments(tw, []*v1.TournamentInfo{m})
+	case *v1.ListTournamentsResponse:
+		printTournaments(tw, m.GetTournaments())
+	default:
+		return errors.Errorf("no table output for %T", msg)
+	}
+
+	return tw.Flush()
+}
+
+func printTournaments(w io.Writer, tournaments []*v1.TournamentInfo) {
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tDEPOSIT\tPRIZE\tPLAYERS\tWINNER\tORGANIZER")
+	for _, t := range tournaments {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", t.GetId(), t.GetName(), orDash(t.GetStatus()),
+			formatPoints(t.GetDeposit()), formatPoints(t.GetPrize()), len(t.GetUsers()),
+			orDash(t.GetWinner()), orDash(t.GetOrganizer()))
+	}
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+// orDash replaces empty value and id which isn't set with dash.
+func orDash(value string) string {
+	if value == "" || value == primitive.NilObjectID.Hex() {
+		return "-"
+	}
+
+	return value
+}
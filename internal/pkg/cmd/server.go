@@ -2,88 +2,397 @@ package cmd
 
 import (
 	"context"
-	"errors"
-	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/audit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/broker"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/config"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/export"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/mail"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/metrics"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/notification"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/outbox"
 	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/protocol/grpc"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/server"
 	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
 	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tlsconfig"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tracing"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/webhook"
+	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"gopkg.in/yaml.v3"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-// TODO: move config to separate pkg.
-type config struct {
-	ConnStr    string `yaml:"conn_str"`
-	ServerPort int32  `yaml:"server_port"`
-	DBName     string `yaml:"db_name"`
-}
+const (
+	defaultShutdownTimeout = 15 * time.Second
+	flushTimeout           = 5 * time.Second
+)
 
-// Validate checks if all config values are set.
-func (conf *config) Validate() error {
-	if conf.ConnStr == "" {
-		return errors.New("connection string is not provided")
-	}
-	if conf.DBName == "" {
-		return errors.New("database name is not provided")
+// runServer runs REST API and gRPC servers until SIGINT or SIGTERM is received.
+func runServer(args []string) error {
+	conf, err := config.Load(args, os.Getenv)
+	if err != nil {
+		return errors.Wrap(err, "error loading config")
 	}
-	if conf.ServerPort < 0 || conf.ServerPort > 65535 {
-		return errors.New("bad server port provided")
+
+	logger, err := logging.New(os.Stdout, conf.Log)
+	if err != nil {
+		return errors.Wrap(err, "error configuring logger")
 	}
+	slog.SetDefault(logger)
 
-	return nil
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return run(ctx, conf)
 }
 
-// RunServer runs gRPC server and HTTP gateway
-func RunServer() error {
-	ctx := context.Background()
+// run applies pending schema migrations unless they are left to "sts migrate",
+// serves requests, dispatches outbox events to webhooks, message broker,
+// notification inbox and email queue and builds personal data exports
+// until ctx is done and then shuts servers down: readiness is
+// switched off, servers drain in-flight requests, background workers stop,
+// then broker and Mongo connections are closed and pending traces are flushed.
+func run(ctx context.Context, conf *config.Config) error {
+	shutdownTracing, err := tracing.Setup(ctx, conf.Tracing)
+	if err != nil {
+		return errors.Wrap(err, "error configuring tracing")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("error flushing traces", "err", err)
+		}
+	}()
 
-	yamlConfigFile, err := os.ReadFile("config.yaml")
+	client, err := connectMongo(ctx, conf.Mongo)
 	if err != nil {
-		log.Fatalf("error opening cofiguration yaml file: %v\n", err)
+		return err
 	}
+	defer disconnectMongo(client, conf.Mongo.ConnectTimeout)
 
-	var conf config
-	err = yaml.Unmarshal(yamlConfigFile, &conf)
+	slog.Info("connected to MongoDB")
+	m := metrics.New()
+	mongoDB := storage.CreateNew(client.Database(conf.Mongo.Database))
+	if err = mongoDB.CreateCollections(ctx); err != nil {
+		return errors.Wrap(err, "error creating collections")
+	}
+	if conf.Migrations.OnStartup {
+		if err = applyMigrations(ctx, mongoDB, conf.Migrations); err != nil {
+			return err
+		}
+	}
+	publisher, err := broker.New(ctx, conf.Broker)
 	if err != nil {
-		log.Fatalf("error unmarshaling yaml configuration file: %v\n", err)
+		return errors.Wrap(err, "error connecting to message broker")
 	}
+	defer func() {
+		if err := publisher.Close(); err != nil {
+			slog.Error("error closing message broker connection", "err", err)
+		}
+	}()
+	hub := events.NewHub(conf.Events.HistorySize, conf.Events.SubscriberBuffer)
+	db := audit.Record(events.Publish(m.InstrumentStorage(mongoDB), hub))
+	m.RegisterTournamentGauge(db)
 
-	if err = conf.Validate(); err != nil {
-		log.Fatalf("error validating config file: %v", err)
+	h := health.New(
+		health.Check{Name: "mongo", Func: mongoDB.Ping},
+		health.Check{Name: "transactions", Func: mongoDB.CheckTransactions},
+	)
+	serverTLS, err := tlsconfig.NewServer(conf.TLS)
+	if err != nil {
+		return errors.Wrap(err, "error configuring TLS")
 	}
+	certs := certPrincipals(conf.Auth.Clients)
 
-	clientOptions := options.Client().ApplyURI(conf.ConnStr)
-	client, err := mongo.Connect(ctx, clientOptions)
+	sessions := auth.NewSessions(db, []byte(conf.Auth.Secret), conf.Auth.AccessTTL, conf.Auth.RefreshTTL)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.DefaultLimits())
+
+	webhooks := webhook.New(mongoDB, webhook.NewURLPolicy(conf.Webhooks.AllowedHosts))
+	inbox := notification.NewInbox(mongoDB, conf.Notifications)
+	// download links are signed with auth secret, signed message differs from access tokens
+	exporter := export.NewExporter(mongoDB, []byte(conf.Auth.Secret), conf.Exports)
+	// every sink dispatches outbox on its own, so outage of one doesn't hold the others
+	sinks := map[string]outbox.Handler{
+		"log":      outbox.Log,
+		"webhooks": webhooks.Enqueue,
+		"broker":   publisher.Publish,
+		"inbox":    inbox.Notify,
+	}
+	serverOpts := []server.Option{
+		server.WithCertPrincipals(certs),
+		server.WithAuthenticator(sessions),
+		server.WithSessions(sessions),
+		server.WithRateLimiter(limiter),
+		server.WithMetrics(m),
+		server.WithHealth(h),
+		server.WithEvents(hub, conf.Events.Heartbeat),
+		server.WithWebhooks(webhooks),
+		server.WithNotifications(inbox),
+		server.WithAudit(mongoDB),
+		server.WithExporter(exporter),
+	}
+	sender, err := mail.New(conf.Email)
+	if err != nil {
+		return errors.Wrap(err, "error configuring email sender")
+	}
+	var mailer *mail.Mailer
+	if sender != nil {
+		mailer = mail.NewMailer(mongoDB, sender, conf.Email)
+		sinks["email"] = mailer.Enqueue
+		serverOpts = append(serverOpts, server.WithMailer(mailer))
+	}
+	httpHandler := server.NewServer(db, serverOpts...)
+	multiplexed := conf.Multiplexed()
+	grpcOpts, err := grpc.Chain(conf.GRPC.Interceptors, map[string]grpc.Interceptor{
+		grpc.InterceptorTracing: {
+			Unary:  otelgrpc.UnaryServerInterceptor(),
+			Stream: otelgrpc.StreamServerInterceptor(),
+		},
+		grpc.InterceptorLogging: {
+			Unary:  logging.UnaryServerInterceptor(),
+			Stream: logging.StreamServerInterceptor(),
+		},
+		grpc.InterceptorMetrics: {
+			Unary:  m.UnaryServerInterceptor(),
+			Stream: m.StreamServerInterceptor(),
+		},
+		grpc.InterceptorRecovery: grpc.Recovery(),
+		grpc.InterceptorDeadline: grpc.Deadline(conf.GRPC.DefaultTimeout),
+		grpc.InterceptorAuth: {
+			Unary:  auth.UnaryServerInterceptor(sessions, certs),
+			Stream: auth.StreamServerInterceptor(sessions, certs),
+		},
+		grpc.InterceptorRateLimit: {
+			Unary:  ratelimit.UnaryServerInterceptor(limiter, v1.RateLimitClass),
+			Stream: ratelimit.StreamServerInterceptor(limiter, v1.RateLimitClass),
+		},
+		grpc.InterceptorErrors: grpc.Errors(v1.Status),
+	})
 	if err != nil {
-		log.Fatalf("error connecting to mongo db: %v", err)
+		return errors.Wrap(err, "error configuring gRPC interceptors")
+	}
+	// multiplexed gRPC is served by HTTP server, which terminates TLS itself
+	if serverTLS != nil && !multiplexed {
+		grpcOpts = append(grpcOpts, ggrpc.Creds(credentials.NewTLS(serverTLS)))
 	}
+	grpcServer := grpc.NewServer(v1.NewToDoServiceServer(db, v1.WithEvents(hub)), h, grpcOpts...)
 
-	defer func() {
-		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-		defer cancel()
-		err := client.Disconnect(ctx)
-		if err != nil {
-			log.Printf("error disconnecting from mongo db: %v", err)
+	grpcAddr := ":" + strconv.FormatInt(int64(conf.GRPC.Port), 10)
+	httpServer := &http.Server{
+		Addr:              ":" + strconv.FormatInt(int64(conf.HTTP.Port), 10),
+		Handler:           httpHandler,
+		ReadHeaderTimeout: conf.HTTP.ReadHeaderTimeout,
+		TLSConfig:         serverTLS,
+	}
+	// gRPC calls served by HTTP server are drained by mixed handler
+	gracefulStop := grpcServer.GracefulStop
+	if multiplexed {
+		mixed := grpc.MixedHandler(grpcServer, httpHandler)
+		if err = mixed.ConfigureServer(httpServer); err != nil {
+			return errors.Wrap(err, "error configuring HTTP/2")
+		}
+		httpServer.Addr = grpcAddr
+		httpServer.Handler = mixed
+		gracefulStop = mixed.GracefulStop
+	}
+	// live feeds never finish on their own and would hold graceful shutdown
+	httpServer.RegisterOnShutdown(httpHandler.CloseFeeds)
+
+	httpListener, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		return errors.Wrap(err, "listen HTTP port")
+	}
+	var grpcListener net.Listener
+	if !multiplexed {
+		if grpcListener, err = net.Listen("tcp", grpcAddr); err != nil {
+			_ = httpListener.Close()
+			return errors.Wrap(err, "listen gRPC port")
+		}
+	}
+
+	dispatchCtx, stopDispatch := context.WithCancel(ctx)
+	var dispatched sync.WaitGroup
+	workers := []func(context.Context){
+		webhook.NewDeliverer(mongoDB, conf.Webhooks).Run,
+		inbox.Run,
+		exporter.Run,
+	}
+	if mailer != nil {
+		workers = append(workers, mailer.Run)
+	}
+	for name, handler := range sinks {
+		workers = append(workers, outbox.NewDispatcher(mongoDB, conf.Outbox, name, handler).Run)
+	}
+	for _, run := range workers {
+		dispatched.Add(1)
+		go func(run func(context.Context)) {
+			defer dispatched.Done()
+			run(dispatchCtx)
+		}(run)
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		slog.Info("starting HTTP server", "addr", httpServer.Addr, "grpc", multiplexed, "tls", serverTLS != nil)
+		if err := serve(httpServer, httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- errors.Wrap(err, "serve HTTP")
 		}
 	}()
+	if grpcListener != nil {
+		go func() {
+			slog.Info("starting gRPC server", "addr", grpcAddr)
+			if err := grpcServer.Serve(grpcListener); err != nil && !errors.Is(err, ggrpc.ErrServerStopped) {
+				errCh <- errors.Wrap(err, "serve gRPC")
+			}
+		}()
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	case err = <-errCh:
+		slog.Error("server failed", "err", err)
+	}
+
+	shutdown(h, httpServer, grpcServer, gracefulStop, conf.Shutdown.DrainDelay, conf.Shutdown.Timeout)
+	// events, deliveries, emails and exports left pending are handled by next instance to start
+	stopDispatch()
+	dispatched.Wait()
+
+	return err
+}
+
+// serve serves HTTPS if server has TLS config and plain HTTP otherwise.
+func serve(srv *http.Server, l net.Listener) error {
+	if srv.TLSConfig != nil {
+		// certificate is provided by TLSConfig.GetCertificate
+		return srv.ServeTLS(l, "", "")
+	}
+
+	return srv.Serve(l)
+}
+
+// certPrincipals maps configured certificate subjects to principals.
+func certPrincipals(clients []config.Client) auth.CertPrincipals {
+	certs := make(auth.CertPrincipals, len(clients))
+	for _, c := range clients {
+		certs[c.Subject] = &auth.Principal{UserID: c.UserID, Role: storage.Role(c.Role)}
+	}
+
+	return certs
+}
+
+// connectMongo connects to MongoDB and checks that it is reachable.
+func connectMongo(ctx context.Context, conf config.Mongo) (*mongo.Client, error) {
+	clientOptions, err := mongoOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "error connecting to mongo db")
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, conf.ConnectTimeout)
+	err = client.Ping(pingCtx, nil)
+	cancel()
+	if err != nil {
+		disconnectMongo(client, conf.ConnectTimeout)
+		return nil, errors.Wrap(err, "error connecting to mongo db")
+	}
+
+	return client, nil
+}
+
+func disconnectMongo(client *mongo.Client, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	err = client.Ping(ctx, nil)
+	if err := client.Disconnect(ctx); err != nil {
+		slog.Error("error disconnecting from mongo db", "err", err)
+	}
+}
+
+// mongoOptions builds MongoDB client options with tracing and logging monitor.
+func mongoOptions(conf config.Mongo) (*options.ClientOptions, error) {
+	clientTLS, err := tlsconfig.NewClient(conf.TLS)
 	if err != nil {
-		log.Fatalf("error connecting to mongo db: %v", err)
+		return nil, errors.Wrap(err, "configure mongo TLS")
+	}
+
+	mode, err := readpref.ModeFromString(conf.ReadPreference)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse read preference")
+	}
+	pref, err := readpref.New(mode)
+	if err != nil {
+		return nil, errors.Wrap(err, "create read preference")
+	}
+
+	opts := options.Client().ApplyURI(conf.URI).
+		SetConnectTimeout(conf.ConnectTimeout).
+		SetMaxPoolSize(conf.MaxPoolSize).
+		SetReadPreference(pref).
+		SetMonitor(tracing.CommandMonitor(logging.CommandMonitor()))
+	if clientTLS != nil {
+		opts.SetTLSConfig(clientTLS)
 	}
 
-	log.Println("Connected to MongoDB!")
-	db := storage.CreateNew(client.Database(conf.DBName))
+	return opts, nil
+}
+
+// shutdown marks service unready, waits drainDelay and gracefully stops
+// both servers, gRPC one by gracefulStop. Servers are stopped forcibly
+// if they don't finish in timeout.
+func shutdown(h *health.Health, httpServer *http.Server, grpcServer *ggrpc.Server, gracefulStop func(),
+	drainDelay, timeout time.Duration) {
+	h.Drain()
+	if drainDelay > 0 {
+		slog.Info("draining", "delay", drainDelay)
+		time.Sleep(drainDelay)
+	}
+
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	servPort := strconv.FormatInt(int64(conf.ServerPort), 10)
-	v1API := v1.NewToDoServiceServer(db)
+	stopped := make(chan struct{})
+	go func() {
+		gracefulStop()
+		close(stopped)
+	}()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Error("error shutting down HTTP server", "err", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("gRPC server didn't stop in time, closing connections")
+		grpcServer.Stop()
+	}
 
-	return grpc.RunServer(ctx, v1API, servPort)
+	slog.Info("servers stopped")
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/protocol/grpc"
+)
+
+func TestShutdown(t *testing.T) {
+	h := health.New()
+	grpcServer := grpc.NewServer(v1.UnimplementedTournamentServer{}, h)
+	httpServer := &http.Server{Handler: http.NotFoundHandler()}
+	require := require.New(t)
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+
+	grpcDone := make(chan error, 1)
+	go func() { grpcDone <- grpcServer.Serve(grpcListener) }()
+	httpDone := make(chan error, 1)
+	go func() { httpDone <- httpServer.Serve(httpListener) }()
+
+	conn, err := ggrpc.Dial(grpcListener.Addr().String(), ggrpc.WithBlock(),
+		ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err, "gRPC server should be serving")
+	defer conn.Close()
+
+	shutdown(h, httpServer, grpcServer, grpcServer.GracefulStop, 0, time.Second)
+
+	_, err = h.Ready(context.TODO())
+	require.Equal(health.ErrDraining, err, "Readiness should fail after shutdown")
+	require.NoError(<-grpcDone)
+	require.Equal(http.ErrServerClosed, <-httpDone)
+}
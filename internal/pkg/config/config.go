@@ -0,0 +1,346 @@
+// Package config loads service configuration from layered sources:
+// defaults, YAML file, environment variables and secret files.
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gopkg.in/yaml.v3"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/broker"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/export"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/mail"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/migrate"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/notification"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/outbox"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/protocol/grpc"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tlsconfig"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tracing"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/webhook"
+)
+
+const (
+	// EnvPrefix starts names of environment variables overriding config values.
+	EnvPrefix = "STS"
+
+	// DefaultPath is config file used if -config flag is not provided.
+	DefaultPath = "config.yaml"
+
+	// BackendMongo is the only supported storage backend.
+	BackendMongo = "mongo"
+)
+
+// Config is complete service configuration.
+type Config struct {
+	HTTP          HTTP                `yaml:"http"`
+	GRPC          GRPC                `yaml:"grpc"`
+	Mongo         Mongo               `yaml:"mongo"`
+	Storage       Storage             `yaml:"storage"`
+	Migrations    migrate.Config      `yaml:"migrations"`
+	Events        Events              `yaml:"events"`
+	Outbox        outbox.Config       `yaml:"outbox"`
+	Webhooks      webhook.Config      `yaml:"webhooks"`
+	Broker        broker.Config       `yaml:"broker"`
+	Notifications notification.Config `yaml:"notifications"`
+	Email         mail.Config         `yaml:"email"`
+	Exports       export.Config       `yaml:"exports"`
+	Auth          Auth                `yaml:"auth"`
+	Shutdown      Shutdown            `yaml:"shutdown"`
+	Log           logging.Config      `yaml:"log"`
+	Tracing       tracing.Config      `yaml:"tracing"`
+
+	// TLS applies to both REST API and gRPC listeners.
+	TLS tlsconfig.Config `yaml:"tls"`
+}
+
+// HTTP configures REST API server.
+type HTTP struct {
+	// Port is REST API port. API is served on gRPC port if Port
+	// is not set or equals gRPC port.
+	Port              int32         `yaml:"port"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+}
+
+// GRPC configures gRPC server.
+type GRPC struct {
+	Port int32 `yaml:"port"`
+
+	// DefaultTimeout limits unary calls which came without deadline.
+	DefaultTimeout time.Duration `yaml:"default_timeout"`
+
+	// Interceptors lists server interceptors, outermost first. Known ones are
+	// tracing, logging, metrics, recovery, deadline, auth, ratelimit, errors.
+	Interceptors []string `yaml:"interceptors"`
+}
+
+// Mongo configures MongoDB client.
+type Mongo struct {
+	URI            string        `yaml:"uri"`
+	Database       string        `yaml:"database"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	MaxPoolSize    uint64        `yaml:"max_pool_size"`
+
+	// ReadPreference is one of primary, primaryPreferred, secondary,
+	// secondaryPreferred, nearest.
+	ReadPreference string `yaml:"read_preference"`
+
+	TLS tlsconfig.ClientConfig `yaml:"tls"`
+}
+
+// Storage selects storage backend.
+type Storage struct {
+	Backend string `yaml:"backend"`
+}
+
+// Events configures in-process hub of tournament events.
+type Events struct {
+	// HistorySize is number of last events kept for resuming streams.
+	HistorySize int `yaml:"history_size"`
+
+	// SubscriberBuffer is number of events buffered per subscriber
+	// before it is dropped as too slow.
+	SubscriberBuffer int `yaml:"subscriber_buffer"`
+
+	// Heartbeat is interval of keep-alive messages on idle HTTP live feeds.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+const (
+	// MinSecretLength is minimal length in bytes of secret tokens are signed with.
+	MinSecretLength = 32
+
+	// exampleSecret was shipped in example config and is never accepted.
+	exampleSecret = "change-me-in-production"
+)
+
+// Auth configures access and refresh tokens.
+type Auth struct {
+	Secret     string        `yaml:"secret"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+
+	// Clients authenticate with client certificates instead of tokens.
+	// They require mutual TLS to be enabled.
+	Clients []Client `yaml:"clients"`
+}
+
+// Client maps common name of client certificate to principal.
+type Client struct {
+	Subject string `yaml:"subject"`
+	UserID  string `yaml:"user_id"`
+	Role    string `yaml:"role"`
+}
+
+// Shutdown configures graceful shutdown.
+type Shutdown struct {
+	// DrainDelay is time between readiness starts failing and servers stop
+	// accepting requests, so load balancer has time to notice.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// Default returns config used as base for all other sources.
+func Default() Config {
+	return Config{
+		HTTP: HTTP{Port: 8080, ReadHeaderTimeout: 10 * time.Second},
+		GRPC: GRPC{
+			Port:           8000,
+			DefaultTimeout: 30 * time.Second,
+			Interceptors:   append([]string(nil), grpc.DefaultInterceptors...),
+		},
+		Mongo: Mongo{
+			URI:            "mongodb://localhost:27017",
+			Database:       "sts",
+			ConnectTimeout: 5 * time.Second,
+			MaxPoolSize:    100,
+			ReadPreference: readpref.PrimaryMode.String(),
+		},
+		Storage:    Storage{Backend: BackendMongo},
+		Migrations: migrate.DefaultConfig(),
+		Events: Events{
+			HistorySize:      events.DefaultHistorySize,
+			SubscriberBuffer: events.DefaultBufferSize,
+			Heartbeat:        15 * time.Second,
+		},
+		Outbox:        outbox.DefaultConfig(),
+		Webhooks:      webhook.DefaultConfig(),
+		Broker:        broker.DefaultConfig(),
+		Notifications: notification.DefaultConfig(),
+		Email:         mail.DefaultConfig(),
+		Exports:       export.DefaultConfig(),
+		Auth:          Auth{AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour},
+		Shutdown:      Shutdown{DrainDelay: 5 * time.Second, Timeout: 15 * time.Second},
+		Log:           logging.Config{Level: "info", Format: "json"},
+		Tracing:       tracing.Config{Exporter: "none", SampleRatio: 1},
+	}
+}
+
+// Load builds config from defaults, YAML file given by -config flag in args,
+// STS_* environment variables and STS_*_FILE secret files, in that order.
+// Empty -config skips reading file. Loaded config is validated.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	fs := flag.NewFlagSet("sts", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	path := fs.String("config", DefaultPath, "path to YAML config file")
+	if err := fs.Parse(args); err != nil {
+		return nil, errors.Wrap(err, "parse flags")
+	}
+
+	conf := Default()
+	if *path != "" {
+		if err := conf.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&conf, EnvPrefix, getenv); err != nil {
+		return nil, err
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+func (conf *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "read config file")
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err = dec.Decode(conf); err != nil && err != io.EOF {
+		return errors.Wrapf(err, "parse config file %s", path)
+	}
+
+	return nil
+}
+
+// Multiplexed reports if REST API and gRPC share single port.
+func (conf *Config) Multiplexed() bool {
+	return conf.HTTP.Port == 0 || conf.HTTP.Port == conf.GRPC.Port
+}
+
+// Errors lists all problems found in config.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
+}
+
+// Validate checks all config values and reports every problem at once.
+func (conf *Config) Validate() error {
+	var errs Errors
+	check := func(ok bool, problem string) {
+		if !ok {
+			errs = append(errs, problem)
+		}
+	}
+
+	check(validPort(conf.HTTP.Port), "http.port must be in range [0, 65535]")
+	check(conf.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout must not be negative")
+	check(validPort(conf.GRPC.Port), "grpc.port must be in range [0, 65535]")
+	check(conf.GRPC.DefaultTimeout >= 0, "grpc.default_timeout must not be negative")
+	if err := grpc.ValidateInterceptors(conf.GRPC.Interceptors); err != nil {
+		errs = append(errs, "grpc.interceptors: "+err.Error())
+	}
+	check(conf.Mongo.URI != "", "mongo.uri is not provided")
+	check(conf.Mongo.Database != "", "mongo.database is not provided")
+	check(conf.Mongo.ConnectTimeout > 0, "mongo.connect_timeout must be positive")
+	_, err := readpref.ModeFromString(conf.Mongo.ReadPreference)
+	check(err == nil, "mongo.read_preference is unknown")
+	check(conf.Storage.Backend == BackendMongo, "storage.backend must be mongo")
+	check(conf.Migrations.PollInterval > 0, "migrations.poll_interval must be positive")
+	check(conf.Migrations.LockTTL > 0, "migrations.lock_ttl must be positive")
+	check(conf.Events.HistorySize > 0, "events.history_size must be positive")
+	check(conf.Events.SubscriberBuffer > 0, "events.subscriber_buffer must be positive")
+	check(conf.Events.Heartbeat > 0, "events.heartbeat must be positive")
+	check(conf.Outbox.PollInterval > 0, "outbox.poll_interval must be positive")
+	check(conf.Outbox.BatchSize > 0, "outbox.batch_size must be positive")
+	check(conf.Outbox.LockTTL > 0, "outbox.lock_ttl must be positive")
+	check(conf.Outbox.Retention >= 0, "outbox.retention must not be negative")
+	check(conf.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(conf.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive")
+	check(conf.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(conf.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(conf.Webhooks.RetryBackoff > 0 && conf.Webhooks.MaxBackoff >= conf.Webhooks.RetryBackoff,
+		"webhooks.retry_backoff must be positive and not exceed webhooks.max_backoff")
+	check(conf.Webhooks.LockTTL > conf.Webhooks.Timeout, "webhooks.lock_ttl must exceed webhooks.timeout")
+	if conf.Broker.URL != "" {
+		check(conf.Broker.Stream != "", "broker.stream is not provided")
+		check(conf.Broker.SubjectPrefix != "", "broker.subject_prefix is not provided")
+		check(conf.Broker.Timeout > 0, "broker.timeout must be positive")
+		check(conf.Broker.Duplicates >= 0, "broker.duplicates must not be negative")
+	}
+	check(conf.Notifications.Retention >= 0 && conf.Notifications.ReadRetention >= 0,
+		"notifications retentions must not be negative")
+	switch conf.Email.Sender {
+	case mail.SenderNone:
+	case mail.SenderSMTP, mail.SenderFile:
+		check(conf.Email.From != "", "email.from is not provided")
+		check(conf.Email.Sender != mail.SenderSMTP || conf.Email.SMTP.Addr != "", "email.smtp.addr is not provided")
+		check(conf.Email.Sender != mail.SenderFile || conf.Email.Dir != "", "email.dir is not provided")
+		check(conf.Email.VerificationTTL > 0, "email.verification_ttl must be positive")
+		check(conf.Email.ReminderLead > 0, "email.reminder_lead must be positive")
+		check(conf.Email.PollInterval > 0, "email.poll_interval must be positive")
+		check(conf.Email.BatchSize > 0, "email.batch_size must be positive")
+		check(conf.Email.Timeout > 0, "email.timeout must be positive")
+		check(conf.Email.MaxAttempts > 0, "email.max_attempts must be positive")
+		check(conf.Email.RetryBackoff > 0 && conf.Email.MaxBackoff >= conf.Email.RetryBackoff,
+			"email.retry_backoff must be positive and not exceed email.max_backoff")
+		check(conf.Email.LockTTL > conf.Email.Timeout, "email.lock_ttl must exceed email.timeout")
+	default:
+		check(false, "email.sender must be one of none, smtp, file")
+	}
+	check(conf.Exports.TTL > 0, "exports.ttl must be positive")
+	check(conf.Exports.PollInterval > 0, "exports.poll_interval must be positive")
+	check(conf.Exports.Timeout > 0, "exports.timeout must be positive")
+	check(conf.Exports.LockTTL > conf.Exports.Timeout, "exports.lock_ttl must exceed exports.timeout")
+	switch {
+	case conf.Auth.Secret == "":
+		check(false, "auth.secret is not provided")
+	case conf.Auth.Secret == exampleSecret:
+		check(false, "auth.secret must not be the example value")
+	default:
+		check(len(conf.Auth.Secret) >= MinSecretLength, "auth.secret must be at least 32 bytes long")
+	}
+	check(conf.Auth.AccessTTL >= 0 && conf.Auth.RefreshTTL >= 0, "auth token TTLs must not be negative")
+	for _, c := range conf.Auth.Clients {
+		check(c.Subject != "", "auth.clients subject is not provided")
+		check(primitive.IsValidObjectID(c.UserID), "auth.clients user_id must be id of user")
+		check(storage.Role(c.Role).Valid(), "auth.clients role must be one of admin, organizer, player")
+	}
+	check(len(conf.Auth.Clients) == 0 || conf.TLS.ClientCAFile != "", "auth.clients require tls.client_ca_file")
+	if err := conf.TLS.Validate(); err != nil {
+		errs = append(errs, "tls: "+err.Error())
+	}
+	if err := conf.Mongo.TLS.Validate(); err != nil {
+		errs = append(errs, "mongo.tls: "+err.Error())
+	}
+	check(conf.Shutdown.DrainDelay >= 0, "shutdown.drain_delay must not be negative")
+	check(conf.Shutdown.Timeout >= 0, "shutdown.timeout must not be negative")
+	check(conf.Tracing.SampleRatio >= 0 && conf.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio must be in range [0, 1]")
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validPort(port int32) bool {
+	return port >= 0 && port <= 65535
+}
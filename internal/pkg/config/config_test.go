@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+grpc:
+  port: 9000
+mongo:
+  database: tournaments
+  max_pool_size: 10
+auth:
+  secret: from-file-0123456789abcdef0123456789
+`)
+	secret := writeFile(t, "secret", "from-secret-file-0123456789abcdef0123456789\n")
+
+	conf, err := Load([]string{"-config", path}, env(map[string]string{
+		"STS_MONGO_DATABASE":        "from-env",
+		"STS_MONGO_CONNECT_TIMEOUT": "2s",
+		"STS_TRACING_SAMPLE_RATIO":  "0.5",
+		"STS_GRPC_INTERCEPTORS":     "recovery, errors",
+		"STS_AUTH_SECRET":           "from-env-0123456789abcdef0123456789",
+		"STS_AUTH_SECRET_FILE":      secret,
+	}))
+	require := require.New(t)
+	require.NoError(err)
+
+	require.Equal(int32(8080), conf.HTTP.Port, "Default should be kept")
+	require.Equal(int32(9000), conf.GRPC.Port, "File should override default")
+	require.Equal(uint64(10), conf.Mongo.MaxPoolSize, "File should override default")
+	require.Equal("from-env", conf.Mongo.Database, "Env should override file")
+	require.Equal(2*time.Second, conf.Mongo.ConnectTimeout)
+	require.Equal(0.5, conf.Tracing.SampleRatio)
+	require.Equal([]string{"recovery", "errors"}, conf.GRPC.Interceptors)
+	require.Equal("from-secret-file-0123456789abcdef0123456789", conf.Auth.Secret,
+		"Secret file should override env")
+}
+
+func TestLoad_Without_File(t *testing.T) {
+	conf, err := Load([]string{"-config", ""}, env(map[string]string{"STS_AUTH_SECRET": testSecret}))
+	require := require.New(t)
+	require.NoError(err)
+
+	expected := Default()
+	expected.Auth.Secret = testSecret
+	require.Equal(&expected, conf)
+	require.False(conf.Multiplexed())
+}
+
+func TestLoad_Errors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, env(nil))
+	require.Error(err, "Missing file should fail")
+
+	_, err = Load([]string{"-config", writeFile(t, "config.yaml", "mongo:\n  url: typo\n")}, env(nil))
+	require.Error(err, "Unknown key should fail")
+
+	_, err = Load([]string{"-config", ""}, env(map[string]string{"STS_GRPC_PORT": "port"}))
+	require.Error(err, "Bad number should fail")
+}
+
+func TestValidate_Aggregates(t *testing.T) {
+	conf := Default()
+	conf.HTTP.Port = 70000
+	conf.Mongo.ReadPreference = "anywhere"
+	conf.Storage.Backend = "postgres"
+	conf.GRPC.Interceptors = []string{"retry"}
+
+	err := conf.Validate()
+	require.Equal(t, Errors{
+		"http.port must be in range [0, 65535]",
+		`grpc.interceptors: unknown interceptor "retry"`,
+		"mongo.read_preference is unknown",
+		"storage.backend must be mongo",
+		"auth.secret is not provided",
+	}, err)
+}
+
+func TestValidate_Secret(t *testing.T) {
+	conf := Default()
+	require := require.New(t)
+
+	conf.Auth.Secret = "change-me-in-production"
+	require.Equal(Errors{"auth.secret must not be the example value"}, conf.Validate())
+
+	conf.Auth.Secret = testSecret[:MinSecretLength-1]
+	require.Equal(Errors{"auth.secret must be at least 32 bytes long"}, conf.Validate())
+
+	conf.Auth.Secret = testSecret
+	require.NoError(conf.Validate())
+}
+
+func TestValidate_TLS(t *testing.T) {
+	conf := Default()
+	conf.Auth.Secret = testSecret
+	conf.Auth.Clients = []Client{
+		{Subject: "billing", UserID: "billing", Role: "superuser"},
+		{Subject: "reports", Role: "admin"},
+	}
+	conf.TLS.CertFile = "tls.crt"
+	conf.Mongo.TLS.CertFile = "client.crt"
+
+	err := conf.Validate()
+	require.Equal(t, Errors{
+		"auth.clients user_id must be id of user",
+		"auth.clients role must be one of admin, organizer, player",
+		"auth.clients user_id must be id of user",
+		"auth.clients require tls.client_ca_file",
+		"tls: cert_file and key_file must be set together",
+		"mongo.tls: cert_file and key_file must be set together",
+	}, err)
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides config fields with environment variables named after
+// YAML keys, e.g. STS_MONGO_MAX_POOL_SIZE sets mongo.max_pool_size.
+// String fields can also be read from file named by variable with
+// _FILE suffix, which takes precedence, e.g. STS_AUTH_SECRET_FILE.
+// Lists of strings are comma separated.
+func applyEnv(conf *Config, prefix string, getenv func(string) string) error {
+	return walk(reflect.ValueOf(conf).Elem(), prefix, func(name string, field reflect.Value) error {
+		if value := getenv(name); value != "" {
+			if err := setValue(field, value); err != nil {
+				return errors.Wrapf(err, "parse %s", name)
+			}
+		}
+
+		if field.Kind() != reflect.String {
+			return nil
+		}
+		if path := getenv(name + "_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "read %s_FILE", name)
+			}
+			field.SetString(strings.TrimRight(string(data), "\r\n"))
+		}
+
+		return nil
+	})
+}
+
+// walk calls fn for every leaf field of struct v with its variable name.
+func walk(v reflect.Value, prefix string, fn func(name string, field reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(key)
+		field := v.Field(i)
+		var err error
+		if field.Kind() == reflect.Struct {
+			err = walk(field, name, fn)
+		} else {
+			err = fn(name, field)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return errors.Errorf("unsupported type %s", field.Type())
+		}
+		items := strings.Split(value, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return errors.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package events fans out tournament changes made through storage
+// to live subscribers and keeps recent history for resuming.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// Type tells what happened to tournament or user balance.
+type Type string
+
+// Types of published events.
+const (
+	TypePlayerJoined  Type = "playerJoined"
+	TypePlayerLeft    Type = "playerLeft"
+	TypePrizeChanged  Type = "prizeChanged"
+	TypeStatusChanged Type = "statusChanged"
+	TypeWinnerSet     Type = "winnerSet"
+	TypeCanceled      Type = "canceled"
+
+	// TypeBalanceChanged is published for user balance and has no TournamentID,
+	// so tournament watchers never see balances of other users.
+	TypeBalanceChanged Type = "balanceChanged"
+)
+
+// Event is a single tournament or balance change.
+type Event struct {
+	Position     Position
+	Type         Type
+	TournamentID string
+
+	// UserID is player who joined or left, winner or owner of balance.
+	UserID  string
+	Prize   float64
+	Status  storage.TournamentStatus
+	Balance float64
+	Time    time.Time
+}
+
+// Position identifies event in stream of hub. Epoch changes on every
+// start of hub, so positions issued before restart are not resumable.
+type Position struct {
+	Epoch string
+	Seq   uint64
+}
+
+// IsZero reports if p points to no event.
+func (p Position) IsZero() bool {
+	return p == Position{}
+}
+
+// String returns opaque representation of p handed to clients.
+func (p Position) String() string {
+	if p.IsZero() {
+		return ""
+	}
+
+	return p.Epoch + "." + strconv.FormatUint(p.Seq, 10)
+}
+
+// ParsePosition parses position returned by Position.String.
+func ParsePosition(s string) (Position, error) {
+	if s == "" {
+		return Position{}, nil
+	}
+
+	epoch, seq, ok := strings.Cut(s, ".")
+	if !ok || epoch == "" {
+		return Position{}, errors.Errorf("malformed position %q", s)
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return Position{}, errors.Wrapf(err, "malformed position %q", s)
+	}
+
+	return Position{Epoch: epoch, Seq: n}, nil
+}
+
+func newEpoch() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Filter selects events subscriber is interested in.
+type Filter func(Event) bool
+
+// ForTournament selects events of tournament with provided id.
+func ForTournament(id string) Filter {
+	return func(e Event) bool {
+		return e.TournamentID == id
+	}
+}
+
+// ForBalance selects balance changes of user with provided id.
+func ForBalance(userID string) Filter {
+	return func(e Event) bool {
+		return e.Type == TypeBalanceChanged && e.UserID == userID
+	}
+}
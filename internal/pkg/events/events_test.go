@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func next(t *testing.T, sub *Subscription) Event {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	e, err := sub.Next(ctx)
+	require.NoError(t, err)
+	return e
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := NewHub(3, 8)
+	require := require.New(t)
+
+	sub := hub.Subscribe(Position{}, ForTournament("t1"))
+	require.False(sub.Resumed, "Subscription without position should not be resumed")
+	hub.Publish(Event{Type: TypePlayerJoined, TournamentID: "t1"}, Event{Type: TypePlayerJoined, TournamentID: "t2"})
+	hub.Publish(Event{Type: TypePrizeChanged, TournamentID: "t1"})
+
+	first := next(t, sub)
+	require.Equal(TypePlayerJoined, first.Type)
+	require.Equal(TypePrizeChanged, next(t, sub).Type, "Events of other tournaments should be filtered out")
+	sub.Close()
+
+	// reconnect after first event, second one is replayed from history
+	hub.Publish(Event{Type: TypePlayerLeft, TournamentID: "t1"})
+	position, err := ParsePosition(first.Position.String())
+	require.NoError(err)
+	sub = hub.Subscribe(position, ForTournament("t1"))
+	defer sub.Close()
+	require.True(sub.Resumed)
+	require.Equal(TypePrizeChanged, next(t, sub).Type)
+	require.Equal(TypePlayerLeft, next(t, sub).Type)
+
+	// first event is evicted from history of size 3
+	hub.Publish(Event{Type: TypeStatusChanged, TournamentID: "t1"})
+	require.False(hub.Subscribe(position, ForTournament("t1")).Resumed, "Evicted position should not be resumed")
+	require.False(hub.Subscribe(Position{Epoch: "other", Seq: 1}, ForTournament("t1")).Resumed,
+		"Position of other epoch should not be resumed")
+
+	_, err = ParsePosition("garbage")
+	require.Error(err)
+}
+
+func TestHub_Lagged(t *testing.T) {
+	hub := NewHub(16, 2)
+	require := require.New(t)
+
+	slow := hub.Subscribe(Position{}, ForTournament("t1"))
+	fast := hub.Subscribe(Position{}, ForTournament("t1"))
+	defer fast.Close()
+	hub.Publish(Event{TournamentID: "t1"}, Event{TournamentID: "t1"})
+	next(t, fast)
+	next(t, fast)
+	hub.Publish(Event{TournamentID: "t1"})
+
+	require.Equal(uint64(3), next(t, fast).Position.Seq)
+	next(t, slow)
+	next(t, slow)
+	_, err := slow.Next(context.Background())
+	require.ErrorIs(err, ErrLagged, "Slow subscriber should be dropped instead of blocking publisher")
+	slow.Close()
+}
+
+func TestPublishingService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().JoinTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(userID)).Times(1).Return(nil)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).
+		Return(&storage.Tournament{Deposit: 100, Prize: 100}, nil)
+	mock.EXPECT().LeaveTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(userID)).Times(1).
+		Return(errors.New("remove user from tournament list"))
+	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(userID)).Times(1).Return(nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(1).Return(&storage.User{Balance: 100}, nil)
+	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).Return(nil)
+
+	hub := NewHub(16, 16)
+	sub := hub.Subscribe(Position{}, ForTournament(tournamentID))
+	defer sub.Close()
+	balance := hub.Subscribe(Position{}, ForBalance(userID))
+	defer balance.Close()
+	db := Publish(mock, hub)
+	require := require.New(t)
+
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.Error(db.LeaveTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.FinishTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.DeleteTournament(context.TODO(), tournamentID))
+
+	expected := []Event{
+		{Type: TypePlayerJoined, UserID: userID},
+		{Type: TypePrizeChanged, Prize: 100},
+		{Type: TypeWinnerSet, UserID: userID},
+		{Type: TypeStatusChanged, Status: storage.StatusFinished},
+		{Type: TypeCanceled},
+	}
+	for _, e := range expected {
+		actual := next(t, sub)
+		require.Equal(e.Type, actual.Type)
+		require.Equal(tournamentID, actual.TournamentID)
+		require.Equal(e.UserID, actual.UserID)
+		require.Equal(e.Prize, actual.Prize)
+		require.Equal(e.Status, actual.Status)
+	}
+	require.Equal(uint64(6), hub.Subscribe(Position{}, ForTournament(tournamentID)).Position.Seq,
+		"Failed mutation should not be published")
+
+	e := next(t, balance)
+	require.Equal(TypeBalanceChanged, e.Type)
+	require.Empty(e.TournamentID, "Balance should not be published to tournament watchers")
+	require.Equal(100.0, e.Balance)
+}
+
+func TestPublishingService_Balance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID().Hex()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Eq(userID), gomock.Eq(50.0)).Times(1).Return(nil)
+	mock.EXPECT().TakeUserBalance(gomock.Any(), gomock.Eq(userID), gomock.Eq(80.0)).Times(1).
+		Return(errors.New("not enough points"))
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(1).Return(&storage.User{Balance: 50}, nil)
+
+	hub := NewHub(16, 16)
+	sub := hub.Subscribe(Position{}, ForBalance(userID))
+	defer sub.Close()
+	other := hub.Subscribe(Position{}, ForBalance(primitive.NewObjectID().Hex()))
+	defer other.Close()
+	db := Publish(mock, hub)
+	require := require.New(t)
+
+	require.NoError(db.FundUserBalance(context.TODO(), userID, 50))
+	require.Error(db.TakeUserBalance(context.TODO(), userID, 80))
+
+	e := next(t, sub)
+	require.Equal(TypeBalanceChanged, e.Type)
+	require.Equal(userID, e.UserID)
+	require.Equal(50.0, e.Balance)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := other.Next(ctx)
+	require.ErrorIs(err, context.DeadlineExceeded, "Balance of other user should be filtered out")
+}
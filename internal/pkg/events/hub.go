@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLagged is returned to subscriber which didn't keep up with events and
+// was dropped. It can subscribe again from position of last received event.
+var ErrLagged = errors.New("subscriber lagged behind")
+
+// Default sizes of hub history and subscriber buffer.
+const (
+	DefaultHistorySize = 1024
+	DefaultBufferSize  = 64
+)
+
+// Hub assigns positions to published events, remembers last of them
+// and delivers them to subscribers without ever blocking publisher.
+type Hub struct {
+	epoch      string
+	bufferSize int
+
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	next    int
+	subs    map[*Subscription]struct{}
+}
+
+// NewHub creates hub remembering historySize last events for resuming
+// subscribers and buffering up to bufferSize events per subscriber.
+func NewHub(historySize, bufferSize int) *Hub {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	return &Hub{
+		epoch:      newEpoch(),
+		bufferSize: bufferSize,
+		history:    make([]Event, 0, historySize),
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns positions to events in provided order and delivers them.
+// Subscribers with full buffer are dropped with ErrLagged.
+func (h *Hub) Publish(events ...Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range events {
+		h.seq++
+		e.Position = Position{Epoch: h.epoch, Seq: h.seq}
+		if len(h.history) < cap(h.history) {
+			h.history = append(h.history, e)
+		} else {
+			h.history[h.next] = e
+			h.next = (h.next + 1) % len(h.history)
+		}
+
+		for s := range h.subs {
+			if !s.filter(e) {
+				continue
+			}
+			select {
+			case s.ch <- e:
+			default:
+				h.remove(s)
+			}
+		}
+	}
+}
+
+// Subscribe starts delivery of events matching filter. If from is within
+// remembered history, events after it are replayed and Resumed is set.
+// Otherwise only new events are delivered.
+func (h *Hub) Subscribe(from Position, filter Filter) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Subscription{
+		hub:      h,
+		filter:   filter,
+		ch:       make(chan Event, h.bufferSize),
+		Position: Position{Epoch: h.epoch, Seq: h.seq},
+	}
+	if !from.IsZero() && from.Epoch == h.epoch && from.Seq <= h.seq &&
+		from.Seq+uint64(len(h.history)) >= h.seq {
+		s.Resumed = true
+		for i := range h.history {
+			e := h.history[(h.next+i)%len(h.history)]
+			if e.Position.Seq > from.Seq && filter(e) {
+				s.replay = append(s.replay, e)
+			}
+		}
+	}
+	h.subs[s] = struct{}{}
+
+	return s
+}
+
+// remove must be called with mu held.
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscription receives events from hub.
+type Subscription struct {
+	// Position is position of last event published before subscription.
+	Position Position
+
+	// Resumed is set if subscription continues from requested position.
+	Resumed bool
+
+	hub    *Hub
+	filter Filter
+	replay []Event
+	ch     chan Event
+}
+
+// Next returns next event. It fails with ErrLagged if subscriber was dropped
+// for being slow and with ctx error if ctx is done.
+func (s *Subscription) Next(ctx context.Context) (Event, error) {
+	if len(s.replay) > 0 {
+		e := s.replay[0]
+		s.replay = s.replay[1:]
+		return e, nil
+	}
+
+	select {
+	case e, ok := <-s.ch:
+		if !ok {
+			return Event{}, ErrLagged
+		}
+		return e, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Close stops delivery of events.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
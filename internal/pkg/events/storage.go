@@ -0,0 +1,127 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+var _ storage.Service = (*PublishingService)(nil)
+
+// PublishingService is storage.Service decorator which publishes events
+// after tournament and balance mutations succeed. Other methods are passed through.
+type PublishingService struct {
+	storage.Service
+	hub *Hub
+}
+
+// Publish wraps db so that its tournament and balance mutations are published to hub.
+func Publish(db storage.Service, hub *Hub) *PublishingService {
+	return &PublishingService{Service: db, hub: hub}
+}
+
+// JoinTournament implements storage.Service.
+func (s *PublishingService) JoinTournament(ctx context.Context, tournamentID, userID string) error {
+	if err := s.Service.JoinTournament(ctx, tournamentID, userID); err != nil {
+		return err
+	}
+
+	s.publishMember(ctx, TypePlayerJoined, tournamentID, userID)
+	return nil
+}
+
+// LeaveTournament implements storage.Service.
+func (s *PublishingService) LeaveTournament(ctx context.Context, tournamentID, userID string) error {
+	if err := s.Service.LeaveTournament(ctx, tournamentID, userID); err != nil {
+		return err
+	}
+
+	s.publishMember(ctx, TypePlayerLeft, tournamentID, userID)
+	return nil
+}
+
+// SetTournamentStatus implements storage.Service.
+func (s *PublishingService) SetTournamentStatus(ctx context.Context, tournamentID string,
+	status storage.TournamentStatus) error {
+	if err := s.Service.SetTournamentStatus(ctx, tournamentID, status); err != nil {
+		return err
+	}
+
+	s.hub.Publish(Event{Type: TypeStatusChanged, TournamentID: tournamentID, Status: status, Time: time.Now()})
+	return nil
+}
+
+// FinishTournament implements storage.Service.
+func (s *PublishingService) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) error {
+	if err := s.Service.FinishTournament(ctx, tournamentID, winnerUserID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.hub.Publish(
+		Event{Type: TypeWinnerSet, TournamentID: tournamentID, UserID: winnerUserID, Time: now},
+		Event{Type: TypeStatusChanged, TournamentID: tournamentID, Status: storage.StatusFinished, Time: now},
+	)
+	s.publishBalance(ctx, winnerUserID)
+	return nil
+}
+
+// FundUserBalance implements storage.Service.
+func (s *PublishingService) FundUserBalance(ctx context.Context, id string, points float64) error {
+	if err := s.Service.FundUserBalance(ctx, id, points); err != nil {
+		return err
+	}
+
+	s.publishBalance(ctx, id)
+	return nil
+}
+
+// TakeUserBalance implements storage.Service.
+func (s *PublishingService) TakeUserBalance(ctx context.Context, id string, points float64) error {
+	if err := s.Service.TakeUserBalance(ctx, id, points); err != nil {
+		return err
+	}
+
+	s.publishBalance(ctx, id)
+	return nil
+}
+
+// DeleteTournament implements storage.Service.
+func (s *PublishingService) DeleteTournament(ctx context.Context, id string) error {
+	if err := s.Service.DeleteTournament(ctx, id); err != nil {
+		return err
+	}
+
+	s.hub.Publish(Event{Type: TypeCanceled, TournamentID: id, Time: time.Now()})
+	return nil
+}
+
+// publishMember publishes membership change followed by new prize. Prize
+// is read after change, so event is skipped if tournament can't be read.
+func (s *PublishingService) publishMember(ctx context.Context, typ Type, tournamentID, userID string) {
+	now := time.Now()
+	member := Event{Type: typ, TournamentID: tournamentID, UserID: userID, Time: now}
+
+	tournament, err := s.Service.GetTournament(ctx, tournamentID)
+	if err != nil {
+		slog.WarnContext(ctx, "read tournament prize for event", "tournamentID", tournamentID, "err", err)
+		s.hub.Publish(member)
+		return
+	}
+
+	s.hub.Publish(member, Event{Type: TypePrizeChanged, TournamentID: tournamentID, Prize: tournament.Prize, Time: now})
+}
+
+// publishBalance publishes new balance of user. Balance is read after change,
+// so event is skipped if user can't be read.
+func (s *PublishingService) publishBalance(ctx context.Context, userID string) {
+	user, err := s.Service.GetUser(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "read user balance for event", "userID", userID, "err", err)
+		return
+	}
+
+	s.hub.Publish(Event{Type: TypeBalanceChanged, UserID: userID, Balance: user.Balance, Time: time.Now()})
+}
@@ -0,0 +1,400 @@
+// Package export builds archives of personal data users request for privacy
+// reasons. Archive is built in background and is downloaded by signed link
+// which expires together with the archive.
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// lockName is name of lease which lets single instance build archives.
+	lockName = "exports"
+
+	// purgeInterval is period of removing expired archives.
+	purgeInterval = time.Hour
+
+	// auditPage is number of audit entries read at once while collecting balance history.
+	auditPage = 1000
+)
+
+// ErrInvalidLink is returned by Open if link signature doesn't match or link is expired.
+var ErrInvalidLink = errors.New("invalid or expired download link")
+
+// Config configures building and keeping archives.
+type Config struct {
+	// TTL is how long built archive and its download link are kept.
+	TTL          time.Duration `yaml:"ttl"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Timeout limits building single archive.
+	Timeout time.Duration `yaml:"timeout"`
+	// LockTTL is lease of instance building archives, it must outlive Timeout.
+	LockTTL time.Duration `yaml:"lock_ttl"`
+}
+
+// DefaultConfig returns config used when exports section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		TTL:          24 * time.Hour,
+		PollInterval: 5 * time.Second,
+		Timeout:      5 * time.Minute,
+		LockTTL:      6 * time.Minute,
+	}
+}
+
+// Store is part of storage.DB exporter works with.
+type Store interface {
+	GetUser(ctx context.Context, id string) (*storage.User, error)
+	GetCredentialsByUserID(ctx context.Context, userID string) (*storage.Credentials, error)
+	ListUserTournaments(ctx context.Context, userID string) ([]storage.Tournament, error)
+	ListNotifications(ctx context.Context, userID string, unread bool, limit int64) ([]storage.Notification, error)
+	ListAuditEntries(ctx context.Context, f storage.AuditFilter) ([]storage.AuditEntry, error)
+	RequestExport(ctx context.Context, userID string, now time.Time) (*storage.Export, error)
+	GetExport(ctx context.Context, userID, id string) (*storage.Export, error)
+	PendingExports(ctx context.Context, limit int64) ([]storage.Export, error)
+	UpdateExport(ctx context.Context, e *storage.Export) error
+	DeleteExpiredExports(ctx context.Context, before time.Time) (int64, error)
+	SaveExportArchive(ctx context.Context, id primitive.ObjectID, r io.Reader) error
+	OpenExportArchive(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error)
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// Exporter queues exports requested by users, builds their archives
+// and signs links downloading them.
+type Exporter struct {
+	Store
+	secret []byte
+	conf   Config
+	owner  string
+	now    func() time.Time
+}
+
+// profile is user info together with his login.
+type profile struct {
+	*storage.User
+	Login string `json:"login,omitempty"`
+}
+
+// balanceChange is entry of balance history.
+type balanceChange struct {
+	Time   time.Time           `json:"time"`
+	Action storage.AuditAction `json:"action"`
+	// Amount is positive if balance is increased.
+	Amount       float64 `json:"amount"`
+	TournamentID string  `json:"tournamentId,omitempty"`
+}
+
+// NewExporter creates exporter keeping archives in store. Download
+// links are signed with secret.
+func NewExporter(store Store, secret []byte, conf Config) *Exporter {
+	defaults := DefaultConfig()
+	if conf.TTL <= 0 {
+		conf.TTL = defaults.TTL
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaults.PollInterval
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaults.Timeout
+	}
+	if conf.LockTTL <= conf.Timeout {
+		conf.LockTTL = conf.Timeout + time.Minute
+	}
+
+	host, _ := os.Hostname()
+	return &Exporter{
+		Store:  store,
+		secret: secret,
+		conf:   conf,
+		owner:  fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+		now:    time.Now,
+	}
+}
+
+// Request returns export of user which is being built or is ready.
+// New export is queued if there is none.
+func (x *Exporter) Request(ctx context.Context, userID string) (*storage.Export, error) {
+	e, err := x.RequestExport(ctx, userID, x.now())
+	if err != nil {
+		return nil, errors.Wrapf(err, "request export of user %s", userID)
+	}
+
+	return e, nil
+}
+
+// Link returns path downloading archive of ready export. It's signed
+// and is valid until export expires.
+func (x *Exporter) Link(e *storage.Export) string {
+	if e.Status != storage.ExportReady || e.ExpiresAt == nil {
+		return ""
+	}
+
+	expires := e.ExpiresAt.Unix()
+	return fmt.Sprintf("/user/%s/export/%s/archive?expires=%d&signature=%s",
+		e.UserID, e.ID.Hex(), expires, x.sign(e.UserID, e.ID.Hex(), expires))
+}
+
+// Open checks link parameters and returns archive of export. It returns
+// ErrInvalidLink if signature doesn't match or link is expired.
+func (x *Exporter) Open(ctx context.Context, userID, id, expires, signature string) (io.ReadCloser, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	expected := x.sign(userID, id, exp)
+	if !hmac.Equal([]byte(signature), []byte(expected)) || !x.now().Before(time.Unix(exp, 0)) {
+		return nil, ErrInvalidLink
+	}
+
+	e, err := x.GetExport(ctx, userID, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get export %s", id)
+	}
+	if e.Status != storage.ExportReady {
+		return nil, errors.Wrapf(mongo.ErrNoDocuments, "export %s is %s", id, e.Status)
+	}
+
+	archive, err := x.OpenExportArchive(ctx, e.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open archive of export %s", id)
+	}
+
+	return archive, nil
+}
+
+func (x *Exporter) sign(userID, id string, expires int64) string {
+	mac := hmac.New(sha256.New, x.secret)
+	fmt.Fprintf(mac, "export:%s:%s:%d", userID, id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run builds queued archives and removes expired ones until ctx is done.
+// Only instance holding lease builds them, others wait for it to expire.
+func (x *Exporter) Run(ctx context.Context) {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), x.conf.PollInterval)
+		defer cancel()
+		if err := x.ReleaseLock(ctx, lockName, x.owner); err != nil {
+			slog.Error("error releasing exports lock", "err", err)
+		}
+	}()
+
+	ticker := time.NewTicker(x.conf.PollInterval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+	for {
+		_, err := x.Build(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error building exports", "err", err)
+		}
+		if err == nil && time.Since(lastPurge) > purgeInterval {
+			x.purge(ctx)
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Build builds archives of pending exports one by one and returns number
+// of completed exports. Export whose archive can't be built is marked failed,
+// so error is returned only if store fails. Nothing is built if lease is held
+// by other instance.
+func (x *Exporter) Build(ctx context.Context) (int, error) {
+	completed := 0
+	for {
+		ok, err := x.AcquireLock(ctx, lockName, x.owner, x.conf.LockTTL)
+		if err != nil {
+			return completed, errors.Wrap(err, "acquire lock")
+		}
+		if !ok {
+			return completed, nil
+		}
+
+		pending, err := x.PendingExports(ctx, 1)
+		if err != nil {
+			return completed, errors.Wrap(err, "read pending exports")
+		}
+		if len(pending) == 0 {
+			return completed, nil
+		}
+
+		e := &pending[0]
+		if err = x.build(ctx, e); err != nil {
+			if ctx.Err() != nil {
+				return completed, ctx.Err()
+			}
+			slog.WarnContext(ctx, "error building export", "export", e.ID.Hex(), "user", e.UserID, "err", err)
+			e.Status, e.LastError = storage.ExportFailed, err.Error()
+		} else {
+			e.Status, e.LastError = storage.ExportReady, ""
+		}
+		now := x.now().UTC()
+		expiresAt := now.Add(x.conf.TTL)
+		e.CompletedAt, e.ExpiresAt = &now, &expiresAt
+		if err = x.UpdateExport(ctx, e); err != nil {
+			return completed, errors.Wrapf(err, "update export %s", e.ID.Hex())
+		}
+		completed++
+	}
+}
+
+// build collects personal data of user and stores it as zip of JSON files.
+func (x *Exporter) build(ctx context.Context, e *storage.Export) error {
+	ctx, cancel := context.WithTimeout(ctx, x.conf.Timeout)
+	defer cancel()
+
+	files, err := x.collect(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeArchive(w, files))
+	}()
+	err = x.SaveExportArchive(ctx, e.ID, r)
+	// unblocks writer if archive isn't read to the end
+	r.Close()
+	if err != nil {
+		return errors.Wrap(err, "save archive")
+	}
+
+	return nil
+}
+
+// file is JSON file of archive.
+type file struct {
+	name string
+	data interface{}
+}
+
+func (x *Exporter) collect(ctx context.Context, userID string) ([]file, error) {
+	user, err := x.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user")
+	}
+	p := profile{User: user}
+	creds, err := x.GetCredentialsByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errors.Wrap(err, "get credentials")
+	}
+	if creds != nil {
+		p.Login = creds.Login
+	}
+
+	tournaments, err := x.ListUserTournaments(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list tournaments")
+	}
+	balance, err := x.balanceHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := x.ListNotifications(ctx, userID, false, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "list notifications")
+	}
+
+	return []file{
+		{"profile.json", p},
+		{"balance.json", balance},
+		{"tournaments.json", tournaments},
+		{"notifications.json", notifications},
+	}, nil
+}
+
+// balanceHistory returns balance changes of user recorded in audit log,
+// oldest first. Tournament prizes are included as well.
+func (x *Exporter) balanceHistory(ctx context.Context, userID string) ([]balanceChange, error) {
+	history := []balanceChange{}
+	filter := storage.AuditFilter{TargetID: userID, Limit: auditPage}
+	for {
+		entries, err := x.ListAuditEntries(ctx, filter)
+		if err != nil {
+			return nil, errors.Wrap(err, "list audit entries")
+		}
+
+		for _, e := range entries {
+			change := balanceChange{Time: e.Time, Action: e.Action}
+			switch e.Action {
+			case storage.AuditBalanceFunded:
+				change.Amount = e.Amount
+			case storage.AuditBalanceTaken:
+				change.Amount = -e.Amount
+			case storage.AuditTournamentFinished:
+				// targets are tournament and its winner
+				if len(e.TargetIDs) < 2 || e.TargetIDs[1] != userID {
+					continue
+				}
+				change.TournamentID = e.TargetIDs[0]
+				change.Amount, _ = e.Before["prize"].(float64)
+			default:
+				continue
+			}
+			history = append(history, change)
+		}
+
+		if int64(len(entries)) < filter.Limit {
+			break
+		}
+		filter.BeforeID = entries[len(entries)-1].ID
+	}
+
+	// entries are read newest first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	return history, nil
+}
+
+func writeArchive(w io.Writer, files []file) error {
+	archive := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := archive.Create(f.name)
+		if err != nil {
+			return errors.Wrapf(err, "create %s", f.name)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(f.data); err != nil {
+			return errors.Wrapf(err, "encode %s", f.name)
+		}
+	}
+
+	return errors.Wrap(archive.Close(), "close archive")
+}
+
+func (x *Exporter) purge(ctx context.Context) {
+	deleted, err := x.DeleteExpiredExports(ctx, x.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "error removing expired exports", "err", err)
+		return
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "expired exports removed", "count", deleted)
+	}
+}
@@ -0,0 +1,265 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// memoryStore keeps user data, exports and their archives in memory.
+type memoryStore struct {
+	users         map[string]*storage.User
+	tournaments   []storage.Tournament
+	notifications []storage.Notification
+	audit         []storage.AuditEntry
+	exports       []storage.Export
+	archives      map[primitive.ObjectID][]byte
+	err           error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: map[string]*storage.User{}, archives: map[primitive.ObjectID][]byte{}}
+}
+
+func (s *memoryStore) GetUser(_ context.Context, id string) (*storage.User, error) {
+	if user, ok := s.users[id]; ok {
+		return user, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) GetCredentialsByUserID(_ context.Context, userID string) (*storage.Credentials, error) {
+	return &storage.Credentials{Login: "gena", PasswordHash: []byte("secret")}, nil
+}
+
+func (s *memoryStore) ListUserTournaments(context.Context, string) ([]storage.Tournament, error) {
+	return s.tournaments, s.err
+}
+
+func (s *memoryStore) ListNotifications(context.Context, string, bool, int64) ([]storage.Notification, error) {
+	return s.notifications, nil
+}
+
+func (s *memoryStore) ListAuditEntries(_ context.Context, f storage.AuditFilter) ([]storage.AuditEntry, error) {
+	var entries []storage.AuditEntry
+	for _, e := range s.audit {
+		if (f.BeforeID.IsZero() || e.ID.Hex() < f.BeforeID.Hex()) && int64(len(entries)) < f.Limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) RequestExport(_ context.Context, userID string, now time.Time) (*storage.Export, error) {
+	for i := range s.exports {
+		e := &s.exports[i]
+		if e.UserID == userID && (e.Status == storage.ExportPending ||
+			e.Status == storage.ExportReady && e.ExpiresAt.After(now)) {
+			return e, nil
+		}
+	}
+	s.exports = append(s.exports, storage.Export{ID: primitive.NewObjectID(), UserID: userID,
+		Status: storage.ExportPending, CreatedAt: now})
+	return &s.exports[len(s.exports)-1], nil
+}
+
+func (s *memoryStore) GetExport(_ context.Context, userID, id string) (*storage.Export, error) {
+	for _, e := range s.exports {
+		if e.UserID == userID && e.ID.Hex() == id {
+			return &e, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) PendingExports(_ context.Context, limit int64) ([]storage.Export, error) {
+	var pending []storage.Export
+	for _, e := range s.exports {
+		if e.Status == storage.ExportPending && int64(len(pending)) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memoryStore) UpdateExport(_ context.Context, e *storage.Export) error {
+	for i := range s.exports {
+		if s.exports[i].ID == e.ID {
+			s.exports[i] = *e
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteExpiredExports(_ context.Context, before time.Time) (int64, error) {
+	var kept []storage.Export
+	for _, e := range s.exports {
+		if e.ExpiresAt != nil && e.ExpiresAt.Before(before) {
+			delete(s.archives, e.ID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	deleted := int64(len(s.exports) - len(kept))
+	s.exports = kept
+	return deleted, nil
+}
+
+func (s *memoryStore) SaveExportArchive(_ context.Context, id primitive.ObjectID, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.archives[id] = data
+	return nil
+}
+
+func (s *memoryStore) OpenExportArchive(_ context.Context, id primitive.ObjectID) (io.ReadCloser, error) {
+	data, ok := s.archives[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStore) AcquireLock(context.Context, string, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseLock(context.Context, string, string) error {
+	return nil
+}
+
+func TestExporter(t *testing.T) {
+	userID := primitive.NewObjectID()
+	tournamentID := primitive.NewObjectID().Hex()
+	store := newMemoryStore()
+	store.users[userID.Hex()] = &storage.User{ID: userID, Name: "Gena", Balance: 70, Role: storage.RolePlayer,
+		Verification: &storage.EmailVerification{TokenHash: "secret"}}
+	store.tournaments = []storage.Tournament{{Name: "Cup", Winner: userID}}
+	store.notifications = []storage.Notification{{UserID: userID.Hex(), Kind: storage.NotificationTournamentWon}}
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	// newest first, as audit log is read
+	store.audit = []storage.AuditEntry{
+		{ID: ids[2], Action: storage.AuditTournamentFinished, TargetIDs: []string{tournamentID, userID.Hex()},
+			Before: map[string]interface{}{"prize": 40.0}},
+		{ID: ids[1], Action: storage.AuditBalanceTaken, TargetIDs: []string{userID.Hex()}, Amount: 20},
+		{ID: ids[0], Action: storage.AuditBalanceFunded, TargetIDs: []string{userID.Hex()}, Amount: 50},
+	}
+
+	x := NewExporter(store, []byte("key"), Config{})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	x.now = func() time.Time { return now }
+	require := require.New(t)
+
+	e, err := x.Request(context.TODO(), userID.Hex())
+	require.NoError(err)
+	require.Equal(storage.ExportPending, e.Status)
+	require.Empty(x.Link(e), "Pending export should have no link")
+	again, err := x.Request(context.TODO(), userID.Hex())
+	require.NoError(err)
+	require.Equal(e.ID, again.ID, "Pending export should be reused")
+
+	completed, err := x.Build(context.TODO())
+	require.NoError(err)
+	require.Equal(1, completed)
+	e, err = x.GetExport(context.TODO(), userID.Hex(), e.ID.Hex())
+	require.NoError(err)
+	require.Equal(storage.ExportReady, e.Status)
+	require.Equal(now.Add(DefaultConfig().TTL), *e.ExpiresAt)
+
+	link, err := url.Parse(x.Link(e))
+	require.NoError(err)
+	require.Equal("/user/"+userID.Hex()+"/export/"+e.ID.Hex()+"/archive", link.Path)
+	archive, err := x.Open(context.TODO(), userID.Hex(), e.ID.Hex(),
+		link.Query().Get("expires"), link.Query().Get("signature"))
+	require.NoError(err)
+	data, err := io.ReadAll(archive)
+	require.NoError(err)
+
+	files := readArchive(t, data)
+	require.Len(files, 4)
+	var p map[string]interface{}
+	require.NoError(json.Unmarshal(files["profile.json"], &p))
+	require.Equal("Gena", p["name"])
+	require.Equal("gena", p["login"])
+	require.NotContains(string(files["profile.json"]), "secret", "Secrets should not be exported")
+	var balance []balanceChange
+	require.NoError(json.Unmarshal(files["balance.json"], &balance))
+	require.Len(balance, 3)
+	require.Equal(50.0, balance[0].Amount, "History should start with the oldest change")
+	require.Equal(-20.0, balance[1].Amount)
+	require.Equal(40.0, balance[2].Amount)
+	require.Equal(tournamentID, balance[2].TournamentID)
+	require.Contains(string(files["tournaments.json"]), "Cup")
+	require.Contains(string(files["notifications.json"]), "tournamentWon")
+
+	_, err = x.Open(context.TODO(), userID.Hex(), e.ID.Hex(), link.Query().Get("expires"), "bad")
+	require.ErrorIs(err, ErrInvalidLink)
+	_, err = x.Open(context.TODO(), primitive.NewObjectID().Hex(), e.ID.Hex(),
+		link.Query().Get("expires"), link.Query().Get("signature"))
+	require.ErrorIs(err, ErrInvalidLink, "Link should be valid only for user it's signed for")
+
+	now = now.Add(DefaultConfig().TTL)
+	_, err = x.Open(context.TODO(), userID.Hex(), e.ID.Hex(),
+		link.Query().Get("expires"), link.Query().Get("signature"))
+	require.ErrorIs(err, ErrInvalidLink, "Link should expire with export")
+
+	now = now.Add(time.Second)
+	x.purge(context.TODO())
+	require.Empty(store.exports)
+	require.Empty(store.archives)
+}
+
+func TestExporter_Failed(t *testing.T) {
+	userID := primitive.NewObjectID()
+	store := newMemoryStore()
+	store.users[userID.Hex()] = &storage.User{ID: userID, Name: "Gena"}
+	store.err = errors.New("connection reset")
+
+	x := NewExporter(store, []byte("key"), Config{})
+	require := require.New(t)
+
+	e, err := x.Request(context.TODO(), userID.Hex())
+	require.NoError(err)
+	completed, err := x.Build(context.TODO())
+	require.NoError(err, "Failed export should not stop building others")
+	require.Equal(1, completed)
+
+	e, err = x.GetExport(context.TODO(), userID.Hex(), e.ID.Hex())
+	require.NoError(err)
+	require.Equal(storage.ExportFailed, e.Status)
+	require.Contains(e.LastError, "connection reset")
+	require.Empty(store.archives)
+
+	again, err := x.Request(context.TODO(), userID.Hex())
+	require.NoError(err)
+	require.NotEqual(e.ID, again.ID, "Failed export should be requested again")
+}
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	return files
+}
@@ -0,0 +1,181 @@
+// Package health implements liveness and readiness probes for HTTP and gRPC.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// DefaultTimeout limits duration of all readiness checks.
+	DefaultTimeout = 2 * time.Second
+
+	// DefaultInterval is period of gRPC health status refresh.
+	DefaultInterval = 5 * time.Second
+)
+
+// ErrDraining is reported by readiness check after shutdown has begun.
+var ErrDraining = errors.New("server is shutting down")
+
+// CheckFunc returns nil if dependency is ready to serve requests.
+type CheckFunc func(ctx context.Context) error
+
+// Check is named readiness check.
+type Check struct {
+	Name string
+	Func CheckFunc
+}
+
+// Health runs readiness checks and tracks shutdown draining.
+type Health struct {
+	checks    []Check
+	timeout   time.Duration
+	draining  atomic.Bool
+	drainOnce sync.Once
+	drained   chan struct{}
+}
+
+// New creates Health with provided readiness checks.
+func New(checks ...Check) *Health {
+	return &Health{
+		checks:  checks,
+		timeout: DefaultTimeout,
+		drained: make(chan struct{}),
+	}
+}
+
+// Drain marks server as shutting down, so readiness checks fail
+// and load balancer stops routing new requests to it.
+func (h *Health) Drain() {
+	h.drainOnce.Do(func() {
+		h.draining.Store(true)
+		close(h.drained)
+	})
+}
+
+// Ready runs all checks concurrently and returns map of check name to its error.
+// Returned error is nil only if all checks passed and server is not draining.
+func (h *Health) Ready(ctx context.Context) (map[string]error, error) {
+	if h.draining.Load() {
+		return nil, ErrDraining
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	results := make(map[string]error, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			err := c.Func(ctx)
+			mu.Lock()
+			results[c.Name] = err
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	for _, c := range h.checks {
+		if err := results[c.Name]; err != nil {
+			return results, errors.Wrapf(err, "check %s", c.Name)
+		}
+	}
+
+	return results, nil
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler reports that process is alive and able to serve HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+// ReadinessHandler responds 200 when all checks passed and 503 otherwise.
+// Body lists result of every check.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		results, err := h.Ready(req.Context())
+
+		resp := readinessResponse{Status: "ok", Checks: make(map[string]string, len(results))}
+		for name, checkErr := range results {
+			resp.Checks[name] = "ok"
+			if checkErr != nil {
+				resp.Checks[name] = checkErr.Error()
+			}
+		}
+		code := http.StatusOK
+		if err != nil {
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			slog.WarnContext(req.Context(), "not ready", "err", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.ErrorContext(req.Context(), "can't encode response", "handler", "readiness", "err", err)
+		}
+	})
+}
+
+// Watch periodically runs readiness checks and reflects result in srv for
+// overall server ("") and every provided service name. Once server is
+// draining all statuses are switched to NOT_SERVING permanently.
+// Watch blocks until ctx is done.
+func (h *Health) Watch(ctx context.Context, srv *health.Server, interval time.Duration, services ...string) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.update(ctx, srv, services)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.drained:
+			srv.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Health) update(ctx context.Context, srv *health.Server, services []string) {
+	_, err := h.Ready(ctx)
+	if errors.Is(err, ErrDraining) {
+		srv.Shutdown()
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+		slog.WarnContext(ctx, "not ready", "err", err)
+	}
+	srv.SetServingStatus("", status)
+	for _, service := range services {
+		srv.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func okCheck(context.Context) error { return nil }
+
+func failedCheck(context.Context) error { return errors.New("no reachable servers") }
+
+func TestReady(t *testing.T) {
+	require := require.New(t)
+
+	h := New(Check{Name: "mongo", Func: okCheck}, Check{Name: "transactions", Func: okCheck})
+	results, err := h.Ready(context.TODO())
+	require.NoError(err)
+	require.Len(results, 2)
+
+	h = New(Check{Name: "mongo", Func: failedCheck}, Check{Name: "transactions", Func: okCheck})
+	results, err = h.Ready(context.TODO())
+	require.EqualError(err, "check mongo: no reachable servers")
+	require.NoError(results["transactions"])
+
+	h = New(Check{Name: "mongo", Func: okCheck})
+	h.Drain()
+	h.Drain()
+	_, err = h.Ready(context.TODO())
+	require.Equal(ErrDraining, err)
+}
+
+func TestHandlers(t *testing.T) {
+	require := require.New(t)
+
+	w := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	require.Equal(http.StatusOK, w.Result().StatusCode)
+
+	h := New(Check{Name: "mongo", Func: okCheck}, Check{Name: "transactions", Func: failedCheck})
+	w = httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(http.StatusServiceUnavailable, w.Result().StatusCode)
+
+	var resp readinessResponse
+	require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(readinessResponse{
+		Status: "unavailable",
+		Checks: map[string]string{"mongo": "ok", "transactions": "no reachable servers"},
+	}, resp)
+
+	h = New(Check{Name: "mongo", Func: okCheck})
+	w = httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(http.StatusOK, w.Result().StatusCode)
+
+	h.Drain()
+	w = httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(http.StatusServiceUnavailable, w.Result().StatusCode)
+}
+
+func TestWatch(t *testing.T) {
+	var failed bool
+	h := New(Check{Name: "mongo", Func: func(context.Context) error {
+		if failed {
+			return errors.New("no reachable servers")
+		}
+		return nil
+	}})
+	srv := health.NewServer()
+	require := require.New(t)
+
+	h.update(context.TODO(), srv, []string{"main.Tournament"})
+	requireStatus(t, srv, "main.Tournament", healthpb.HealthCheckResponse_SERVING)
+
+	failed = true
+	h.update(context.TODO(), srv, []string{"main.Tournament"})
+	requireStatus(t, srv, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	requireStatus(t, srv, "main.Tournament", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	failed = false
+	done := make(chan struct{})
+	go func() {
+		h.Watch(context.TODO(), srv, time.Hour, "main.Tournament")
+		close(done)
+	}()
+	h.Drain()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("Watch should return after drain")
+	}
+	requireStatus(t, srv, "", healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func requireStatus(t *testing.T, srv *health.Server, service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+
+	resp, err := srv.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	require.Equal(t, expected, resp.Status)
+}
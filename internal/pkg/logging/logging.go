@@ -0,0 +1,106 @@
+// Package logging configures structured logger and carries request id through context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDKey is attribute key used for request id in log records.
+	RequestIDKey = "request_id"
+
+	// TraceIDKey is attribute key used for trace id in log records.
+	TraceIDKey = "trace_id"
+
+	// SpanIDKey is attribute key used for span id in log records.
+	SpanIDKey = "span_id"
+)
+
+// Config describes logger output.
+type Config struct {
+	// Level is one of debug, info, warn, error. Defaults to info.
+	Level string `yaml:"level"`
+
+	// Format is either json or text. Defaults to json.
+	Format string `yaml:"format"`
+}
+
+// New creates logger writing to w according to cfg. Every record logged
+// with context carrying request id gets request_id attribute, and with
+// context carrying recording span gets trace_id and span_id attributes.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, errors.Wrapf(err, "parse log level %q", cfg.Level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, errors.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+type requestIDKey struct{}
+
+// NewContext returns copy of ctx carrying provided request id.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns request id stored in ctx or empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID generates random request id.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds request id and trace context from context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String(TraceIDKey, sc.TraceID().String()), slog.String(SpanIDKey, sc.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+
+	_, err := New(&bytes.Buffer{}, Config{Level: "verbose"})
+	require.Error(err)
+
+	_, err = New(&bytes.Buffer{}, Config{Format: "xml"})
+	require.Error(err)
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Level: "warn", Format: "json"})
+	require.NoError(err)
+
+	logger.InfoContext(NewContext(context.TODO(), "req-1"), "skipped")
+	require.Empty(buf.String(), "Info record should be filtered out")
+
+	logger.WarnContext(NewContext(context.TODO(), "req-1"), "kept")
+
+	var record map[string]interface{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &record))
+	require.Equal("kept", record["msg"])
+	require.Equal("req-1", record[RequestIDKey])
+}
+
+func TestNew_TraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	require := require.New(t)
+	require.NoError(err)
+
+	traceID := trace.TraceID{1, 2, 3}
+	spanID := trace.SpanID{4, 5, 6}
+	ctx := trace.ContextWithSpanContext(context.TODO(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	logger.InfoContext(ctx, "traced")
+
+	var record map[string]interface{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(traceID.String(), record[TraceIDKey])
+	require.Equal(spanID.String(), record[SpanIDKey])
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	require := require.New(t)
+	require.NoError(err)
+
+	defaultLogger := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(defaultLogger)
+
+	var actualRequestID string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		actualRequestID = RequestID(req.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest("GET", "/user", nil)
+	req.Header.Set(RequestIDHeader, "incoming-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	require.Equal("incoming-id", actualRequestID, "Incoming request id should be accepted")
+	require.Equal("incoming-id", w.Result().Header.Get(RequestIDHeader), "Request id should be echoed")
+
+	var record map[string]interface{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &record))
+	require.Equal("http request", record["msg"])
+	require.Equal(float64(http.StatusTeapot), record["status"])
+	require.Equal("incoming-id", record[RequestIDKey])
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+	require.Len(actualRequestID, 32, "Request id should be generated")
+}
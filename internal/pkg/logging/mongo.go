@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// CommandMonitor logs every MongoDB command at debug level
+// with request id of context command was issued with.
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			slog.DebugContext(ctx, "mongo command started",
+				"command", e.CommandName,
+				"database", e.DatabaseName,
+				"request_num", e.RequestID,
+			)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			slog.DebugContext(ctx, "mongo command succeeded",
+				"command", e.CommandName,
+				"request_num", e.RequestID,
+				"duration", time.Duration(e.DurationNanos),
+			)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			slog.WarnContext(ctx, "mongo command failed",
+				"command", e.CommandName,
+				"request_num", e.RequestID,
+				"duration", time.Duration(e.DurationNanos),
+				"err", e.Failure,
+			)
+		},
+	}
+}
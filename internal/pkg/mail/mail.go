@@ -0,0 +1,133 @@
+// Package mail emails users about their tournaments and prize payouts.
+// Emails are sent only to verified addresses and are queued in storage,
+// so failed sends are retried.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Senders supported by New.
+const (
+	SenderNone = "none"
+	SenderSMTP = "smtp"
+	SenderFile = "file"
+)
+
+// Config configures email sender and delivery worker.
+type Config struct {
+	// Sender is one of none, smtp or file. Emails are off with none,
+	// file sender writes them to Dir for local development.
+	Sender string     `yaml:"sender"`
+	From   string     `yaml:"from"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+	Dir    string     `yaml:"dir"`
+
+	// VerifyURL is page confirming email address, token and user query parameters
+	// are appended to it. Bare token is emailed if it's empty.
+	VerifyURL       string        `yaml:"verify_url"`
+	VerificationTTL time.Duration `yaml:"verification_ttl"`
+
+	// ReminderLead is how long before scheduled start of tournament its players are reminded.
+	ReminderLead time.Duration `yaml:"reminder_lead"`
+
+	// PollInterval is delay between checks for due emails and reminders.
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// BatchSize is number of emails read at once.
+	BatchSize int64 `yaml:"batch_size"`
+
+	// Timeout limits single send attempt.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxAttempts is number of failed attempts after which email is dropped.
+	MaxAttempts int `yaml:"max_attempts"`
+
+	// RetryBackoff is delay after the first failed attempt.
+	// It doubles with every next failure up to MaxBackoff.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	MaxBackoff   time.Duration `yaml:"max_backoff"`
+
+	// LockTTL is lease duration. It's renewed after every attempt, so it must exceed Timeout.
+	LockTTL time.Duration `yaml:"lock_ttl"`
+}
+
+// SMTPConfig configures SMTP server emails are sent through.
+type SMTPConfig struct {
+	// Addr is host:port of server. STARTTLS is used if server supports it.
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	// Password is better provided with STS_EMAIL_SMTP_PASSWORD or STS_EMAIL_SMTP_PASSWORD_FILE.
+	Password string `yaml:"password"`
+}
+
+// DefaultConfig returns config used when email section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		Sender:          SenderNone,
+		VerificationTTL: 24 * time.Hour,
+		ReminderLead:    15 * time.Minute,
+		PollInterval:    5 * time.Second,
+		BatchSize:       50,
+		Timeout:         30 * time.Second,
+		MaxAttempts:     5,
+		RetryBackoff:    time.Minute,
+		MaxBackoff:      time.Hour,
+		LockTTL:         time.Minute,
+	}
+}
+
+// Message is plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends single email.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates sender chosen by config. It returns nil sender if emails are off.
+func New(conf Config) (Sender, error) {
+	switch conf.Sender {
+	case SenderNone, "":
+		return nil, nil
+	case SenderSMTP:
+		return NewSMTPSender(conf.SMTP, conf.From), nil
+	case SenderFile:
+		return NewFileSender(conf.Dir, conf.From)
+	default:
+		return nil, errors.Errorf("unknown email sender %q", conf.Sender)
+	}
+}
+
+// format builds RFC 5322 message with quoted-printable UTF-8 body.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(msg.Body)); err != nil {
+		return nil, errors.Wrap(err, "encode body")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "encode body")
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,353 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// memoryStore keeps users, tournaments and queued emails in memory.
+type memoryStore struct {
+	users       map[string]*storage.User
+	tournaments map[string]*storage.Tournament
+	emails      []storage.Email
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: map[string]*storage.User{}, tournaments: map[string]*storage.Tournament{}}
+}
+
+func (s *memoryStore) GetUser(_ context.Context, id string) (*storage.User, error) {
+	if user, ok := s.users[id]; ok {
+		return user, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) GetTournament(_ context.Context, id string) (*storage.Tournament, error) {
+	if t, ok := s.tournaments[id]; ok {
+		return t, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) ScheduleTournament(_ context.Context, tournamentID string, startsAt time.Time) error {
+	t := s.tournaments[tournamentID]
+	t.StartsAt, t.RemindedAt = &startsAt, nil
+	return nil
+}
+
+func (s *memoryStore) DueTournamentReminders(_ context.Context, now, before time.Time,
+	limit int64) ([]storage.Tournament, error) {
+	var due []storage.Tournament
+	for _, t := range s.tournaments {
+		if t.Status == storage.StatusSignIn && t.StartsAt != nil && t.StartsAt.After(now) &&
+			!t.StartsAt.After(before) && t.RemindedAt == nil && int64(len(due)) < limit {
+			due = append(due, *t)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) MarkTournamentReminded(_ context.Context, id primitive.ObjectID, startsAt time.Time) error {
+	for _, t := range s.tournaments {
+		if t.ID == id && t.StartsAt.Equal(startsAt) {
+			now := time.Now()
+			t.RemindedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SetUserEmail(_ context.Context, userID, email string, v storage.EmailVerification) error {
+	user := s.users[userID]
+	user.Email, user.EmailVerified, user.Verification = email, false, &v
+	return nil
+}
+
+func (s *memoryStore) VerifyUserEmail(_ context.Context, userID, tokenHash string, now time.Time) error {
+	user := s.users[userID]
+	if user.Verification == nil || user.Verification.TokenHash != tokenHash || !now.Before(user.Verification.ExpiresAt) {
+		return mongo.ErrNoDocuments
+	}
+	user.EmailVerified, user.Verification = true, nil
+	return nil
+}
+
+func (s *memoryStore) SetUserEmailOptOut(_ context.Context, userID string, kinds []storage.NotificationKind) error {
+	s.users[userID].EmailOptOut = kinds
+	return nil
+}
+
+func (s *memoryStore) AddEmail(_ context.Context, e storage.Email) error {
+	for _, queued := range s.emails {
+		if !e.EventID.IsZero() && queued.EventID == e.EventID && queued.UserID == e.UserID {
+			return nil
+		}
+	}
+	e.ID, e.Status = primitive.NewObjectID(), storage.DeliveryPending
+	s.emails = append(s.emails, e)
+	return nil
+}
+
+func (s *memoryStore) DueEmails(_ context.Context, now time.Time, limit int64) ([]storage.Email, error) {
+	var due []storage.Email
+	for _, e := range s.emails {
+		if e.Status == storage.DeliveryPending && !e.NextAttemptAt.After(now) && int64(len(due)) < limit {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) UpdateEmail(_ context.Context, e *storage.Email) error {
+	for i := range s.emails {
+		if s.emails[i].ID == e.ID {
+			s.emails[i] = *e
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) AcquireLock(context.Context, string, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseLock(context.Context, string, string) error {
+	return nil
+}
+
+// recordingSender records sent messages and fails while err is set.
+type recordingSender struct {
+	sent []Message
+	err  error
+}
+
+func (s *recordingSender) Send(_ context.Context, msg Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestMailer_Verification(t *testing.T) {
+	store := newMemoryStore()
+	store.users["u1"] = &storage.User{Name: "Gena"}
+	sender := &recordingSender{}
+	conf := DefaultConfig()
+	conf.VerifyURL = "https://sts.example/verify"
+	mailer := NewMailer(store, sender, conf)
+	require := require.New(t)
+
+	require.NoError(mailer.RequestVerification(context.TODO(), "u1", "gena@example.com"))
+	sent, err := mailer.Send(context.TODO())
+	require.NoError(err)
+	require.Equal(1, sent)
+	require.Equal("gena@example.com", sender.sent[0].To)
+	require.Equal("Confirm your email address", sender.sent[0].Subject)
+
+	var link *url.URL
+	for _, word := range strings.Fields(sender.sent[0].Body) {
+		if strings.HasPrefix(word, conf.VerifyURL) {
+			link, err = url.Parse(word)
+			require.NoError(err)
+		}
+	}
+	require.NotNil(link, "Email should contain verification link")
+	require.Equal("u1", link.Query().Get("user"))
+
+	require.ErrorIs(mailer.Verify(context.TODO(), "u1", "wrong"), ErrInvalidToken)
+	require.NoError(mailer.Verify(context.TODO(), "u1", link.Query().Get("token")))
+	require.True(store.users["u1"].EmailVerified)
+	require.ErrorIs(mailer.Verify(context.TODO(), "u1", link.Query().Get("token")), ErrInvalidToken,
+		"Token should be used once")
+
+	require.NoError(mailer.RequestVerification(context.TODO(), "u1", "gena@example.org"))
+	require.False(store.users["u1"].EmailVerified, "Changed email should be verified again")
+}
+
+func TestMailer_Enqueue(t *testing.T) {
+	store := newMemoryStore()
+	store.users["u1"] = &storage.User{Name: "Gena", Email: "u1@example.com", EmailVerified: true}
+	store.users["u2"] = &storage.User{Name: "Vova", Email: "u2@example.com"}
+	store.users["u3"] = &storage.User{Name: "Petya", Email: "u3@example.com", EmailVerified: true,
+		EmailOptOut: []storage.NotificationKind{storage.NotificationTournamentStarted}}
+	store.tournaments["t1"] = &storage.Tournament{Name: "Spring Cup"}
+	mailer := NewMailer(store, &recordingSender{}, DefaultConfig())
+	require := require.New(t)
+
+	started := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentStarted,
+		TournamentID: "t1", UserIDs: []string{"u1", "u2", "u3", "deleted"}}
+	finished := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentFinished,
+		TournamentID: "t1", UserID: "u3", Amount: 150, UserIDs: []string{"u1", "u2", "u3"}}
+	funded := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventBalanceFunded, UserID: "u1", Amount: 10}
+	for _, e := range []storage.OutboxEvent{started, started, finished, funded} {
+		require.NoError(mailer.Enqueue(context.TODO(), e))
+	}
+
+	require.Len(store.emails, 2, "Only verified users who didn't opt out should get emails once")
+	require.Equal("u1@example.com", store.emails[0].To)
+	require.Equal("Tournament Spring Cup has started", store.emails[0].Subject)
+	require.Equal("u3@example.com", store.emails[1].To)
+	require.Equal("You won tournament Spring Cup", store.emails[1].Subject)
+	require.Contains(store.emails[1].Body, "Prize of 150 points")
+}
+
+func TestMailer_Remind(t *testing.T) {
+	store := newMemoryStore()
+	players := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	store.users[players[0].Hex()] = &storage.User{Name: "Gena", Email: "u1@example.com", EmailVerified: true}
+	store.users[players[1].Hex()] = &storage.User{Name: "Petya", Email: "u3@example.com", EmailVerified: true,
+		EmailOptOut: []storage.NotificationKind{storage.NotificationTournamentStarting}}
+	store.tournaments["t1"] = &storage.Tournament{ID: primitive.NewObjectID(), Name: "Spring Cup",
+		Status: storage.StatusSignIn, Users: players}
+	store.tournaments["t2"] = &storage.Tournament{ID: primitive.NewObjectID(), Name: "Autumn Cup",
+		Status: storage.StatusSignIn, Users: players}
+	mailer := NewMailer(store, &recordingSender{}, DefaultConfig())
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	mailer.now = func() time.Time { return now }
+	require := require.New(t)
+
+	require.NoError(store.ScheduleTournament(context.TODO(), "t1", now.Add(10*time.Minute)))
+	require.NoError(store.ScheduleTournament(context.TODO(), "t2", now.Add(time.Hour)))
+	reminded, err := mailer.Remind(context.TODO())
+	require.NoError(err)
+	require.Equal(1, reminded, "Only tournament starting within lead should be reminded about")
+	require.Len(store.emails, 1, "Only verified players who didn't opt out should be reminded")
+	require.Equal("u1@example.com", store.emails[0].To)
+	require.Equal("Tournament Spring Cup starts soon", store.emails[0].Subject)
+	require.Contains(store.emails[0].Body, "starts at 12:10 UTC, 1 May")
+
+	reminded, err = mailer.Remind(context.TODO())
+	require.NoError(err)
+	require.Zero(reminded, "Players should be reminded once")
+
+	require.NoError(store.ScheduleTournament(context.TODO(), "t1", now.Add(5*time.Minute)))
+	reminded, err = mailer.Remind(context.TODO())
+	require.NoError(err)
+	require.Equal(1, reminded, "Rescheduled tournament should be reminded about again")
+	require.Len(store.emails, 2)
+}
+
+func TestMailer_Send(t *testing.T) {
+	store := newMemoryStore()
+	sender := &recordingSender{err: errors.New("connection refused")}
+	conf := DefaultConfig()
+	conf.MaxAttempts = 2
+	mailer := NewMailer(store, sender, conf)
+	now := time.Now()
+	mailer.now = func() time.Time { return now }
+	require := require.New(t)
+
+	require.NoError(store.AddEmail(context.TODO(), storage.Email{To: "u1@example.com", Subject: "Hi"}))
+	sent, err := mailer.Send(context.TODO())
+	require.NoError(err)
+	require.Equal(1, sent)
+	require.Equal(storage.DeliveryPending, store.emails[0].Status)
+	require.Equal(now.Add(conf.RetryBackoff), store.emails[0].NextAttemptAt)
+	require.Equal("connection refused", store.emails[0].LastError)
+
+	sent, err = mailer.Send(context.TODO())
+	require.NoError(err)
+	require.Zero(sent, "Email should not be retried before backoff passes")
+
+	now = now.Add(conf.RetryBackoff)
+	_, err = mailer.Send(context.TODO())
+	require.NoError(err)
+	require.Equal(storage.DeliveryDead, store.emails[0].Status, "Email should be dropped after MaxAttempts")
+
+	sender.err = nil
+	require.NoError(store.AddEmail(context.TODO(), storage.Email{To: "u2@example.com", Subject: "Hi"}))
+	_, err = mailer.Send(context.TODO())
+	require.NoError(err)
+	require.Equal(storage.DeliveryDelivered, store.emails[1].Status)
+	require.NotNil(store.emails[1].SentAt)
+	require.Len(sender.sent, 1)
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	require := require.New(t)
+	sender, err := New(Config{Sender: SenderFile, Dir: dir, From: "sts@example.com"})
+	require.NoError(err)
+
+	require.NoError(sender.Send(context.TODO(), Message{To: "u1@example.com", Subject: "Привет", Body: "Hi\nthere"}))
+	files, err := os.ReadDir(dir)
+	require.NoError(err)
+	require.Len(files, 1)
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(err)
+	require.Contains(string(data), "To: u1@example.com\r\n")
+	require.Contains(string(data), "Subject: =?utf-8?q?", "Non-ASCII subject should be encoded")
+	require.Contains(string(data), "\r\n\r\nHi\r\nthere")
+}
+
+func TestSMTPSender(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(ln, received)
+
+	sender := NewSMTPSender(SMTPConfig{Addr: ln.Addr().String()}, "sts@example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sender.Send(ctx, Message{To: "u1@example.com", Subject: "Hi", Body: "Good luck"}))
+
+	commands := <-received
+	require.Contains(t, commands, "MAIL FROM:<sts@example.com>")
+	require.Contains(t, commands, "RCPT TO:<u1@example.com>")
+	require.Contains(t, commands, "Good luck")
+	require.Equal(t, "QUIT", commands[len(commands)-1])
+}
+
+// serveSMTP accepts single session on ln and sends lines client wrote to received.
+func serveSMTP(ln net.Listener, received chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	var lines []string
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			break
+		}
+		lines = append(lines, line)
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			_ = tp.PrintfLine("250 localhost")
+		case line == "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, _ := tp.ReadDotLines()
+			lines = append(lines, data...)
+			_ = tp.PrintfLine("250 queued")
+		case line == "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			received <- lines
+			return
+		default:
+			_ = tp.PrintfLine("250 ok")
+		}
+	}
+	received <- lines
+}
@@ -0,0 +1,429 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// lockName is name of lease which lets single instance send emails.
+	lockName = "emails"
+
+	// verificationTemplate is name of template confirming email address.
+	verificationTemplate = "verification"
+
+	// tokenSize is number of random bytes in verification token.
+	tokenSize = 32
+)
+
+// Kinds lists notifications which are emailed. User may opt out of any of them.
+var Kinds = []storage.NotificationKind{
+	storage.NotificationTournamentStarting,
+	storage.NotificationTournamentStarted,
+	storage.NotificationTournamentWon,
+}
+
+// ErrInvalidToken is returned by Verify if token doesn't match pending verification or is expired.
+var ErrInvalidToken = errors.New("invalid or expired verification token")
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// templates are parsed once, every one renders subject in the first line
+// and body after blank line.
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.tmpl"))
+
+// Store is part of storage.DB mailer works with.
+type Store interface {
+	GetUser(ctx context.Context, id string) (*storage.User, error)
+	GetTournament(ctx context.Context, id string) (*storage.Tournament, error)
+	ScheduleTournament(ctx context.Context, tournamentID string, startsAt time.Time) error
+	DueTournamentReminders(ctx context.Context, now, before time.Time, limit int64) ([]storage.Tournament, error)
+	MarkTournamentReminded(ctx context.Context, id primitive.ObjectID, startsAt time.Time) error
+	SetUserEmail(ctx context.Context, userID, email string, v storage.EmailVerification) error
+	VerifyUserEmail(ctx context.Context, userID, tokenHash string, now time.Time) error
+	SetUserEmailOptOut(ctx context.Context, userID string, kinds []storage.NotificationKind) error
+	AddEmail(ctx context.Context, e storage.Email) error
+	DueEmails(ctx context.Context, now time.Time, limit int64) ([]storage.Email, error)
+	UpdateEmail(ctx context.Context, e *storage.Email) error
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// Mailer verifies email addresses of users, queues emails for domain
+// events and reminders about scheduled tournaments and sends queued emails. Failed email is retried with
+// exponential backoff and is dropped after MaxAttempts failures.
+type Mailer struct {
+	Store
+	sender Sender
+	conf   Config
+	owner  string
+	now    func() time.Time
+}
+
+// templateData is available to all templates.
+type templateData struct {
+	Name       string
+	Email      string
+	Link       string
+	Token      string
+	TTL        time.Duration
+	Tournament string
+	StartsAt   time.Time
+	Amount     float64
+}
+
+// NewMailer creates mailer queueing emails in store and sending them with sender.
+func NewMailer(store Store, sender Sender, conf Config) *Mailer {
+	defaults := DefaultConfig()
+	if conf.VerificationTTL <= 0 {
+		conf.VerificationTTL = defaults.VerificationTTL
+	}
+	if conf.ReminderLead <= 0 {
+		conf.ReminderLead = defaults.ReminderLead
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaults.PollInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaults.BatchSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaults.Timeout
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = defaults.MaxAttempts
+	}
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = defaults.RetryBackoff
+	}
+	if conf.MaxBackoff < conf.RetryBackoff {
+		conf.MaxBackoff = conf.RetryBackoff
+	}
+	if conf.LockTTL <= conf.Timeout {
+		conf.LockTTL = conf.Timeout + defaults.LockTTL
+	}
+
+	host, _ := os.Hostname()
+	return &Mailer{
+		Store:  store,
+		sender: sender,
+		conf:   conf,
+		owner:  fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+		now:    time.Now,
+	}
+}
+
+// RequestVerification sets unverified email of user and queues email with
+// verification token to it. Emails aren't sent to address until it's verified.
+func (m *Mailer) RequestVerification(ctx context.Context, userID, email string) error {
+	user, err := m.GetUser(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "get user %s", userID)
+	}
+
+	raw := make([]byte, tokenSize)
+	if _, err = rand.Read(raw); err != nil {
+		return errors.Wrap(err, "generate token")
+	}
+	token := hex.EncodeToString(raw)
+	verification := storage.EmailVerification{
+		TokenHash: hashToken(token),
+		ExpiresAt: m.now().Add(m.conf.VerificationTTL),
+	}
+	if err = m.SetUserEmail(ctx, userID, email, verification); err != nil {
+		return errors.Wrapf(err, "set email of user %s", userID)
+	}
+
+	data := templateData{Name: user.Name, Email: email, Token: token, TTL: m.conf.VerificationTTL}
+	if m.conf.VerifyURL != "" {
+		link, err := url.Parse(m.conf.VerifyURL)
+		if err != nil {
+			return errors.Wrap(err, "parse verify URL")
+		}
+		query := link.Query()
+		query.Set("user", userID)
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		data.Link = link.String()
+	}
+
+	return m.queue(ctx, userID, email, primitive.NilObjectID, verificationTemplate, data)
+}
+
+// Verify marks email of user as verified if token matches the last one sent to it.
+func (m *Mailer) Verify(ctx context.Context, userID, token string) error {
+	err := m.VerifyUserEmail(ctx, userID, hashToken(token), m.now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return errors.Wrapf(err, "verify email of user %s", userID)
+	}
+
+	return nil
+}
+
+// Enqueue queues emails to players of started tournament and to winner of
+// finished one. Users without verified email and users who opted out are
+// skipped. It's outbox.Handler and may be called more than once for the same event.
+func (m *Mailer) Enqueue(ctx context.Context, e storage.OutboxEvent) error {
+	var kind storage.NotificationKind
+	var recipients []string
+	switch e.Type {
+	case storage.EventTournamentStarted:
+		kind, recipients = storage.NotificationTournamentStarted, e.UserIDs
+	case storage.EventTournamentFinished:
+		kind, recipients = storage.NotificationTournamentWon, []string{e.UserID}
+	default:
+		return nil
+	}
+
+	tournament := e.TournamentID
+	t, err := m.GetTournament(ctx, e.TournamentID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return errors.Wrapf(err, "get tournament %s", e.TournamentID)
+	}
+	if t != nil {
+		tournament = t.Name
+	}
+
+	for _, userID := range recipients {
+		user, err := m.GetUser(ctx, userID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "get user %s", userID)
+		}
+		if !wants(user, kind) {
+			continue
+		}
+
+		data := templateData{Name: user.Name, Tournament: tournament, Amount: e.Amount}
+		if err = m.queue(ctx, userID, user.Email, e.ID, string(kind), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remind queues emails to players of tournaments which start within
+// ReminderLead and returns number of tournaments reminded about. Nothing is
+// queued if lease is held by other instance.
+func (m *Mailer) Remind(ctx context.Context) (int, error) {
+	ok, err := m.AcquireLock(ctx, lockName, m.owner, m.conf.LockTTL)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire lock")
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	now := m.now()
+	due, err := m.DueTournamentReminders(ctx, now, now.Add(m.conf.ReminderLead), m.conf.BatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "read due reminders")
+	}
+
+	kind := storage.NotificationTournamentStarting
+	for i, t := range due {
+		// tournament is marked after its emails are queued, so player
+		// may be reminded twice if instance stops in between
+		for _, id := range t.Users {
+			userID := id.Hex()
+			user, err := m.GetUser(ctx, userID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			if err != nil {
+				return i, errors.Wrapf(err, "get user %s", userID)
+			}
+			if !wants(user, kind) {
+				continue
+			}
+
+			data := templateData{Name: user.Name, Tournament: t.Name, StartsAt: *t.StartsAt}
+			if err = m.queue(ctx, userID, user.Email, primitive.NilObjectID, string(kind), data); err != nil {
+				return i, err
+			}
+		}
+		if err = m.MarkTournamentReminded(ctx, t.ID, *t.StartsAt); err != nil {
+			return i, errors.Wrapf(err, "mark tournament %s reminded", t.ID.Hex())
+		}
+	}
+
+	return len(due), nil
+}
+
+// wants reports whether user gets notifications of kind by email.
+func wants(user *storage.User, kind storage.NotificationKind) bool {
+	if user.Email == "" || !user.EmailVerified {
+		return false
+	}
+	for _, k := range user.EmailOptOut {
+		if k == kind {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Mailer) queue(ctx context.Context, userID, to string, eventID primitive.ObjectID,
+	name string, data templateData) error {
+	subject, body, err := render(name, data)
+	if err != nil {
+		return err
+	}
+
+	err = m.AddEmail(ctx, storage.Email{
+		UserID:   userID,
+		EventID:  eventID,
+		Template: name,
+		To:       to,
+		Subject:  subject,
+		Body:     body,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "queue %s email to user %s", name, userID)
+	}
+
+	return nil
+}
+
+// render executes template with provided name and splits result into subject and body.
+func render(name string, data templateData) (string, string, error) {
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, name+".tmpl", data); err != nil {
+		return "", "", errors.Wrapf(err, "render %s template", name)
+	}
+
+	subject, body, _ := strings.Cut(b.String(), "\n")
+	return strings.TrimSpace(subject), strings.TrimLeft(body, "\n"), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Run queues reminders and sends queued emails until ctx is done. Only
+// instance holding lease does it, others wait for it to expire.
+func (m *Mailer) Run(ctx context.Context) {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.conf.PollInterval)
+		defer cancel()
+		if err := m.ReleaseLock(ctx, lockName, m.owner); err != nil {
+			slog.Error("error releasing emails lock", "err", err)
+		}
+	}()
+
+	ticker := time.NewTicker(m.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Remind(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error queueing reminders", "err", err)
+		}
+		if _, err := m.Send(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error sending emails", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Send makes attempt of every due email and returns number of attempts.
+// Failed attempts are rescheduled, so error is returned only if store fails.
+// Nothing is sent if lease is held by other instance.
+func (m *Mailer) Send(ctx context.Context) (int, error) {
+	attempts := 0
+	for {
+		ok, err := m.AcquireLock(ctx, lockName, m.owner, m.conf.LockTTL)
+		if err != nil {
+			return attempts, errors.Wrap(err, "acquire lock")
+		}
+		if !ok {
+			return attempts, nil
+		}
+
+		due, err := m.DueEmails(ctx, m.now(), m.conf.BatchSize)
+		if err != nil {
+			return attempts, errors.Wrap(err, "read due emails")
+		}
+
+		for i := range due {
+			m.attempt(ctx, &due[i])
+			attempts++
+			if err = m.UpdateEmail(ctx, &due[i]); err != nil {
+				return attempts, errors.Wrapf(err, "update email %s", due[i].ID.Hex())
+			}
+			// lease lost in the middle of batch means other instance took over
+			if ok, err = m.AcquireLock(ctx, lockName, m.owner, m.conf.LockTTL); err != nil || !ok {
+				return attempts, errors.Wrap(err, "renew lock")
+			}
+		}
+
+		if int64(len(due)) < m.conf.BatchSize {
+			return attempts, nil
+		}
+	}
+}
+
+// attempt sends email and updates it with result.
+func (m *Mailer) attempt(ctx context.Context, e *storage.Email) {
+	e.Attempts++
+	e.LastError = ""
+
+	sendCtx, cancel := context.WithTimeout(ctx, m.conf.Timeout)
+	err := m.sender.Send(sendCtx, Message{To: e.To, Subject: e.Subject, Body: e.Body})
+	cancel()
+	now := m.now()
+	switch {
+	case err == nil:
+		e.Status = storage.DeliveryDelivered
+		e.SentAt = &now
+		return
+	case e.Attempts >= m.conf.MaxAttempts:
+		e.Status = storage.DeliveryDead
+	default:
+		e.NextAttemptAt = now.Add(m.backoff(e.Attempts))
+	}
+	e.LastError = err.Error()
+	slog.WarnContext(ctx, "sending email failed", "email", e.ID.Hex(), "template", e.Template,
+		"attempts", e.Attempts, "status", e.Status, "err", err)
+}
+
+// backoff returns delay after provided number of failed attempts.
+func (m *Mailer) backoff(attempts int) time.Duration {
+	delay := m.conf.RetryBackoff
+	for i := 1; i < attempts && delay < m.conf.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > m.conf.MaxBackoff {
+		delay = m.conf.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SMTPSender sends emails through SMTP server.
+type SMTPSender struct {
+	conf SMTPConfig
+	from string
+}
+
+// NewSMTPSender creates sender using server of conf. Credentials are sent
+// only over TLS or to server on localhost.
+func NewSMTPSender(conf SMTPConfig, from string) *SMTPSender {
+	return &SMTPSender{conf: conf, from: from}
+}
+
+// Send delivers msg to SMTP server. Connection is closed when ctx is done.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	data, err := format(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(s.conf.Addr)
+	if err != nil {
+		return errors.Wrap(err, "parse SMTP address")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.conf.Addr)
+	if err != nil {
+		return errors.Wrap(err, "dial SMTP server")
+	}
+	// net/smtp has no contexts, deadline and close interrupt blocked client instead
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "greet SMTP server")
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrap(err, "start TLS")
+		}
+	}
+	if s.conf.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", s.conf.Username, s.conf.Password, host)); err != nil {
+			return errors.Wrap(err, "authenticate")
+		}
+	}
+	if err = c.Mail(s.from); err != nil {
+		return errors.Wrap(err, "set sender")
+	}
+	if err = c.Rcpt(msg.To); err != nil {
+		return errors.Wrap(err, "set recipient")
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "start data")
+	}
+	if _, err = w.Write(data); err != nil {
+		return errors.Wrap(err, "write data")
+	}
+	if err = w.Close(); err != nil {
+		return errors.Wrap(err, "end data")
+	}
+
+	return c.Quit()
+}
+
+// FileSender writes every email to its own .eml file for local development.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender creates sender writing emails to dir, dir is created if missing.
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "create email directory")
+	}
+
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+// Send writes msg to file named by current time, so files sort in order emails are sent.
+func (s *FileSender) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(s.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return errors.Wrap(err, "generate file name")
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	if err = os.WriteFile(filepath.Join(s.dir, name), data, 0o640); err != nil {
+		return errors.Wrap(err, "write email file")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestInstrumentedService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().AddUser(gomock.Any(), gomock.Eq("Vasya")).Times(1).Return(userID, nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("get doc from collection"))
+	mock.EXPECT().JoinTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(userID)).Times(1).Return(nil)
+	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(userID)).Times(1).Return(nil)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(2).
+		Return(&storage.Tournament{Deposit: 100, Prize: 300}, nil)
+
+	m := New()
+	db := m.InstrumentStorage(mock)
+	require := require.New(t)
+
+	_, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	_, err = db.GetUser(context.TODO(), userID)
+	require.Error(err)
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.FinishTournament(context.TODO(), tournamentID, userID))
+
+	require.Equal(1.0, testutil.ToFloat64(m.usersCreated))
+	require.Equal(100.0, testutil.ToFloat64(m.depositsCollected))
+	require.Equal(300.0, testutil.ToFloat64(m.prizesPaid))
+	require.Equal(1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("GetUser")))
+	require.Equal(0.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("AddUser")))
+	require.Equal(4, testutil.CollectAndCount(m.storageDuration))
+}
+
+func TestMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().CountTournamentsByStatus(gomock.Any()).Times(1).
+		Return(map[storage.TournamentStatus]int64{storage.StatusSignIn: 3, storage.StatusFinished: 3}, nil)
+
+	m := New()
+	m.RegisterTournamentGauge(mock)
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	router.Handle("/metrics", m.Handler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/42", nil))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require := require.New(t)
+	require.Equal(http.StatusOK, w.Result().StatusCode)
+
+	body := w.Body.String()
+	require.True(strings.Contains(body, `sts_http_requests_total{code="404",method="GET",route="/user/{id}"} 1`), body)
+	require.True(strings.Contains(body, `sts_tournaments{status="signIn"} 3`), body)
+	require.True(strings.Contains(body, `sts_tournaments{status="finished"} 3`), body)
+	require.True(strings.Contains(body, `sts_tournaments{status="started"} 0`), body)
+}
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+var _ storage.Service = (*InstrumentedService)(nil)
+
+// InstrumentedService is storage.Service decorator which observes latency
+// and errors of every method and counts business events.
+type InstrumentedService struct {
+	next    storage.Service
+	metrics *Metrics
+}
+
+// InstrumentStorage wraps db so that its calls are measured.
+func (m *Metrics) InstrumentStorage(db storage.Service) *InstrumentedService {
+	return &InstrumentedService{next: db, metrics: m}
+}
+
+func (s *InstrumentedService) observe(method string, start time.Time, err *error) {
+	s.metrics.storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		s.metrics.storageErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// GetUser implements storage.Service.
+func (s *InstrumentedService) GetUser(ctx context.Context, id string) (user *storage.User, err error) {
+	defer s.observe("GetUser", time.Now(), &err)
+	return s.next.GetUser(ctx, id)
+}
+
+// DeleteUser implements storage.Service.
+func (s *InstrumentedService) DeleteUser(ctx context.Context, id string, opts storage.DeleteUserOptions) (err error) {
+	defer s.observe("DeleteUser", time.Now(), &err)
+	return s.next.DeleteUser(ctx, id, opts)
+}
+
+// TakeUserBalance implements storage.Service.
+func (s *InstrumentedService) TakeUserBalance(ctx context.Context, id string, points float64) (err error) {
+	defer s.observe("TakeUserBalance", time.Now(), &err)
+	return s.next.TakeUserBalance(ctx, id, points)
+}
+
+// FundUserBalance implements storage.Service.
+func (s *InstrumentedService) FundUserBalance(ctx context.Context, id string, points float64) (err error) {
+	defer s.observe("FundUserBalance", time.Now(), &err)
+	return s.next.FundUserBalance(ctx, id, points)
+}
+
+// SetUserRole implements storage.Service.
+func (s *InstrumentedService) SetUserRole(ctx context.Context, id string, role storage.Role) (err error) {
+	defer s.observe("SetUserRole", time.Now(), &err)
+	return s.next.SetUserRole(ctx, id, role)
+}
+
+// AddTournament implements storage.Service.
+func (s *InstrumentedService) AddTournament(ctx context.Context, name string, deposit float64,
+	organizerID string) (id string, err error) {
+	defer s.observe("AddTournament", time.Now(), &err)
+	return s.next.AddTournament(ctx, name, deposit, organizerID)
+}
+
+// GetTournament implements storage.Service.
+func (s *InstrumentedService) GetTournament(ctx context.Context,
+	id string) (tournament *storage.Tournament, err error) {
+	defer s.observe("GetTournament", time.Now(), &err)
+	return s.next.GetTournament(ctx, id)
+}
+
+// DeleteTournament implements storage.Service.
+func (s *InstrumentedService) DeleteTournament(ctx context.Context, id string) (err error) {
+	defer s.observe("DeleteTournament", time.Now(), &err)
+	return s.next.DeleteTournament(ctx, id)
+}
+
+// IncreaseTournamentPrize implements storage.Service.
+func (s *InstrumentedService) IncreaseTournamentPrize(ctx context.Context, id string, amount float64) (err error) {
+	defer s.observe("IncreaseTournamentPrize", time.Now(), &err)
+	return s.next.IncreaseTournamentPrize(ctx, id, amount)
+}
+
+// DecreaseTournamentPrize implements storage.Service.
+func (s *InstrumentedService) DecreaseTournamentPrize(ctx context.Context, id string, amount float64) (err error) {
+	defer s.observe("DecreaseTournamentPrize", time.Now(), &err)
+	return s.next.DecreaseTournamentPrize(ctx, id, amount)
+}
+
+// SetTournamentWinner implements storage.Service.
+func (s *InstrumentedService) SetTournamentWinner(ctx context.Context, tournamentID, userID string) (err error) {
+	defer s.observe("SetTournamentWinner", time.Now(), &err)
+	return s.next.SetTournamentWinner(ctx, tournamentID, userID)
+}
+
+// SetTournamentStatus implements storage.Service.
+func (s *InstrumentedService) SetTournamentStatus(ctx context.Context, tournamentID string,
+	status storage.TournamentStatus) (err error) {
+	defer s.observe("SetTournamentStatus", time.Now(), &err)
+	return s.next.SetTournamentStatus(ctx, tournamentID, status)
+}
+
+// AddUserToTournamentList implements storage.Service.
+func (s *InstrumentedService) AddUserToTournamentList(ctx context.Context, tournamentID, userID string) (err error) {
+	defer s.observe("AddUserToTournamentList", time.Now(), &err)
+	return s.next.AddUserToTournamentList(ctx, tournamentID, userID)
+}
+
+// CountTournamentsByStatus implements storage.Service.
+func (s *InstrumentedService) CountTournamentsByStatus(
+	ctx context.Context) (counts map[storage.TournamentStatus]int64, err error) {
+	defer s.observe("CountTournamentsByStatus", time.Now(), &err)
+	return s.next.CountTournamentsByStatus(ctx)
+}
+
+// ListTournaments implements storage.Service.
+func (s *InstrumentedService) ListTournaments(ctx context.Context, status storage.TournamentStatus,
+	limit int64) (tournaments []storage.Tournament, err error) {
+	defer s.observe("ListTournaments", time.Now(), &err)
+	return s.next.ListTournaments(ctx, status, limit)
+}
+
+// RemoveUserFromTournamentList implements storage.Service.
+func (s *InstrumentedService) RemoveUserFromTournamentList(ctx context.Context, tournamentID,
+	userID string) (err error) {
+	defer s.observe("RemoveUserFromTournamentList", time.Now(), &err)
+	return s.next.RemoveUserFromTournamentList(ctx, tournamentID, userID)
+}
+
+// GetCredentialsByLogin implements storage.Service.
+func (s *InstrumentedService) GetCredentialsByLogin(ctx context.Context,
+	login string) (creds *storage.Credentials, err error) {
+	defer s.observe("GetCredentialsByLogin", time.Now(), &err)
+	return s.next.GetCredentialsByLogin(ctx, login)
+}
+
+// GetCredentialsByUserID implements storage.Service.
+func (s *InstrumentedService) GetCredentialsByUserID(ctx context.Context,
+	userID string) (creds *storage.Credentials, err error) {
+	defer s.observe("GetCredentialsByUserID", time.Now(), &err)
+	return s.next.GetCredentialsByUserID(ctx, userID)
+}
+
+// SetPasswordHash implements storage.Service.
+func (s *InstrumentedService) SetPasswordHash(ctx context.Context, userID string, passwordHash []byte) (err error) {
+	defer s.observe("SetPasswordHash", time.Now(), &err)
+	return s.next.SetPasswordHash(ctx, userID, passwordHash)
+}
+
+// AddSession implements storage.Service.
+func (s *InstrumentedService) AddSession(ctx context.Context, userID, tokenHash string,
+	expiresAt time.Time) (err error) {
+	defer s.observe("AddSession", time.Now(), &err)
+	return s.next.AddSession(ctx, userID, tokenHash, expiresAt)
+}
+
+// GetSession implements storage.Service.
+func (s *InstrumentedService) GetSession(ctx context.Context, tokenHash string) (session *storage.Session, err error) {
+	defer s.observe("GetSession", time.Now(), &err)
+	return s.next.GetSession(ctx, tokenHash)
+}
+
+// RevokeSession implements storage.Service.
+func (s *InstrumentedService) RevokeSession(ctx context.Context, tokenHash string) (err error) {
+	defer s.observe("RevokeSession", time.Now(), &err)
+	return s.next.RevokeSession(ctx, tokenHash)
+}
+
+// RevokeUserSessions implements storage.Service.
+func (s *InstrumentedService) RevokeUserSessions(ctx context.Context, userID string) (err error) {
+	defer s.observe("RevokeUserSessions", time.Now(), &err)
+	return s.next.RevokeUserSessions(ctx, userID)
+}
+
+// LeaveTournament implements storage.Service.
+func (s *InstrumentedService) LeaveTournament(ctx context.Context, tournamentID, userID string) (err error) {
+	defer s.observe("LeaveTournament", time.Now(), &err)
+	return s.next.LeaveTournament(ctx, tournamentID, userID)
+}
+
+// AddUser implements storage.Service and counts created users.
+func (s *InstrumentedService) AddUser(ctx context.Context, name string) (userID string, err error) {
+	defer s.observe("AddUser", time.Now(), &err)
+	userID, err = s.next.AddUser(ctx, name)
+	if err == nil {
+		s.metrics.usersCreated.Inc()
+	}
+	return userID, err
+}
+
+// RegisterUser implements storage.Service and counts created users.
+func (s *InstrumentedService) RegisterUser(ctx context.Context, name, login string,
+	passwordHash []byte) (userID string, err error) {
+	defer s.observe("RegisterUser", time.Now(), &err)
+	userID, err = s.next.RegisterUser(ctx, name, login, passwordHash)
+	if err == nil {
+		s.metrics.usersCreated.Inc()
+	}
+	return userID, err
+}
+
+// JoinTournament implements storage.Service and sums collected deposits.
+// Deposit is read from joined tournament after successful join.
+func (s *InstrumentedService) JoinTournament(ctx context.Context, tournamentID, userID string) (err error) {
+	defer s.observe("JoinTournament", time.Now(), &err)
+	if err = s.next.JoinTournament(ctx, tournamentID, userID); err != nil {
+		return err
+	}
+
+	if tournament, getErr := s.next.GetTournament(ctx, tournamentID); getErr == nil {
+		s.metrics.depositsCollected.Add(tournament.Deposit)
+	}
+	return nil
+}
+
+// FinishTournament implements storage.Service and sums paid prizes.
+// Prize is read from finished tournament after successful finish.
+func (s *InstrumentedService) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) (err error) {
+	defer s.observe("FinishTournament", time.Now(), &err)
+	if err = s.next.FinishTournament(ctx, tournamentID, winnerUserID); err != nil {
+		return err
+	}
+
+	if tournament, getErr := s.next.GetTournament(ctx, tournamentID); getErr == nil {
+		s.metrics.prizesPaid.Add(tournament.Prize)
+	}
+	return nil
+}
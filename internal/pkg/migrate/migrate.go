@@ -0,0 +1,158 @@
+// Package migrate applies schema migrations recorded in database. Only
+// instance holding lease migrates, others wait until it's done.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// lockName is name of lease which lets single instance apply migrations.
+const lockName = "schemaMigrations"
+
+// Config configures applying migrations.
+type Config struct {
+	// OnStartup applies pending migrations before servers start.
+	// Otherwise they are applied by "sts migrate" only.
+	OnStartup bool `yaml:"on_startup"`
+
+	// PollInterval is period of checking if other instance finished migrating.
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// LockTTL is lease of instance applying migrations. It's extended before
+	// every migration, so it must outlive the longest one.
+	LockTTL time.Duration `yaml:"lock_ttl"`
+}
+
+// DefaultConfig returns config used when migrations section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		OnStartup:    true,
+		PollInterval: time.Second,
+		LockTTL:      10 * time.Minute,
+	}
+}
+
+// Store is part of storage.DB migrations are applied to.
+type Store interface {
+	AppliedMigrations(ctx context.Context) ([]storage.AppliedMigration, error)
+	ApplyMigration(ctx context.Context, m storage.Migration) error
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// Migrator applies migrations which aren't recorded in store.
+type Migrator struct {
+	store      Store
+	migrations []storage.Migration
+	conf       Config
+	owner      string
+}
+
+// New creates migrator applying migrations ordered by version to store.
+func New(store Store, migrations []storage.Migration, conf Config) *Migrator {
+	defaults := DefaultConfig()
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaults.PollInterval
+	}
+	if conf.LockTTL <= 0 {
+		conf.LockTTL = defaults.LockTTL
+	}
+
+	host, _ := os.Hostname()
+	return &Migrator{
+		store:      store,
+		migrations: migrations,
+		conf:       conf,
+		owner:      fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Run applies pending migrations in order of versions and returns number
+// of applied ones. If other instance is migrating, Run waits for it and
+// then applies migrations it left pending, if any.
+func (m *Migrator) Run(ctx context.Context) (int, error) {
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version <= m.migrations[i-1].Version {
+			return 0, errors.Errorf("migration %d is out of order", m.migrations[i].Version)
+		}
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.conf.PollInterval)
+		defer cancel()
+		if err := m.store.ReleaseLock(ctx, lockName, m.owner); err != nil {
+			slog.Error("error releasing migrations lock", "err", err)
+		}
+	}()
+
+	applied, err := m.store.AppliedMigrations(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get applied migrations")
+	}
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+		if len(m.migrations) == 0 || a.Version > m.migrations[len(m.migrations)-1].Version {
+			slog.WarnContext(ctx, "database has migration unknown to this version of service",
+				"version", a.Version, "description", a.Description)
+		}
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if done[migration.Version] {
+			continue
+		}
+		// lease is extended so that it doesn't expire during long migrations
+		ok, err := m.store.AcquireLock(ctx, lockName, m.owner, m.conf.LockTTL)
+		if err != nil {
+			return count, errors.Wrap(err, "extend migrations lock")
+		}
+		if !ok {
+			return count, errors.New("migrations lock is taken over by other instance")
+		}
+
+		slog.InfoContext(ctx, "applying migration", "version", migration.Version,
+			"description", migration.Description)
+		if err = m.store.ApplyMigration(ctx, migration); err != nil {
+			return count, errors.Wrapf(err, "apply migration %d", migration.Version)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// lock waits until lease is acquired or ctx is done.
+func (m *Migrator) lock(ctx context.Context) error {
+	for waiting := false; ; waiting = true {
+		ok, err := m.store.AcquireLock(ctx, lockName, m.owner, m.conf.LockTTL)
+		if err != nil {
+			return errors.Wrap(err, "acquire migrations lock")
+		}
+		if ok {
+			return nil
+		}
+		if !waiting {
+			slog.InfoContext(ctx, "waiting for other instance to apply migrations")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.conf.PollInterval):
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// memoryStore keeps applied migrations and single lease in memory.
+type memoryStore struct {
+	applied []storage.AppliedMigration
+	owner   string
+}
+
+func (s *memoryStore) AppliedMigrations(context.Context) ([]storage.AppliedMigration, error) {
+	return s.applied, nil
+}
+
+func (s *memoryStore) ApplyMigration(ctx context.Context, m storage.Migration) error {
+	if err := m.Up(ctx, nil); err != nil {
+		return err
+	}
+	s.applied = append(s.applied, storage.AppliedMigration{Version: m.Version, Description: m.Description})
+	return nil
+}
+
+func (s *memoryStore) AcquireLock(_ context.Context, _, owner string, _ time.Duration) (bool, error) {
+	if s.owner != "" && s.owner != owner {
+		return false, nil
+	}
+	s.owner = owner
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseLock(_ context.Context, _, owner string) error {
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+// recorder returns migrations appending their versions to applied.
+func recorder(applied *[]int, versions ...int) []storage.Migration {
+	migrations := make([]storage.Migration, 0, len(versions))
+	for _, v := range versions {
+		v := v
+		migrations = append(migrations, storage.Migration{Version: v, Description: "test",
+			Up: func(context.Context, *mongo.Database) error {
+				*applied = append(*applied, v)
+				return nil
+			}})
+	}
+	return migrations
+}
+
+func TestMigrator_Run(t *testing.T) {
+	var applied []int
+	store := &memoryStore{applied: []storage.AppliedMigration{{Version: 1}}}
+	require := require.New(t)
+
+	count, err := New(store, recorder(&applied, 1, 2, 3), Config{}).Run(context.TODO())
+	require.NoError(err)
+	require.Equal(2, count)
+	require.Equal([]int{2, 3}, applied, "Only pending migrations should be applied in order")
+	require.Empty(store.owner, "Lease should be released")
+
+	count, err = New(store, recorder(&applied, 1, 2, 3), Config{}).Run(context.TODO())
+	require.NoError(err)
+	require.Zero(count)
+	require.Equal([]int{2, 3}, applied)
+}
+
+func TestMigrator_Run_Failed(t *testing.T) {
+	store := &memoryStore{}
+	var applied []int
+	migrations := recorder(&applied, 1, 2, 3)
+	migrations[1].Up = func(context.Context, *mongo.Database) error { return errors.New("index build failed") }
+	require := require.New(t)
+
+	count, err := New(store, migrations, Config{}).Run(context.TODO())
+	require.ErrorContains(err, "apply migration 2")
+	require.Equal(1, count)
+	require.Equal([]int{1}, applied, "Migrations after failed one should not be applied")
+	require.Empty(store.owner)
+
+	_, err = New(store, recorder(&applied, 2, 1), Config{}).Run(context.TODO())
+	require.ErrorContains(err, "out of order")
+}
+
+func TestMigrator_Run_Locked(t *testing.T) {
+	var applied []int
+	store := &memoryStore{owner: "other"}
+	m := New(store, recorder(&applied, 1), Config{PollInterval: time.Millisecond})
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := m.Run(ctx)
+	require.ErrorIs(err, context.DeadlineExceeded, "Migrator should wait for other instance")
+	require.Empty(applied)
+
+	store.owner = ""
+	count, err := m.Run(context.TODO())
+	require.NoError(err)
+	require.Equal(1, count)
+}
@@ -0,0 +1,146 @@
+// Package notification keeps in-app inbox of notifications users get
+// about tournaments they joined and about their balance.
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// purgeInterval is period of removing notifications older than retention.
+const purgeInterval = time.Hour
+
+// Config configures retention of notifications.
+type Config struct {
+	// Retention is how long notifications are kept. Zero keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+
+	// ReadRetention is how long read notifications are kept.
+	// Zero keeps them as long as unread ones.
+	ReadRetention time.Duration `yaml:"read_retention"`
+}
+
+// DefaultConfig returns config used when notifications section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		Retention:     90 * 24 * time.Hour,
+		ReadRetention: 30 * 24 * time.Hour,
+	}
+}
+
+// Store is part of storage.DB inbox works with.
+type Store interface {
+	AddNotifications(ctx context.Context, notifications []storage.Notification) error
+	ListNotifications(ctx context.Context, userID string, unread bool, limit int64) ([]storage.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID string) (int64, error)
+	MarkNotificationRead(ctx context.Context, userID, id string) error
+	MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error)
+	DeleteNotifications(ctx context.Context, readBefore, before time.Time) (int64, error)
+}
+
+// Inbox creates notifications for domain events and removes old ones.
+type Inbox struct {
+	Store
+	conf Config
+}
+
+// NewInbox creates inbox keeping notifications in store.
+func NewInbox(store Store, conf Config) *Inbox {
+	return &Inbox{Store: store, conf: conf}
+}
+
+// Notify creates notifications for players of started, finished or cancelled
+// tournament, for its winner and for user whose balance is funded. It's
+// outbox.Handler and may be called more than once for the same event.
+func (i *Inbox) Notify(ctx context.Context, e storage.OutboxEvent) error {
+	if err := i.AddNotifications(ctx, notifications(e)); err != nil {
+		return errors.Wrapf(err, "add notifications for event %s", e.ID.Hex())
+	}
+
+	return nil
+}
+
+func notifications(e storage.OutboxEvent) []storage.Notification {
+	notify := func(userID string, kind storage.NotificationKind, amount float64) storage.Notification {
+		return storage.Notification{
+			UserID:       userID,
+			EventID:      e.ID,
+			Kind:         kind,
+			TournamentID: e.TournamentID,
+			Amount:       amount,
+		}
+	}
+
+	var notifications []storage.Notification
+	switch e.Type {
+	case storage.EventTournamentStarted:
+		for _, userID := range e.UserIDs {
+			notifications = append(notifications, notify(userID, storage.NotificationTournamentStarted, 0))
+		}
+	case storage.EventTournamentFinished:
+		for _, userID := range e.UserIDs {
+			if userID != e.UserID {
+				notifications = append(notifications, notify(userID, storage.NotificationTournamentFinished, 0))
+			}
+		}
+		notifications = append(notifications, notify(e.UserID, storage.NotificationTournamentWon, e.Amount))
+	case storage.EventTournamentCancelled:
+		for _, userID := range e.UserIDs {
+			notifications = append(notifications, notify(userID, storage.NotificationTournamentCancelled, 0))
+		}
+	case storage.EventBalanceFunded:
+		notifications = append(notifications, notify(e.UserID, storage.NotificationBalanceFunded, e.Amount))
+	}
+
+	return notifications
+}
+
+// Run removes notifications older than retention every hour until ctx is done.
+func (i *Inbox) Run(ctx context.Context) {
+	if i.conf.Retention <= 0 && i.conf.ReadRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := i.Purge(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error purging notifications", "err", err)
+		}
+		if deleted > 0 {
+			slog.InfoContext(ctx, "notifications purged", "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes notifications which are older than retention at provided time.
+func (i *Inbox) Purge(ctx context.Context, now time.Time) (int64, error) {
+	// zero time keeps everything, no notification is created before it
+	var before, readBefore time.Time
+	if i.conf.Retention > 0 {
+		before = now.Add(-i.conf.Retention)
+	}
+	readBefore = before
+	if i.conf.ReadRetention > 0 {
+		readBefore = now.Add(-i.conf.ReadRetention)
+	}
+
+	deleted, err := i.DeleteNotifications(ctx, readBefore, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "delete notifications")
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// recordingStore records notifications added and purge bounds.
+type recordingStore struct {
+	Store
+	added      []storage.Notification
+	readBefore time.Time
+	before     time.Time
+}
+
+func (s *recordingStore) AddNotifications(_ context.Context, notifications []storage.Notification) error {
+	s.added = append(s.added, notifications...)
+	return nil
+}
+
+func (s *recordingStore) DeleteNotifications(_ context.Context, readBefore, before time.Time) (int64, error) {
+	s.readBefore, s.before = readBefore, before
+	return 0, nil
+}
+
+func TestInbox_Notify(t *testing.T) {
+	store := &recordingStore{}
+	inbox := NewInbox(store, DefaultConfig())
+	require := require.New(t)
+
+	started := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentStarted,
+		TournamentID: "t1", UserIDs: []string{"u1", "u2"}}
+	finished := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentFinished,
+		TournamentID: "t1", UserID: "u2", Amount: 100, UserIDs: []string{"u1", "u2"}}
+	cancelled := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentCancelled,
+		TournamentID: "t2", UserIDs: []string{"u3"}}
+	funded := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventBalanceFunded,
+		UserID: "u1", Amount: 10}
+	taken := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventBalanceTaken,
+		UserID: "u1", Amount: 10}
+	for _, e := range []storage.OutboxEvent{started, finished, cancelled, funded, taken} {
+		require.NoError(inbox.Notify(context.TODO(), e))
+	}
+
+	require.Equal([]storage.Notification{
+		{UserID: "u1", EventID: started.ID, Kind: storage.NotificationTournamentStarted, TournamentID: "t1"},
+		{UserID: "u2", EventID: started.ID, Kind: storage.NotificationTournamentStarted, TournamentID: "t1"},
+		{UserID: "u1", EventID: finished.ID, Kind: storage.NotificationTournamentFinished, TournamentID: "t1"},
+		{UserID: "u2", EventID: finished.ID, Kind: storage.NotificationTournamentWon, TournamentID: "t1", Amount: 100},
+		{UserID: "u3", EventID: cancelled.ID, Kind: storage.NotificationTournamentCancelled, TournamentID: "t2"},
+		{UserID: "u1", EventID: funded.ID, Kind: storage.NotificationBalanceFunded, Amount: 10},
+	}, store.added, "Winner should be notified about win only and taken points should not be notified")
+}
+
+func TestInbox_Purge(t *testing.T) {
+	store := &recordingStore{}
+	now := time.Now()
+	require := require.New(t)
+
+	_, err := NewInbox(store, Config{Retention: 48 * time.Hour, ReadRetention: time.Hour}).Purge(context.TODO(), now)
+	require.NoError(err)
+	require.Equal(now.Add(-48*time.Hour), store.before)
+	require.Equal(now.Add(-time.Hour), store.readBefore)
+
+	_, err = NewInbox(store, Config{Retention: 48 * time.Hour}).Purge(context.TODO(), now)
+	require.NoError(err)
+	require.Equal(store.before, store.readBefore, "Read notifications should be kept as long as unread ones")
+
+	_, err = NewInbox(store, Config{ReadRetention: time.Hour}).Purge(context.TODO(), now)
+	require.NoError(err)
+	require.True(store.before.IsZero(), "Unread notifications should be kept forever")
+}
@@ -0,0 +1,216 @@
+// Package outbox delivers domain events which storage records in outbox
+// collection in the same transaction as mutations which caused them.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// lockPrefix starts name of lease which lets single instance
+	// dispatch events to sink.
+	lockPrefix = "outbox."
+
+	// maxBackoff limits delay between retries of failing event.
+	maxBackoff = time.Minute
+
+	// purgeInterval is period of removing events older than retention.
+	purgeInterval = time.Hour
+)
+
+// Config configures dispatcher.
+type Config struct {
+	// PollInterval is delay between checks for new events.
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// BatchSize is number of events read at once.
+	BatchSize int64 `yaml:"batch_size"`
+
+	// LockTTL is lease duration. Other instance takes over
+	// dispatching if lease holder doesn't renew it in time.
+	LockTTL time.Duration `yaml:"lock_ttl"`
+
+	// Retention is how long processed events are kept. Zero keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// DefaultConfig returns config used when outbox section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		LockTTL:      30 * time.Second,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// Store is part of storage.DB dispatcher works with.
+type Store interface {
+	PendingOutboxEvents(ctx context.Context, consumer string, limit int64) ([]storage.OutboxEvent, error)
+	MarkOutboxEventProcessed(ctx context.Context, consumer string, seq int64) error
+	DeleteProcessedOutboxEvents(ctx context.Context, consumer string, before time.Time) (int64, error)
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// Handler delivers event downstream. It may be called more than once
+// for the same event, so it should be idempotent.
+type Handler func(ctx context.Context, e storage.OutboxEvent) error
+
+// Log is handler which only logs events.
+func Log(ctx context.Context, e storage.OutboxEvent) error {
+	slog.InfoContext(ctx, "domain event", "id", e.ID.Hex(), "type", e.Type,
+		"userID", e.UserID, "tournamentID", e.TournamentID)
+	return nil
+}
+
+// Dispatcher passes pending events to handler of single sink one by one in
+// order of their sequence and moves its cursor once handler succeeds. Event
+// which fails is retried with growing delay before any later event is passed,
+// so delivery is at least once and in order. Every sink has own dispatcher,
+// so outage of one sink doesn't hold the others.
+type Dispatcher struct {
+	store    Store
+	conf     Config
+	name     string
+	handler  Handler
+	owner    string
+	failures int
+}
+
+// NewDispatcher creates dispatcher passing events of store to handler of sink
+// with provided name. Name identifies cursor and lease of sink in store.
+func NewDispatcher(store Store, conf Config, name string, handler Handler) *Dispatcher {
+	defaults := DefaultConfig()
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaults.PollInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaults.BatchSize
+	}
+	if conf.LockTTL <= 0 {
+		conf.LockTTL = defaults.LockTTL
+	}
+
+	host, _ := os.Hostname()
+	return &Dispatcher{
+		store:   store,
+		conf:    conf,
+		name:    name,
+		handler: handler,
+		owner:   fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Run dispatches events until ctx is done. Only instance holding lease
+// dispatches, others wait for it to expire.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), d.conf.PollInterval)
+		defer cancel()
+		if err := d.store.ReleaseLock(ctx, lockPrefix+d.name, d.owner); err != nil {
+			slog.Error("error releasing outbox lock", "sink", d.name, "err", err)
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var lastPurge time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		_, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error dispatching outbox events", "sink", d.name, "err", err)
+		}
+		if err == nil && d.conf.Retention > 0 && time.Since(lastPurge) > purgeInterval {
+			lastPurge = time.Now()
+			d.purge(ctx)
+		}
+
+		timer.Reset(d.delay())
+	}
+}
+
+// Dispatch passes pending events to handler until there are none left
+// or one of them fails. It returns number of processed events. Nothing is
+// dispatched if lease is held by other instance.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	ok, err := d.store.AcquireLock(ctx, lockPrefix+d.name, d.owner, d.conf.LockTTL)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire lock")
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	// lease is renewed before every event, so only handling of single event
+	// must fit in LockTTL
+	processed := 0
+	for {
+		pending, err := d.store.PendingOutboxEvents(ctx, d.name, d.conf.BatchSize)
+		if err != nil {
+			return processed, errors.Wrap(err, "read pending events")
+		}
+
+		for _, e := range pending {
+			// lease lost to other instance is not an error, it continues from here
+			if processed > 0 {
+				if ok, err = d.store.AcquireLock(ctx, lockPrefix+d.name, d.owner, d.conf.LockTTL); err != nil || !ok {
+					return processed, errors.Wrap(err, "renew lock")
+				}
+			}
+			if err = d.handler(ctx, e); err != nil {
+				d.failures++
+				return processed, errors.Wrapf(err, "handle event %s", e.ID.Hex())
+			}
+			if err = d.store.MarkOutboxEventProcessed(ctx, d.name, e.Seq); err != nil {
+				return processed, errors.Wrapf(err, "mark event %s processed", e.ID.Hex())
+			}
+			d.failures = 0
+			processed++
+		}
+
+		if int64(len(pending)) < d.conf.BatchSize {
+			return processed, nil
+		}
+	}
+}
+
+// delay returns time until next dispatch. It doubles with every
+// consecutive failure of the same event.
+func (d *Dispatcher) delay() time.Duration {
+	delay := d.conf.PollInterval
+	for i := 0; i < d.failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) purge(ctx context.Context) {
+	deleted, err := d.store.DeleteProcessedOutboxEvents(ctx, d.name, time.Now().Add(-d.conf.Retention))
+	if err != nil {
+		slog.ErrorContext(ctx, "error purging outbox", "sink", d.name, "err", err)
+		return
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "outbox purged", "sink", d.name, "deleted", deleted)
+	}
+}
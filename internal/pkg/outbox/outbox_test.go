@@ -0,0 +1,197 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// memoryStore keeps outbox, cursors and leases in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	events  []storage.OutboxEvent
+	cursors map[string]int64
+	locks   map[string]string
+}
+
+func (s *memoryStore) add(types ...storage.EventType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, typ := range types {
+		s.events = append(s.events, storage.OutboxEvent{
+			ID:   primitive.NewObjectID(),
+			Type: typ,
+			Seq:  int64(len(s.events) + 1),
+		})
+	}
+}
+
+func (s *memoryStore) PendingOutboxEvents(_ context.Context, consumer string, limit int64) ([]storage.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []storage.OutboxEvent
+	for _, e := range s.events {
+		if e.Seq > s.cursors[consumer] && int64(len(pending)) < limit {
+			pending = append(pending, e)
+		}
+	}
+
+	return pending, nil
+}
+
+func (s *memoryStore) MarkOutboxEventProcessed(_ context.Context, consumer string, seq int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cursors == nil {
+		s.cursors = make(map[string]int64)
+	}
+	if seq > s.cursors[consumer] {
+		s.cursors[consumer] = seq
+	}
+
+	return nil
+}
+
+func (s *memoryStore) DeleteProcessedOutboxEvents(context.Context, string, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memoryStore) AcquireLock(_ context.Context, name, owner string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locks == nil {
+		s.locks = make(map[string]string)
+	}
+	if s.locks[name] != "" && s.locks[name] != owner {
+		return false, nil
+	}
+	s.locks[name] = owner
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseLock(_ context.Context, name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locks[name] == owner {
+		delete(s.locks, name)
+	}
+	return nil
+}
+
+func TestDispatcher_InOrderAtLeastOnce(t *testing.T) {
+	store := &memoryStore{}
+	store.add(storage.EventUserCreated, storage.EventBalanceFunded, storage.EventTournamentCreated)
+
+	var delivered []storage.EventType
+	fail := true
+	handler := func(_ context.Context, e storage.OutboxEvent) error {
+		delivered = append(delivered, e.Type)
+		if e.Type == storage.EventBalanceFunded && fail {
+			fail = false
+			return errors.New("downstream is unavailable")
+		}
+		return nil
+	}
+	d := NewDispatcher(store, Config{BatchSize: 2}, "test", handler)
+	require := require.New(t)
+
+	n, err := d.Dispatch(context.TODO())
+	require.Error(err)
+	require.Equal(1, n)
+	require.Equal(2*DefaultConfig().PollInterval, d.delay(), "Failure should back off")
+
+	n, err = d.Dispatch(context.TODO())
+	require.NoError(err)
+	require.Equal(2, n)
+	require.Equal(DefaultConfig().PollInterval, d.delay())
+	require.Equal([]storage.EventType{
+		storage.EventUserCreated,
+		storage.EventBalanceFunded,
+		storage.EventBalanceFunded,
+		storage.EventTournamentCreated,
+	}, delivered, "Failed event should be redelivered before later ones")
+
+	pending, err := store.PendingOutboxEvents(context.TODO(), "test", 10)
+	require.NoError(err)
+	require.Empty(pending)
+}
+
+func TestDispatcher_SinksIndependent(t *testing.T) {
+	store := &memoryStore{}
+	store.add(storage.EventUserCreated, storage.EventBalanceFunded)
+
+	var delivered []storage.EventType
+	broken := NewDispatcher(store, Config{}, "broken", func(context.Context, storage.OutboxEvent) error {
+		return errors.New("downstream is unavailable")
+	})
+	working := NewDispatcher(store, Config{}, "working", func(_ context.Context, e storage.OutboxEvent) error {
+		delivered = append(delivered, e.Type)
+		return nil
+	})
+	require := require.New(t)
+
+	_, err := broken.Dispatch(context.TODO())
+	require.Error(err)
+	n, err := working.Dispatch(context.TODO())
+	require.NoError(err)
+	require.Equal(2, n, "Outage of other sink should not hold events")
+
+	n, err = working.Dispatch(context.TODO())
+	require.NoError(err)
+	require.Zero(n, "Retry of other sink should not redeliver events")
+	require.Equal([]storage.EventType{storage.EventUserCreated, storage.EventBalanceFunded}, delivered)
+
+	pending, err := store.PendingOutboxEvents(context.TODO(), "broken", 10)
+	require.NoError(err)
+	require.Len(pending, 2, "Failed sink should keep its events pending")
+}
+
+func TestDispatcher_Lock(t *testing.T) {
+	store := &memoryStore{}
+	store.add(storage.EventUserCreated)
+
+	var calls int
+	count := func(context.Context, storage.OutboxEvent) error {
+		calls++
+		return nil
+	}
+	first := NewDispatcher(store, Config{}, "test", count)
+	second := NewDispatcher(store, Config{}, "test", count)
+	require := require.New(t)
+
+	ok, err := store.AcquireLock(context.TODO(), lockPrefix+"test", first.owner, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+
+	n, err := second.Dispatch(context.TODO())
+	require.NoError(err)
+	require.Zero(n, "Instance without lease should not dispatch")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		first.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(func() bool {
+		pending, _ := store.PendingOutboxEvents(context.TODO(), "test", 10)
+		return len(pending) == 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	require.Equal(1, calls)
+	require.Empty(store.locks, "Lease should be released on stop")
+}
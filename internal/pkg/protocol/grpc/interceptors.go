@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Names of interceptors which can be listed in server config.
+const (
+	InterceptorTracing   = "tracing"
+	InterceptorLogging   = "logging"
+	InterceptorMetrics   = "metrics"
+	InterceptorRecovery  = "recovery"
+	InterceptorDeadline  = "deadline"
+	InterceptorAuth      = "auth"
+	InterceptorRateLimit = "ratelimit"
+	InterceptorErrors    = "errors"
+)
+
+// DefaultInterceptors is default chain, outermost first. Logging and metrics
+// see codes produced by recovery and error translation.
+var DefaultInterceptors = []string{
+	InterceptorTracing,
+	InterceptorLogging,
+	InterceptorMetrics,
+	InterceptorRecovery,
+	InterceptorDeadline,
+	InterceptorAuth,
+	InterceptorRateLimit,
+	InterceptorErrors,
+}
+
+// Interceptor is a pair of unary and stream interceptors doing the same job.
+type Interceptor struct {
+	Unary  grpc.UnaryServerInterceptor
+	Stream grpc.StreamServerInterceptor
+}
+
+// ValidateInterceptors checks that names are known and not repeated.
+func ValidateInterceptors(names []string) error {
+	known := make(map[string]bool, len(DefaultInterceptors))
+	for _, name := range DefaultInterceptors {
+		known[name] = true
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !known[name] {
+			return errors.Errorf("unknown interceptor %q", name)
+		}
+		if seen[name] {
+			return errors.Errorf("interceptor %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// Chain returns server options installing interceptors listed in names,
+// outermost first, taken from available.
+func Chain(names []string, available map[string]Interceptor) ([]grpc.ServerOption, error) {
+	if err := ValidateInterceptors(names); err != nil {
+		return nil, err
+	}
+
+	unary := make([]grpc.UnaryServerInterceptor, 0, len(names))
+	stream := make([]grpc.StreamServerInterceptor, 0, len(names))
+	for _, name := range names {
+		i, ok := available[name]
+		if !ok {
+			return nil, errors.Errorf("interceptor %q is not available", name)
+		}
+		unary = append(unary, i.Unary)
+		stream = append(stream, i.Stream)
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, nil
+}
+
+// Recovery converts panics in handlers to Internal status and logs them with stack.
+func Recovery() Interceptor {
+	recovered := func(ctx context.Context, method string, r interface{}) error {
+		slog.ErrorContext(ctx, "panic in grpc handler", "method", method,
+			"panic", r, "stack", string(debug.Stack()))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (resp interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = recovered(ctx, info.FullMethod, r)
+				}
+			}()
+
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = recovered(ss.Context(), info.FullMethod, r)
+				}
+			}()
+
+			return handler(srv, ss)
+		},
+	}
+}
+
+// Deadline sets timeout on calls which came without deadline. Streams are
+// long-lived by nature, so only unary calls are limited.
+func Deadline(timeout time.Duration) Interceptor {
+	return Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			return handler(srv, ss)
+		},
+	}
+}
+
+// Errors converts errors returned by handlers to statuses with translate.
+func Errors(translate func(error) *status.Status) Interceptor {
+	return Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			resp, err := handler(ctx, req)
+			if err != nil {
+				return nil, translate(err).Err()
+			}
+
+			return resp, nil
+		},
+		Stream: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			if err := handler(srv, ss); err != nil {
+				return translate(err).Err()
+			}
+
+			return nil
+		},
+	}
+}
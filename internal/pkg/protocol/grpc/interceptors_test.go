@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+)
+
+// panickingServer panics in GetUser and fails every other call with plain error.
+type panickingServer struct {
+	v1.UnimplementedTournamentServer
+	deadline chan bool
+}
+
+func (s panickingServer) GetUser(context.Context, *v1.GetUserRequest) (*v1.User, error) {
+	panic("boom")
+}
+
+func (s panickingServer) GetTournament(ctx context.Context, _ *v1.GetTournamentRequest) (*v1.TournamentInfo, error) {
+	_, ok := ctx.Deadline()
+	s.deadline <- ok
+	return nil, errors.New("storage is down")
+}
+
+func TestChain(t *testing.T) {
+	translated := status.New(codes.Unavailable, "translated")
+	opts, err := Chain([]string{InterceptorRecovery, InterceptorDeadline, InterceptorErrors}, map[string]Interceptor{
+		InterceptorRecovery: Recovery(),
+		InterceptorDeadline: Deadline(time.Minute),
+		InterceptorErrors:   Errors(func(error) *status.Status { return translated }),
+	})
+	require := require.New(t)
+	require.NoError(err)
+
+	h := health.New()
+	defer h.Drain()
+	srv := panickingServer{deadline: make(chan bool, 1)}
+	grpcServer := NewServer(srv, h, opts...)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	go func() { _ = grpcServer.Serve(l) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err)
+	defer conn.Close()
+	client := v1.NewTournamentClient(conn)
+
+	_, err = client.GetUser(context.Background(), &v1.GetUserRequest{})
+	require.Equal(codes.Internal, status.Code(err), "Panic should be recovered")
+
+	_, err = client.GetTournament(context.Background(), &v1.GetTournamentRequest{})
+	require.Equal(codes.Unavailable, status.Code(err), "Error should be translated")
+	require.True(<-srv.deadline, "Default deadline should be set")
+}
+
+func TestChain_Errors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := Chain([]string{"retry"}, nil)
+	require.EqualError(err, `unknown interceptor "retry"`)
+
+	_, err = Chain([]string{InterceptorLogging, InterceptorLogging}, nil)
+	require.EqualError(err, `interceptor "logging" is listed twice`)
+
+	_, err = Chain([]string{InterceptorLogging}, map[string]Interceptor{})
+	require.EqualError(err, `interceptor "logging" is not available`)
+}
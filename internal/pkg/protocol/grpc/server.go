@@ -2,41 +2,109 @@ package grpc
 
 import (
 	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
-	"log"
-	"net"
-	"os"
-	"os/signal"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
 )
 
-// RunServer runs gRPC service to publish ToDo service
-func RunServer(ctx context.Context, v1API v1.TournamentServer, port string) error {
-	listen, err := net.Listen("tcp", ":"+port)
-	if err != nil {
-		return err
+// NewServer creates gRPC server publishing Tournament service together with
+// standard grpc.health.v1 service. Health status reflects readiness checks
+// of h and is refreshed until h is drained.
+func NewServer(v1API v1.TournamentServer, h *health.Health, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	v1.RegisterTournamentServer(server, v1API)
+
+	// service is reported unready until first readiness check completes
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus(v1.Tournament_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go h.Watch(context.Background(), healthServer, health.DefaultInterval, v1.Tournament_ServiceDesc.ServiceName)
+
+	return server
+}
+
+// Mixed serves gRPC and plain HTTP on the same port. HTTP/2 requests
+// with application/grpc content type go to gRPC server, the rest to HTTP handler.
+// Cleartext HTTP/2 (h2c) is accepted, so no TLS is required.
+type Mixed struct {
+	grpcServer  *grpc.Server
+	httpHandler http.Handler
+	h2          *http2.Server
+	handler     http.Handler
+
+	mu       sync.Mutex
+	draining bool
+	calls    sync.WaitGroup
+}
+
+// MixedHandler creates handler serving grpcServer and httpHandler on the same port.
+func MixedHandler(grpcServer *grpc.Server, httpHandler http.Handler) *Mixed {
+	m := &Mixed{grpcServer: grpcServer, httpHandler: httpHandler, h2: &http2.Server{}}
+	m.handler = h2c.NewHandler(http.HandlerFunc(m.serve), m.h2)
+	return m
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Mixed) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.handler.ServeHTTP(w, req)
+}
+
+func (m *Mixed) serve(w http.ResponseWriter, req *http.Request) {
+	if req.ProtoMajor != 2 || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+		m.httpHandler.ServeHTTP(w, req)
+		return
 	}
 
-	// register service
-	server := grpc.NewServer()
-	v1.RegisterTournamentServer(server, v1API)
+	m.mu.Lock()
+	if m.draining {
+		m.mu.Unlock()
+		// trailers-only response, clients retry call on other instance
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+		w.Header().Set("Grpc-Message", "server is shutting down")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	m.calls.Add(1)
+	m.mu.Unlock()
+	defer m.calls.Done()
 
-	// graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		for range c {
-			// sign is a ^C, handle it
-			log.Println("shutting down gRPC server...")
+	m.grpcServer.ServeHTTP(w, req)
+}
 
-			server.GracefulStop()
+// ConfigureServer makes srv send GOAWAY to HTTP/2 connections, cleartext
+// ones included, when it is shut down, so clients stop making new calls.
+func (m *Mixed) ConfigureServer(srv *http.Server) error {
+	// ConfigureServer sets up TLS even if srv serves cleartext
+	cleartext := srv.TLSConfig == nil
+	if err := http2.ConfigureServer(srv, m.h2); err != nil {
+		return err
+	}
+	if cleartext {
+		srv.TLSConfig = nil
+	}
+
+	return nil
+}
 
-			<-ctx.Done()
-		}
-	}()
+// GracefulStop rejects new gRPC calls and waits for in-flight ones to finish.
+// It replaces grpc.Server.GracefulStop, which doesn't support calls served
+// by grpc.Server.ServeHTTP. grpc.Server.Stop still cancels in-flight calls.
+func (m *Mixed) GracefulStop() {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
 
-	// start gRPC server
-	log.Println("starting gRPC server...")
-	return server.Serve(listen)
+	m.calls.Wait()
 }
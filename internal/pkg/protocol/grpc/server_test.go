@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+)
+
+func TestMixedHandler(t *testing.T) {
+	h := health.New()
+	defer h.Drain()
+
+	grpcServer := NewServer(v1.UnimplementedTournamentServer{}, h)
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("rest"))
+	})
+	srv := httptest.NewServer(MixedHandler(grpcServer, httpHandler))
+	defer srv.Close()
+	require := require.New(t)
+
+	resp, err := http.Get(srv.URL + "/user")
+	require.NoError(err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	require.NoError(resp.Body.Close())
+	require.Equal("rest", string(body))
+
+	conn, err := grpc.Dial(strings.TrimPrefix(srv.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err)
+	defer conn.Close()
+
+	require.Eventually(func() bool {
+		healthResp, err := healthpb.NewHealthClient(conn).Check(context.TODO(), &healthpb.HealthCheckRequest{
+			Service: v1.Tournament_ServiceDesc.ServiceName,
+		})
+		return err == nil && healthResp.Status == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond, "gRPC request should be served on the same port")
+}
+
+// slowServer blocks GetTournament until release is closed.
+type slowServer struct {
+	v1.UnimplementedTournamentServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowServer) GetTournament(context.Context, *v1.GetTournamentRequest) (*v1.TournamentInfo, error) {
+	close(s.started)
+	<-s.release
+	return &v1.TournamentInfo{Name: "Cup"}, nil
+}
+
+func TestMixed_GracefulStop(t *testing.T) {
+	h := health.New()
+	defer h.Drain()
+
+	slow := &slowServer{started: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := NewServer(slow, h)
+	mixed := MixedHandler(grpcServer, http.NotFoundHandler())
+	srv := httptest.NewServer(mixed)
+	defer srv.Close()
+	require := require.New(t)
+
+	conn, err := grpc.Dial(strings.TrimPrefix(srv.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err)
+	defer conn.Close()
+	client := v1.NewTournamentClient(conn)
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := client.GetTournament(context.TODO(), &v1.GetTournamentRequest{})
+		called <- err
+	}()
+	<-slow.started
+
+	stopped := make(chan struct{})
+	go func() {
+		mixed.GracefulStop()
+		close(stopped)
+	}()
+
+	require.Eventually(func() bool {
+		_, err := client.CreateTournament(context.TODO(), &v1.CreateTournamentRequest{})
+		return status.Code(err) == codes.Unavailable
+	}, time.Second, 10*time.Millisecond, "New calls should be rejected while draining")
+	select {
+	case <-stopped:
+		require.Fail("GracefulStop should wait for in-flight calls")
+	default:
+	}
+
+	close(slow.release)
+	require.NoError(<-called, "In-flight call should be finished")
+	<-stopped
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Middleware limits HTTP requests. classify tells which class request belongs to.
+// It should be installed after auth middleware so principal is known.
+func Middleware(l *Limiter, classify func(*http.Request) Class) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			class := classify(req)
+			allowed, retryAfter := l.Allow(req.Context(), class, hostOf(req.RemoteAddr))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				slog.WarnContext(req.Context(), "too many requests", "class", class, "remote_addr", req.RemoteAddr)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// UnaryServerInterceptor is gRPC counterpart of Middleware. classify receives full method name.
+// Rejected calls get ResourceExhausted status and "retry-after" trailer.
+func UnaryServerInterceptor(l *Limiter, classify func(fullMethod string) Class) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		var clientIP string
+		if p, ok := peer.FromContext(ctx); ok {
+			clientIP = hostOf(p.Addr.String())
+		}
+
+		allowed, retryAfter := l.Allow(ctx, classify(info.FullMethod), clientIP)
+		if !allowed {
+			seconds := strconv.Itoa(retryAfterSeconds(retryAfter))
+			_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", seconds))
+			return nil, status.Errorf(codes.ResourceExhausted, "too many requests, retry after %s seconds", seconds)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is stream counterpart of UnaryServerInterceptor. Stream is limited once when opened.
+func StreamServerInterceptor(l *Limiter, classify func(fullMethod string) Class) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		var clientIP string
+		if p, ok := peer.FromContext(ss.Context()); ok {
+			clientIP = hostOf(p.Addr.String())
+		}
+
+		allowed, retryAfter := l.Allow(ss.Context(), classify(info.FullMethod), clientIP)
+		if !allowed {
+			seconds := strconv.Itoa(retryAfterSeconds(retryAfter))
+			ss.SetTrailer(metadata.Pairs("retry-after", seconds))
+			return status.Errorf(codes.ResourceExhausted, "too many requests, retry after %s seconds", seconds)
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
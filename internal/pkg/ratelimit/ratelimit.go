@@ -0,0 +1,98 @@
+// Package ratelimit implements token bucket rate limiting of requests
+// per principal and per client IP with separate limits per route class.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+)
+
+// Class groups routes which share the same limit.
+type Class string
+
+const (
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+	ClassMoney Class = "money"
+	ClassAdmin Class = "admin"
+
+	// ClassProbe is used for health probes and metrics scraping.
+	// It is absent in DefaultLimits, so such requests are never limited.
+	ClassProbe Class = "probe"
+)
+
+// Limit describes token bucket: Rate tokens are added per second up to Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultLimits returns limits used when none are configured.
+func DefaultLimits() map[Class]Limit {
+	return map[Class]Limit{
+		ClassRead:  {Rate: 20, Burst: 40},
+		ClassWrite: {Rate: 5, Burst: 10},
+		ClassMoney: {Rate: 1, Burst: 5},
+		ClassAdmin: {Rate: 2, Burst: 5},
+	}
+}
+
+// Store keeps token buckets. MemoryStore is used by default,
+// shared implementation may be plugged in to limit across instances.
+type Store interface {
+	// Allow takes one token from bucket with provided key. If bucket is empty
+	// it returns false and duration after which token will be available.
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// Limiter checks requests against per principal and per client IP buckets.
+type Limiter struct {
+	store  Store
+	limits map[Class]Limit
+}
+
+// NewLimiter creates Limiter. Classes missing in limits are not limited.
+func NewLimiter(store Store, limits map[Class]Limit) *Limiter {
+	return &Limiter{
+		store:  store,
+		limits: limits,
+	}
+}
+
+// Allow checks request of provided class made from clientIP. If ctx carries
+// authenticated principal his bucket is checked as well. Returned duration
+// tells when the request may be retried if it is not allowed.
+func (l *Limiter) Allow(ctx context.Context, class Class, clientIP string) (bool, time.Duration) {
+	limit, ok := l.limits[class]
+	if !ok {
+		return true, 0
+	}
+
+	keys := []string{string(class) + ":ip:" + clientIP}
+	if p, err := auth.FromContext(ctx); err == nil {
+		keys = append(keys, string(class)+":user:"+p.UserID)
+	}
+
+	for _, key := range keys {
+		allowed, retryAfter, err := l.store.Allow(ctx, key, limit)
+		if err != nil {
+			// fail open, limiter must not take service down
+			slog.ErrorContext(ctx, "rate limit store failed", "key", key, "err", err)
+			continue
+		}
+		if !allowed {
+			return false, retryAfter
+		}
+	}
+
+	return true, 0
+}
+
+// retryAfterSeconds rounds duration up to whole seconds, at least one.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+)
+
+func TestMemoryStore_Allow(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	require := require.New(t)
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Allow(context.TODO(), "key", limit)
+		require.NoError(err)
+		require.True(allowed, "Request within burst should be allowed")
+	}
+
+	allowed, retryAfter, err := store.Allow(context.TODO(), "key", limit)
+	require.NoError(err)
+	require.False(allowed, "Request over burst should be rejected")
+	require.Equal(time.Second, retryAfter, "The two durations should be the same")
+
+	allowed, _, err = store.Allow(context.TODO(), "other key", limit)
+	require.NoError(err)
+	require.True(allowed, "Buckets should be independent")
+
+	now = now.Add(time.Second)
+	allowed, _, err = store.Allow(context.TODO(), "key", limit)
+	require.NoError(err)
+	require.True(allowed, "Bucket should be refilled")
+
+	now = now.Add(2 * sweepInterval)
+	_, _, err = store.Allow(context.TODO(), "key", limit)
+	require.NoError(err)
+	require.Len(store.buckets, 1, "Idle buckets should be swept")
+}
+
+func TestLimiter_Allow_Per_Principal(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), map[Class]Limit{ClassMoney: {Rate: 0.001, Burst: 1}})
+	ctx := auth.NewContext(context.TODO(), &auth.Principal{UserID: "user-1"})
+	require := require.New(t)
+
+	allowed, _ := l.Allow(ctx, ClassMoney, "10.0.0.1")
+	require.True(allowed)
+
+	allowed, _ = l.Allow(ctx, ClassMoney, "10.0.0.2")
+	require.False(allowed, "Principal bucket should be shared between IPs")
+
+	allowed, _ = l.Allow(context.TODO(), ClassMoney, "10.0.0.3")
+	require.True(allowed, "Anonymous request from other IP should be allowed")
+
+	allowed, _ = l.Allow(ctx, ClassRead, "10.0.0.2")
+	require.True(allowed, "Class without limit should not be limited")
+}
+
+func TestMiddleware(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), map[Class]Limit{ClassWrite: {Rate: 0.5, Burst: 1}})
+	handler := Middleware(l, func(*http.Request) Class { return ClassWrite })(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	require := require.New(t)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/user", nil))
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/user", nil))
+	require.Equal(http.StatusTooManyRequests, w.Result().StatusCode, "The two http codes should be the same")
+	require.Equal("2", w.Result().Header.Get("Retry-After"), "The two headers should be the same")
+}
+
+// trailerStream records trailer set by interceptor.
+type trailerStream struct {
+	grpc.ServerStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *trailerStream) SetTrailer(md metadata.MD) {
+	s.trailer = md
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), map[Class]Limit{ClassRead: {Rate: 0.5, Burst: 1}})
+	interceptor := StreamServerInterceptor(l, func(string) Class { return ClassRead })
+	info := &grpc.StreamServerInfo{FullMethod: "/main.Tournament/WatchTournament"}
+	handler := func(interface{}, grpc.ServerStream) error { return nil }
+	require := require.New(t)
+
+	ss := &trailerStream{}
+	require.NoError(interceptor(nil, ss, info, handler))
+	err := interceptor(nil, ss, info, handler)
+	require.Equal(codes.ResourceExhausted, status.Code(err), "The two codes should be the same")
+	require.Equal([]string{"2"}, ss.trailer.Get("retry-after"), "The two trailers should be the same")
+}
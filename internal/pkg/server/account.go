@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+type userID struct {
+	ID string `json:"userID"`
+}
+
+type registration struct {
+	Name     string `json:"name"`
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type loginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type refreshToken struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type passwordChange struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// WithSessions enables account endpoints and authenticates requests with access tokens
+// issued by provided sessions.
+func WithSessions(sessions *auth.Sessions) Option {
+	return func(s *Server) {
+		s.sessions = sessions
+		s.authn = sessions
+	}
+}
+
+func (s *Server) registerAccountRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/register", s.register).Methods("POST")
+	router.HandleFunc("/auth/login", s.login).Methods("POST")
+	router.HandleFunc("/auth/refresh", s.refresh).Methods("POST")
+	router.HandleFunc("/auth/logout", s.logout).Methods("POST")
+	router.HandleFunc("/user/{id}/password", s.changePassword).Methods("PUT")
+}
+
+func (s *Server) register(w http.ResponseWriter, req *http.Request) {
+	var reg registration
+	err := decodeBody(w, req, &reg)
+	if err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "register", "err", err)
+		return
+	}
+
+	var v validation.Validator
+	v.Name("name", reg.Name)
+	v.Login("login", reg.Login)
+	v.Password("password", reg.Password)
+	if !validate(w, req, "register", &v) {
+		return
+	}
+
+	usrID, err := s.sessions.Register(req.Context(), reg.Name, reg.Login, reg.Password)
+	if errors.Is(err, storage.ErrLoginTaken) {
+		w.WriteHeader(http.StatusConflict)
+		slog.WarnContext(req.Context(), "request rejected", "handler", "register", "err", err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "request failed", "handler", "register", "err", err)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(userID{
+		ID: usrID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "error encoding json", "handler", "register", "err", err)
+		return
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, req *http.Request) {
+	var creds loginRequest
+	err := decodeBody(w, req, &creds)
+	if err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "login", "err", err)
+		return
+	}
+
+	tokens, err := s.sessions.Login(req.Context(), creds.Login, creds.Password)
+	s.writeTokens(w, req, "login", tokens, err)
+}
+
+func (s *Server) refresh(w http.ResponseWriter, req *http.Request) {
+	var token refreshToken
+	err := decodeBody(w, req, &token)
+	if err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "refresh", "err", err)
+		return
+	}
+
+	var v validation.Validator
+	if token.RefreshToken == "" {
+		v.Add("refreshToken", "must not be empty")
+	}
+	if !validate(w, req, "refresh", &v) {
+		return
+	}
+
+	tokens, err := s.sessions.Refresh(req.Context(), token.RefreshToken)
+	s.writeTokens(w, req, "refresh", tokens, err)
+}
+
+func (s *Server) logout(w http.ResponseWriter, req *http.Request) {
+	var token refreshToken
+	err := decodeBody(w, req, &token)
+	if err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "logout", "err", err)
+		return
+	}
+
+	var v validation.Validator
+	if token.RefreshToken == "" {
+		v.Add("refreshToken", "must not be empty")
+	}
+	if !validate(w, req, "logout", &v) {
+		return
+	}
+
+	if err = s.sessions.Logout(req.Context(), token.RefreshToken); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		slog.WarnContext(req.Context(), "request rejected", "handler", "logout", "err", err)
+		return
+	}
+}
+
+func (s *Server) changePassword(w http.ResponseWriter, req *http.Request) {
+	var change passwordChange
+	err := decodeBody(w, req, &change)
+	if err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "changePassword", "err", err)
+		return
+	}
+
+	vars := mux.Vars(req)
+	userID, ok := vars["id"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		slog.WarnContext(req.Context(), "user id is not provided", "handler", "changePassword")
+		return
+	}
+
+	var v validation.Validator
+	v.ObjectID("id", userID)
+	v.Password("newPassword", change.NewPassword)
+	if !validate(w, req, "changePassword", &v) {
+		return
+	}
+
+	if _, err = auth.RequireSelf(req.Context(), userID); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "changePassword", "err", err)
+		return
+	}
+
+	err = s.sessions.ChangePassword(req.Context(), userID, change.OldPassword, change.NewPassword)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		w.WriteHeader(http.StatusForbidden)
+		slog.WarnContext(req.Context(), "request rejected", "handler", "changePassword", "err", err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "request failed", "handler", "changePassword", "err", err)
+		return
+	}
+}
+
+func (s *Server) writeTokens(w http.ResponseWriter, req *http.Request, op string, tokens *auth.Tokens, err error) {
+	if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInvalidToken) {
+		w.WriteHeader(http.StatusUnauthorized)
+		slog.WarnContext(req.Context(), "request rejected", "handler", op, "err", err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "request failed", "handler", op, "err", err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(tokens); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "error encoding json", "handler", op, "err", err)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestRegister_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	randomUserID := primitive.NewObjectID().Hex()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().RegisterUser(gomock.Any(), gomock.Eq("Gennadiy"), gomock.Eq("gena"), gomock.Any()).
+		Times(1).Return(randomUserID, nil)
+
+	enc, err := json.Marshal(registration{Name: "Gennadiy", Login: "gena", Password: "secret-password"})
+	require := require.New(t)
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/auth/register", bytes.NewBuffer(enc))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock, WithSessions(auth.NewSessions(mock, []byte("key"), time.Minute, time.Hour)))
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+
+	var actualUserID userID
+	err = json.NewDecoder(w.Result().Body).Decode(&actualUserID)
+	require.NoError(err)
+	require.Equal(userID{ID: randomUserID}, actualUserID, "The two bodies shoud be the same")
+}
+
+func TestRegister_Login_Taken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().RegisterUser(gomock.Any(), gomock.Any(), gomock.Eq("gena"), gomock.Any()).
+		Times(1).Return("", storage2.ErrLoginTaken)
+
+	enc, err := json.Marshal(registration{Name: "Gennadiy", Login: "gena", Password: "secret-password"})
+	require := require.New(t)
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/auth/register", bytes.NewBuffer(enc))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock, WithSessions(auth.NewSessions(mock, []byte("key"), time.Minute, time.Hour)))
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusConflict, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestLogin_Invalid_Credentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetCredentialsByLogin(gomock.Any(), gomock.Eq("gena")).
+		Times(1).Return(nil, errors.New("get doc from collection"))
+
+	enc, err := json.Marshal(loginRequest{Login: "gena", Password: "secret-password"})
+	require := require.New(t)
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/auth/login", bytes.NewBuffer(enc))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock, WithSessions(auth.NewSessions(mock, []byte("key"), time.Minute, time.Hour)))
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusUnauthorized, w.Result().StatusCode, "The two http codes should be the same")
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/audit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+
+	// auditExportPage is number of entries read at once while exporting CSV.
+	auditExportPage = 1000
+)
+
+var auditCSVHeader = []string{
+	"id", "time", "actorId", "actorRole", "action", "targetIds", "amount", "requestId", "clientIp", "before", "after",
+}
+
+type auditList struct {
+	Entries []storage.AuditEntry `json:"entries"`
+	// Next is value of before parameter reading the next page, it's empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// WithAudit enables admin endpoints querying and exporting audit log and
+// records IP of HTTP clients for audit entries.
+func WithAudit(store audit.Store) Option {
+	return func(s *Server) {
+		s.audit = store
+	}
+}
+
+func (s *Server) registerAuditRoutes(router *mux.Router) {
+	router.HandleFunc("/audit", s.listAuditEntries).Methods("GET")
+	router.HandleFunc("/audit/export", s.exportAuditEntries).Methods("GET")
+}
+
+func (s *Server) listAuditEntries(w http.ResponseWriter, req *http.Request) {
+	filter, ok := auditFilter(w, req, "listAuditEntries", defaultAuditLimit, maxAuditLimit)
+	if !ok {
+		return
+	}
+
+	entries, err := s.audit.ListAuditEntries(req.Context(), filter)
+	if err != nil {
+		writeStorageError(w, req, "listAuditEntries", err)
+		return
+	}
+
+	list := auditList{Entries: entries}
+	if int64(len(entries)) == filter.Limit {
+		list.Next = entries[len(entries)-1].ID.Hex()
+	}
+	writeJSON(w, req, "listAuditEntries", list)
+}
+
+// exportAuditEntries streams all entries matching filter as CSV. Limit is
+// optional here, entries are read page by page.
+func (s *Server) exportAuditEntries(w http.ResponseWriter, req *http.Request) {
+	filter, ok := auditFilter(w, req, "exportAuditEntries", 0, 0)
+	if !ok {
+		return
+	}
+	limit := filter.Limit
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+	out := csv.NewWriter(w)
+	_ = out.Write(auditCSVHeader)
+	var written int64
+	for {
+		filter.Limit = auditExportPage
+		if limit > 0 && limit-written < auditExportPage {
+			filter.Limit = limit - written
+		}
+		entries, err := s.audit.ListAuditEntries(req.Context(), filter)
+		if err != nil {
+			// header is sent already, truncated file is the only way to report failure
+			slog.ErrorContext(req.Context(), "request failed", "handler", "exportAuditEntries", "err", err)
+			return
+		}
+
+		for _, e := range entries {
+			if err = out.Write(auditCSVRecord(e)); err != nil {
+				slog.WarnContext(req.Context(), "error writing csv", "handler", "exportAuditEntries", "err", err)
+				return
+			}
+		}
+		written += int64(len(entries))
+		if int64(len(entries)) < filter.Limit || (limit > 0 && written >= limit) {
+			break
+		}
+		filter.BeforeID = entries[len(entries)-1].ID
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		slog.WarnContext(req.Context(), "error writing csv", "handler", "exportAuditEntries", "err", err)
+	}
+}
+
+// auditFilter checks that caller is admin and parses filter of request query.
+// Zero max leaves limit optional and unbounded.
+func auditFilter(w http.ResponseWriter, req *http.Request, op string, def, max int64) (storage.AuditFilter, bool) {
+	if _, err := auth.RequireAdmin(req.Context()); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", op, "err", err)
+		return storage.AuditFilter{}, false
+	}
+
+	query := req.URL.Query()
+	filter := storage.AuditFilter{
+		ActorID:  query.Get("actor"),
+		Action:   storage.AuditAction(query.Get("action")),
+		TargetID: query.Get("target"),
+	}
+	var v validation.Validator
+	if filter.Action != "" && !filter.Action.Valid() {
+		v.Add("action", "must be known audit action")
+	}
+	filter.From = queryTime(&v, req, "from")
+	filter.To = queryTime(&v, req, "to")
+	if before := query.Get("before"); before != "" {
+		var err error
+		if filter.BeforeID, err = primitive.ObjectIDFromHex(before); err != nil {
+			v.Add("before", "must be 24 characters hex string")
+		}
+	}
+	if max > 0 {
+		filter.Limit = queryLimit(&v, req, def, max)
+	} else if raw := query.Get("limit"); raw != "" {
+		var err error
+		if filter.Limit, err = strconv.ParseInt(raw, 10, 64); err != nil || filter.Limit < 1 {
+			v.Add("limit", "must be positive integer")
+		}
+	}
+	if !validate(w, req, op, &v) {
+		return storage.AuditFilter{}, false
+	}
+
+	return filter, true
+}
+
+func auditCSVRecord(e storage.AuditEntry) []string {
+	snapshot := func(m map[string]interface{}) string {
+		if m == nil {
+			return ""
+		}
+		data, _ := json.Marshal(m)
+		return string(data)
+	}
+
+	amount := ""
+	if e.Amount != 0 {
+		amount = strconv.FormatFloat(e.Amount, 'f', -1, 64)
+	}
+	record := []string{
+		e.ID.Hex(),
+		e.Time.UTC().Format(time.RFC3339),
+		e.ActorID,
+		string(e.ActorRole),
+		string(e.Action),
+		strings.Join(e.TargetIDs, " "),
+		amount,
+		e.RequestID,
+		e.ClientIP,
+		snapshot(e.Before),
+		snapshot(e.After),
+	}
+	for i, cell := range record {
+		record[i] = csvCell(cell)
+	}
+
+	return record
+}
+
+// csvCell keeps spreadsheets from evaluating client provided values, like
+// request id, as formulas.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/audit"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// auditStore keeps audit entries newest first and records filters it's queried with.
+type auditStore struct {
+	audit.Store
+	entries []storage2.AuditEntry
+	filters []storage2.AuditFilter
+}
+
+func (s *auditStore) ListAuditEntries(_ context.Context, f storage2.AuditFilter) ([]storage2.AuditEntry, error) {
+	s.filters = append(s.filters, f)
+	entries := []storage2.AuditEntry{}
+	for _, e := range s.entries {
+		if (f.BeforeID.IsZero() || e.ID.Hex() < f.BeforeID.Hex()) && (f.Action == "" || e.Action == f.Action) &&
+			(f.Limit == 0 || int64(len(entries)) < f.Limit) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func newAuditStore(n int) *auditStore {
+	store := &auditStore{}
+	ids := make([]primitive.ObjectID, n)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	for i := n - 1; i >= 0; i-- {
+		store.entries = append(store.entries, storage2.AuditEntry{
+			ID:        ids[i],
+			Time:      time.Date(2024, 5, 1, 12, 0, i, 0, time.UTC),
+			ActorID:   "admin",
+			ActorRole: storage2.RoleAdmin,
+			Action:    storage2.AuditBalanceFunded,
+			TargetIDs: []string{"u1"},
+			Amount:    10,
+			After:     map[string]interface{}{"balance": 10.0},
+			RequestID: "=HYPERLINK(\"http://evil\")",
+		})
+	}
+	return store
+}
+
+func TestAuditEntries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := newAuditStore(3)
+	s := NewServer(storage2.NewMockService(ctrl), WithAudit(store))
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/audit?action=balance.funded&from=2024-05-01T00:00:00Z&limit=2", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+
+	var list auditList
+	require.NoError(json.NewDecoder(w.Body).Decode(&list))
+	require.Len(list.Entries, 2)
+	require.Equal(store.entries[1].ID.Hex(), list.Next, "Full page should point to the next one")
+	require.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), store.filters[0].From)
+
+	req = httptest.NewRequest("GET", "/audit?before="+list.Next, nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	list = auditList{}
+	require.NoError(json.NewDecoder(w.Body).Decode(&list))
+	require.Len(list.Entries, 1)
+	require.Empty(list.Next)
+
+	req = httptest.NewRequest("GET", "/audit", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleOrganizer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusForbidden, w.Code, "Audit log should be read by admins only")
+
+	req = httptest.NewRequest("GET", "/audit?action=unknown&from=yesterday&before=bad&limit=5000", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code)
+	var resp validationErrors
+	require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(resp.Errors, 4)
+}
+
+func TestExportAuditEntries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := newAuditStore(auditExportPage + 5)
+	s := NewServer(storage2.NewMockService(ctrl), WithAudit(store))
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/audit/export", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.Equal("text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(err)
+	require.Len(records, auditExportPage+6, "All pages should be exported after header")
+	require.Equal(auditCSVHeader, records[0])
+	require.Equal([]string{store.entries[0].ID.Hex(), store.entries[0].Time.Format(time.RFC3339),
+		"admin", "admin", "balance.funded", "u1", "10", `'=HYPERLINK("http://evil")`, "", "", `{"balance":10}`}, records[1],
+		"Formula should be escaped")
+	require.Len(store.filters, 2)
+
+	req = httptest.NewRequest("GET", "/audit/export?limit=3", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	records, err = csv.NewReader(w.Body).ReadAll()
+	require.NoError(err)
+	require.Len(records, 4)
+}
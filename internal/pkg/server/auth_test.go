@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func withPrincipal(req *http.Request, userID string, role storage2.Role) *http.Request {
+	return req.WithContext(auth.NewContext(req.Context(), &auth.Principal{
+		UserID: userID,
+		Role:   role,
+	}))
+}
+
+func TestAddUserBonusPoints_Unauthenticated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	userID := primitive.NewObjectID()
+	enc, err := json.Marshal(userPoints{Points: 200})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/user/%s/fund", userID.Hex())
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusUnauthorized, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestAddUserBonusPoints_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	userID := primitive.NewObjectID()
+	enc, err := json.Marshal(userPoints{Points: 200})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/user/%s/fund", userID.Hex())
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	req = withPrincipal(req, userID.Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusForbidden, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestTakeUserBonusPoints_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().TakeUserBalance(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	userID := primitive.NewObjectID()
+	enc, err := json.Marshal(userPoints{Points: 200})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/user/%s/take", userID.Hex())
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusForbidden, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestCreateNewTournament_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().AddTournament(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	enc, err := json.Marshal(tournament{Name: "Tournament_1", Deposit: 1500})
+	require := require.New(t)
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/tournament", bytes.NewBuffer(enc))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusForbidden, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestJoinTournament_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().JoinTournament(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	tournamentID := primitive.NewObjectID().Hex()
+	enc, err := json.Marshal(userID{ID: primitive.NewObjectID().Hex()})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/tournament/%s/join", tournamentID)
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusForbidden, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestFinishTournament_NotOrganizer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	tournamentID := primitive.NewObjectID().Hex()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: primitive.NewObjectID()}, nil)
+	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	enc, err := json.Marshal(winnerUserID{ID: primitive.NewObjectID().Hex()})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/tournament/%s/finish", tournamentID)
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleOrganizer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusForbidden, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestCancelTournament_Admin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	tournamentID := primitive.NewObjectID().Hex()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: primitive.NewObjectID()}, nil)
+	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).Return(nil)
+
+	expectedURLPath := fmt.Sprintf("/tournament/%s", tournamentID)
+	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+}
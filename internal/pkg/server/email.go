@@ -0,0 +1,226 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/mail"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+type emailSettings struct {
+	Email    string                     `json:"email"`
+	Verified bool                       `json:"verified"`
+	OptOut   []storage.NotificationKind `json:"optOut"`
+}
+
+type emailChange struct {
+	Email string `json:"email"`
+}
+
+type emailVerification struct {
+	Token string `json:"token"`
+}
+
+type emailPreferences struct {
+	OptOut []storage.NotificationKind `json:"optOut"`
+}
+
+type tournamentSchedule struct {
+	StartsAt time.Time `json:"startsAt"`
+}
+
+// WithMailer enables endpoints verifying user email, managing email preferences
+// and scheduling tournaments which players are reminded about by email.
+func WithMailer(m *mail.Mailer) Option {
+	return func(s *Server) {
+		s.mailer = m
+	}
+}
+
+func (s *Server) registerEmailRoutes(router *mux.Router) {
+	router.HandleFunc("/user/{id}/email", s.getEmail).Methods("GET")
+	router.HandleFunc("/user/{id}/email", s.changeEmail).Methods("PUT")
+	router.HandleFunc("/user/{id}/email/verify", s.verifyEmail).Methods("POST")
+	router.HandleFunc("/user/{id}/email/preferences", s.setEmailPreferences).Methods("PUT")
+	router.HandleFunc("/tournament/{id}/schedule", s.scheduleTournament).Methods("PUT")
+}
+
+func (s *Server) getEmail(w http.ResponseWriter, req *http.Request) {
+	userID, ok := s.selfUserID(w, req, "getEmail")
+	if !ok {
+		return
+	}
+
+	user, err := s.mailer.GetUser(req.Context(), userID)
+	if err != nil {
+		writeStorageError(w, req, "getEmail", err)
+		return
+	}
+
+	optOut := user.EmailOptOut
+	if optOut == nil {
+		optOut = []storage.NotificationKind{}
+	}
+	writeJSON(w, req, "getEmail", emailSettings{Email: user.Email, Verified: user.EmailVerified, OptOut: optOut})
+}
+
+func (s *Server) changeEmail(w http.ResponseWriter, req *http.Request) {
+	var change emailChange
+	if err := decodeBody(w, req, &change); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "changeEmail", "err", err)
+		return
+	}
+
+	userID, ok := s.selfUserID(w, req, "changeEmail")
+	if !ok {
+		return
+	}
+
+	var v validation.Validator
+	v.Email("email", change.Email)
+	if !validate(w, req, "changeEmail", &v) {
+		return
+	}
+
+	if err := s.mailer.RequestVerification(req.Context(), userID, change.Email); err != nil {
+		writeStorageError(w, req, "changeEmail", err)
+		return
+	}
+
+	// email is used once verification email sent asynchronously is confirmed
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) verifyEmail(w http.ResponseWriter, req *http.Request) {
+	var verification emailVerification
+	if err := decodeBody(w, req, &verification); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "verifyEmail", "err", err)
+		return
+	}
+
+	userID, ok := s.selfUserID(w, req, "verifyEmail")
+	if !ok {
+		return
+	}
+
+	var v validation.Validator
+	if verification.Token == "" {
+		v.Add("token", "must not be empty")
+	}
+	if !validate(w, req, "verifyEmail", &v) {
+		return
+	}
+
+	err := s.mailer.Verify(req.Context(), userID, verification.Token)
+	if errors.Is(err, mail.ErrInvalidToken) {
+		v.Add("token", "is invalid or expired")
+		validate(w, req, "verifyEmail", &v)
+		return
+	}
+	if err != nil {
+		writeStorageError(w, req, "verifyEmail", err)
+		return
+	}
+}
+
+func (s *Server) setEmailPreferences(w http.ResponseWriter, req *http.Request) {
+	var prefs emailPreferences
+	if err := decodeBody(w, req, &prefs); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "setEmailPreferences", "err", err)
+		return
+	}
+
+	userID, ok := s.selfUserID(w, req, "setEmailPreferences")
+	if !ok {
+		return
+	}
+
+	var v validation.Validator
+	for _, kind := range prefs.OptOut {
+		if !emailed(kind) {
+			v.Add("optOut", "must contain only tournamentStarting, tournamentStarted or tournamentWon")
+			break
+		}
+	}
+	if !validate(w, req, "setEmailPreferences", &v) {
+		return
+	}
+
+	if err := s.mailer.SetUserEmailOptOut(req.Context(), userID, prefs.OptOut); err != nil {
+		writeStorageError(w, req, "setEmailPreferences", err)
+		return
+	}
+}
+
+func (s *Server) scheduleTournament(w http.ResponseWriter, req *http.Request) {
+	var schedule tournamentSchedule
+	if err := decodeBody(w, req, &schedule); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "scheduleTournament", "err", err)
+		return
+	}
+
+	tournamentID := mux.Vars(req)["id"]
+	var v validation.Validator
+	v.ObjectID("id", tournamentID)
+	if !schedule.StartsAt.After(time.Now()) {
+		v.Add("startsAt", "must be in the future")
+	}
+	if !validate(w, req, "scheduleTournament", &v) {
+		return
+	}
+
+	tournament, err := s.mailer.GetTournament(req.Context(), tournamentID)
+	if err != nil {
+		writeStorageError(w, req, "scheduleTournament", err)
+		return
+	}
+	if _, err = auth.RequireTournamentOrganizer(req.Context(), tournament); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "scheduleTournament", "err", err)
+		return
+	}
+
+	if err = s.mailer.ScheduleTournament(req.Context(), tournamentID, schedule.StartsAt); err != nil {
+		writeStorageError(w, req, "scheduleTournament", err)
+		return
+	}
+}
+
+// selfUserID returns valid user id of request path if caller is that user or admin.
+func (s *Server) selfUserID(w http.ResponseWriter, req *http.Request, op string) (string, bool) {
+	userID := mux.Vars(req)["id"]
+	var v validation.Validator
+	v.ObjectID("id", userID)
+	if !validate(w, req, op, &v) {
+		return "", false
+	}
+
+	if _, err := auth.RequireSelf(req.Context(), userID); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", op, "err", err)
+		return "", false
+	}
+
+	return userID, true
+}
+
+// emailed reports whether notifications of kind are sent by email.
+func emailed(kind storage.NotificationKind) bool {
+	for _, k := range mail.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/mail"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// emailStore keeps user, tournament and queued emails in memory.
+type emailStore struct {
+	mail.Store
+	user       storage2.User
+	tournament storage2.Tournament
+	emails     []storage2.Email
+}
+
+func (s *emailStore) GetUser(_ context.Context, id string) (*storage2.User, error) {
+	if id != s.user.ID.Hex() {
+		return nil, mongo.ErrNoDocuments
+	}
+	user := s.user
+	return &user, nil
+}
+
+func (s *emailStore) GetTournament(_ context.Context, id string) (*storage2.Tournament, error) {
+	if id != s.tournament.ID.Hex() {
+		return nil, mongo.ErrNoDocuments
+	}
+	tournament := s.tournament
+	return &tournament, nil
+}
+
+func (s *emailStore) ScheduleTournament(_ context.Context, _ string, startsAt time.Time) error {
+	s.tournament.StartsAt = &startsAt
+	return nil
+}
+
+func (s *emailStore) SetUserEmail(_ context.Context, _, email string, v storage2.EmailVerification) error {
+	s.user.Email, s.user.EmailVerified, s.user.Verification = email, false, &v
+	return nil
+}
+
+func (s *emailStore) VerifyUserEmail(_ context.Context, _, tokenHash string, _ time.Time) error {
+	if s.user.Verification == nil || s.user.Verification.TokenHash != tokenHash {
+		return mongo.ErrNoDocuments
+	}
+	s.user.EmailVerified, s.user.Verification = true, nil
+	return nil
+}
+
+func (s *emailStore) SetUserEmailOptOut(_ context.Context, _ string, kinds []storage2.NotificationKind) error {
+	s.user.EmailOptOut = kinds
+	return nil
+}
+
+func (s *emailStore) AddEmail(_ context.Context, e storage2.Email) error {
+	s.emails = append(s.emails, e)
+	return nil
+}
+
+func TestEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := &emailStore{user: storage2.User{ID: primitive.NewObjectID(), Name: "Gena"}}
+	userID := store.user.ID.Hex()
+	s := NewServer(storage2.NewMockService(ctrl), WithMailer(mail.NewMailer(store, nil, mail.DefaultConfig())))
+	require := require.New(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, fmt.Sprintf("/user/%s/email%s", userID, path), strings.NewReader(body))
+		req = withPrincipal(req, userID, storage2.RolePlayer)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("PUT", "", `{"email":"gena@example.com"}`)
+	require.Equal(http.StatusAccepted, w.Code)
+	require.Len(store.emails, 1)
+	require.Equal("gena@example.com", store.emails[0].To)
+
+	w = do("POST", "/verify", `{"token":"wrong"}`)
+	require.Equal(http.StatusBadRequest, w.Code, "Wrong token should not verify email")
+
+	var token string
+	for _, word := range strings.Fields(store.emails[0].Body) {
+		if len(word) == 64 {
+			token = word
+		}
+	}
+	w = do("POST", "/verify", fmt.Sprintf(`{"token":%q}`, token))
+	require.Equal(http.StatusOK, w.Code)
+
+	w = do("PUT", "/preferences", `{"optOut":["tournamentStarted"]}`)
+	require.Equal(http.StatusOK, w.Code)
+	w = do("PUT", "/preferences", `{"optOut":["balanceFunded"]}`)
+	require.Equal(http.StatusBadRequest, w.Code, "Notifications which aren't emailed should not be accepted")
+
+	w = do("GET", "", "")
+	require.Equal(http.StatusOK, w.Code)
+	var settings emailSettings
+	require.NoError(json.NewDecoder(w.Body).Decode(&settings))
+	require.Equal(emailSettings{
+		Email:    "gena@example.com",
+		Verified: true,
+		OptOut:   []storage2.NotificationKind{storage2.NotificationTournamentStarted},
+	}, settings)
+}
+
+func TestEmail_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := &emailStore{user: storage2.User{ID: primitive.NewObjectID()}}
+	s := NewServer(storage2.NewMockService(ctrl), WithMailer(mail.NewMailer(store, nil, mail.DefaultConfig())))
+	require := require.New(t)
+
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/user/%s/email", store.user.ID.Hex()),
+		strings.NewReader(`{"email":"Gena <gena@example.com>"}`))
+	req = withPrincipal(req, store.user.ID.Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("PUT", fmt.Sprintf("/user/%s/email", store.user.ID.Hex()),
+		strings.NewReader(`{"email":"gena@example.com"}`))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusForbidden, w.Code, "Email of other user should not be changed")
+	require.Empty(store.emails)
+}
+
+func TestScheduleTournament(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	organizer := primitive.NewObjectID()
+	store := &emailStore{tournament: storage2.Tournament{ID: primitive.NewObjectID(), Organizer: organizer}}
+	s := NewServer(storage2.NewMockService(ctrl), WithMailer(mail.NewMailer(store, nil, mail.DefaultConfig())))
+	require := require.New(t)
+
+	do := func(userID, tournamentID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/tournament/%s/schedule", tournamentID), strings.NewReader(body))
+		req = withPrincipal(req, userID, storage2.RoleOrganizer)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	startsAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"startsAt":%q}`, startsAt.Format(time.RFC3339))
+	w := do(primitive.NewObjectID().Hex(), store.tournament.ID.Hex(), body)
+	require.Equal(http.StatusForbidden, w.Code, "Only organizer of tournament should schedule it")
+	require.Nil(store.tournament.StartsAt)
+
+	w = do(organizer.Hex(), store.tournament.ID.Hex(), `{"startsAt":"2020-01-01T00:00:00Z"}`)
+	require.Equal(http.StatusBadRequest, w.Code, "Start in the past should be rejected")
+
+	w = do(organizer.Hex(), primitive.NewObjectID().Hex(), body)
+	require.Equal(http.StatusNotFound, w.Code)
+
+	w = do(organizer.Hex(), store.tournament.ID.Hex(), body)
+	require.Equal(http.StatusOK, w.Code)
+	require.True(startsAt.Equal(*store.tournament.StartsAt))
+}
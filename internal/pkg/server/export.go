@@ -0,0 +1,113 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/export"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+type exportStatus struct {
+	*storage.Export
+	// DownloadURL is signed link to archive, it's set once export is ready.
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// WithExporter enables endpoints exporting personal data of users.
+func WithExporter(x *export.Exporter) Option {
+	return func(s *Server) {
+		s.exporter = x
+	}
+}
+
+func (s *Server) registerExportRoutes(router *mux.Router) {
+	router.HandleFunc("/user/{id}/export", s.requestExport).Methods("GET")
+	router.HandleFunc("/user/{id}/export/{exportID}", s.getExport).Methods("GET")
+	router.HandleFunc("/user/{id}/export/{exportID}/archive", s.downloadExport).Methods("GET")
+}
+
+// requestExport returns status of export which is being built or is ready.
+// Export is queued if there is none, archive is built in background.
+func (s *Server) requestExport(w http.ResponseWriter, req *http.Request) {
+	userID, ok := s.selfUserID(w, req, "requestExport")
+	if !ok {
+		return
+	}
+
+	e, err := s.exporter.Request(req.Context(), userID)
+	if err != nil {
+		writeStorageError(w, req, "requestExport", err)
+		return
+	}
+
+	s.writeExport(w, req, "requestExport", e)
+}
+
+func (s *Server) getExport(w http.ResponseWriter, req *http.Request) {
+	userID, ok := s.selfUserID(w, req, "getExport")
+	if !ok {
+		return
+	}
+	exportID := mux.Vars(req)["exportID"]
+	var v validation.Validator
+	v.ObjectID("exportID", exportID)
+	if !validate(w, req, "getExport", &v) {
+		return
+	}
+
+	e, err := s.exporter.GetExport(req.Context(), userID, exportID)
+	if err != nil {
+		writeStorageError(w, req, "getExport", err)
+		return
+	}
+
+	s.writeExport(w, req, "getExport", e)
+}
+
+// downloadExport streams archive. Signed link is the only credential,
+// so it can be opened by browser.
+func (s *Server) downloadExport(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	var v validation.Validator
+	v.ObjectID("id", vars["id"])
+	v.ObjectID("exportID", vars["exportID"])
+	if !validate(w, req, "downloadExport", &v) {
+		return
+	}
+
+	query := req.URL.Query()
+	archive, err := s.exporter.Open(req.Context(), vars["id"], vars["exportID"],
+		query.Get("expires"), query.Get("signature"))
+	if errors.Is(err, export.ErrInvalidLink) {
+		w.WriteHeader(http.StatusForbidden)
+		slog.WarnContext(req.Context(), "request rejected", "handler", "downloadExport", "err", err)
+		return
+	}
+	if err != nil {
+		writeStorageError(w, req, "downloadExport", err)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+vars["exportID"]+`.zip"`)
+	if _, err = io.Copy(w, archive); err != nil {
+		slog.WarnContext(req.Context(), "error writing archive", "handler", "downloadExport", "err", err)
+	}
+}
+
+// writeExport writes export status, pending export is reported as accepted.
+func (s *Server) writeExport(w http.ResponseWriter, req *http.Request, op string, e *storage.Export) {
+	if e.Status == storage.ExportPending {
+		w.Header().Set("Location", "/user/"+e.UserID+"/export/"+e.ID.Hex())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+	}
+	writeJSON(w, req, op, exportStatus{Export: e, DownloadURL: s.exporter.Link(e)})
+}
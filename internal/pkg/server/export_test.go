@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/export"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// exportStore keeps single export of user and its archive.
+type exportStore struct {
+	export.Store
+	export  *storage2.Export
+	archive []byte
+}
+
+func (s *exportStore) RequestExport(_ context.Context, userID string, now time.Time) (*storage2.Export, error) {
+	if s.export == nil {
+		s.export = &storage2.Export{ID: primitive.NewObjectID(), UserID: userID, Status: storage2.ExportPending,
+			CreatedAt: now}
+	}
+	return s.export, nil
+}
+
+func (s *exportStore) GetExport(_ context.Context, userID, id string) (*storage2.Export, error) {
+	if s.export == nil || s.export.UserID != userID || s.export.ID.Hex() != id {
+		return nil, mongo.ErrNoDocuments
+	}
+	return s.export, nil
+}
+
+func (s *exportStore) OpenExportArchive(context.Context, primitive.ObjectID) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.archive)), nil
+}
+
+func TestExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID().Hex()
+	store := &exportStore{archive: []byte("zip")}
+	s := NewServer(storage2.NewMockService(ctrl), WithExporter(export.NewExporter(store, []byte("key"), export.Config{})))
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/user/"+userID+"/export", nil)
+	req = withPrincipal(req, userID, storage2.RolePlayer)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusAccepted, w.Code)
+	require.Equal("/user/"+userID+"/export/"+store.export.ID.Hex(), w.Header().Get("Location"))
+
+	expiresAt := time.Now().Add(time.Hour)
+	store.export.Status, store.export.ExpiresAt = storage2.ExportReady, &expiresAt
+	req = httptest.NewRequest("GET", w.Header().Get("Location"), nil)
+	req = withPrincipal(req, userID, storage2.RolePlayer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	var status exportStatus
+	require.NoError(json.NewDecoder(w.Body).Decode(&status))
+	require.Equal(storage2.ExportReady, status.Status)
+	require.NotEmpty(status.DownloadURL)
+
+	// link is the only credential of download
+	req = httptest.NewRequest("GET", status.DownloadURL, nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.Equal("application/zip", w.Header().Get("Content-Type"))
+	require.Equal("zip", w.Body.String())
+
+	req = httptest.NewRequest("GET", status.DownloadURL+"0", nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusForbidden, w.Code, "Tampered link should be rejected")
+}
+
+func TestExport_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := &exportStore{}
+	s := NewServer(storage2.NewMockService(ctrl), WithExporter(export.NewExporter(store, []byte("key"), export.Config{})))
+
+	req := httptest.NewRequest("GET", "/user/"+primitive.NewObjectID().Hex()+"/export", nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code, "Only user himself or admin should export his data")
+	require.Nil(t, store.export)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	service "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+// gatewayRoutes lists HTTP bindings declared in tournament.proto. They are
+// registered in router one by one, so middlewares see route templates.
+var gatewayRoutes = []struct {
+	method string
+	path   string
+}{
+	{"POST", "/user"},
+	{"GET", "/user/{id}"},
+	{"DELETE", "/user/{id}"},
+	{"POST", "/user/{id}/take"},
+	{"POST", "/user/{id}/fund"},
+	{"PUT", "/user/{id}/role"},
+	{"POST", "/tournament"},
+	{"GET", "/tournament"},
+	{"GET", "/tournament/{id}"},
+	{"POST", "/tournament/{id}/join"},
+	{"POST", "/tournament/{id}/leave"},
+	{"POST", "/tournament/{id}/start"},
+	{"POST", "/tournament/{id}/finish"},
+	{"DELETE", "/tournament/{id}"},
+}
+
+// newGateway creates REST gateway calling svc in process.
+func newGateway(svc v1.TournamentServer) http.Handler {
+	gw := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &strictJSON{
+			JSONPb: runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+			},
+		}),
+		runtime.WithErrorHandler(writeGatewayError),
+	)
+	// RegisterTournamentHandlerServer never fails
+	_ = v1.RegisterTournamentHandlerServer(context.Background(), gw, svc)
+
+	return http.MaxBytesHandler(gw, maxBodySize)
+}
+
+// strictJSON is JSON marshaler which rejects request bodies
+// with unknown fields or with more than one JSON value.
+type strictJSON struct {
+	runtime.JSONPb
+}
+
+func (m *strictJSON) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		dec := json.NewDecoder(r)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if dec.More() {
+			return errors.New("must contain single JSON object")
+		}
+
+		return m.Unmarshal(raw, v)
+	})
+}
+
+// writeGatewayError responds with field errors on invalid argument
+// and with bare status code otherwise, as hand-written handlers did.
+func writeGatewayError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler,
+	w http.ResponseWriter, _ *http.Request, err error) {
+	st := service.Status(err)
+	if st.Code() != codes.InvalidArgument {
+		w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+		return
+	}
+
+	var errs validation.Errors
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, fv := range br.GetFieldViolations() {
+				errs = append(errs, validation.FieldError{Field: fv.GetField(), Message: fv.GetDescription()})
+			}
+		}
+	}
+	if len(errs) == 0 {
+		errs = validation.Errors{{Field: "body", Message: st.Message()}}
+		slog.WarnContext(ctx, "can't decode request body", "err", err)
+	}
+
+	writeValidationError(w, errs)
+}
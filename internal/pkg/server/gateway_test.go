@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+type winnerUserID struct {
+	ID string `json:"winnerUserID"`
+}
+
+type userName struct {
+	Name string `json:"name"`
+}
+
+type userPoints struct {
+	Points float64 `json:"points"`
+}
+
+type tournament struct {
+	Name    string  `json:"name"`
+	Deposit float64 `json:"deposit"`
+}
+
+type tournamentID struct {
+	ID string `json:"id"`
+}
+
+func TestGateway_StartTournament(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	tournamentID := primitive.NewObjectID().Hex()
+	organizerID := primitive.NewObjectID()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: organizerID}, nil)
+	mock.EXPECT().SetTournamentStatus(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(storage2.StatusStarted)).
+		Times(1).Return(nil)
+
+	expectedURLPath := fmt.Sprintf("/tournament/%s/start", tournamentID)
+	req := httptest.NewRequest("POST", expectedURLPath, nil)
+	req = withPrincipal(req, organizerID.Hex(), storage2.RoleOrganizer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestGateway_Trailing_Data(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().AddUser(gomock.Any(), gomock.Any()).Times(0)
+
+	req := httptest.NewRequest("POST", "/user", bytes.NewBufferString(`{"name":"Gennadiy"}{"name":"Vasiliy"}`))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require := require.New(t)
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+
+	var actual validationErrors
+	err := json.NewDecoder(w.Result().Body).Decode(&actual)
+	require.NoError(err)
+	require.Len(actual.Errors, 1)
+	require.Equal("body", actual.Errors[0].Field)
+}
+
+func TestGateway_Field_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().AddTournament(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	enc, err := json.Marshal(tournament{Name: "", Deposit: -1})
+	require := require.New(t)
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/tournament", bytes.NewBuffer(enc))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+
+	var actual validationErrors
+	err = json.NewDecoder(w.Result().Body).Decode(&actual)
+	require.NoError(err)
+	require.Equal([]string{"name", "deposit"}, fields(actual.Errors), "The two fields should be the same")
+}
+
+func fields(errs validation.Errors) []string {
+	names := make([]string, 0, len(errs))
+	for _, e := range errs {
+		names = append(names, e.Field)
+	}
+
+	return names
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestHealth_Probes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	h := health.New(health.Check{Name: "mongo", Func: func(context.Context) error {
+		return errors.New("no reachable servers")
+	}})
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRead: {Rate: 0.001, Burst: 1},
+	})
+	s := NewServer(mock, WithHealth(h), WithRateLimiter(limiter))
+	require := require.New(t)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		require.Equal(http.StatusOK, w.Result().StatusCode, "Probes should not be rate limited")
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		require.Equal(http.StatusServiceUnavailable, w.Result().StatusCode, "The two http codes should be the same")
+	}
+}
@@ -0,0 +1,413 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	service "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+const (
+	// liveWriteTimeout limits single write to live feed, so connection
+	// of client which stopped reading is closed instead of hanging.
+	liveWriteTimeout = 10 * time.Second
+
+	// maxLiveTournaments limits tournaments watched over one WebSocket.
+	maxLiveTournaments = 100
+
+	defaultHeartbeat = 15 * time.Second
+)
+
+// Types of live feed messages which are not hub events.
+const (
+	liveSnapshot     = "snapshot"
+	liveHeartbeat    = "heartbeat"
+	liveLagged       = "lagged"
+	liveUnsubscribed = "unsubscribed"
+	liveError        = "error"
+)
+
+// liveEvent is message of SSE and WebSocket feeds.
+type liveEvent struct {
+	Position     string              `json:"position"`
+	Type         string              `json:"type"`
+	TournamentID string              `json:"tournamentId"`
+	UserID       string              `json:"userId"`
+	Prize        float64             `json:"prize"`
+	Status       string              `json:"status"`
+	Balance      float64             `json:"balance"`
+	Time         time.Time           `json:"time"`
+	Tournament   *storage.Tournament `json:"tournament,omitempty"`
+	Message      string              `json:"message,omitempty"`
+}
+
+// liveCommand is message sent by WebSocket client. It subscribes to or
+// unsubscribes from either tournament or balance of authenticated user.
+type liveCommand struct {
+	Action       string `json:"action"`
+	TournamentID string `json:"tournamentId"`
+	Balance      bool   `json:"balance"`
+}
+
+// WithEvents exposes events of hub as Server-Sent Events per tournament
+// and as WebSocket feed of several tournaments and own balance.
+// Idle feeds get heartbeat message every heartbeat interval.
+func WithEvents(hub *events.Hub, heartbeat time.Duration) Option {
+	return func(s *Server) {
+		s.hub = hub
+		s.heartbeat = heartbeat
+		if s.heartbeat <= 0 {
+			s.heartbeat = defaultHeartbeat
+		}
+	}
+}
+
+func (s *Server) registerLiveRoutes(router *mux.Router) {
+	router.HandleFunc("/tournament/{id}/events", s.tournamentEvents).Methods("GET")
+	router.HandleFunc("/events/ws", s.liveSocket).Methods("GET")
+}
+
+// tournamentEvents streams tournament changes as Server-Sent Events. Like
+// WatchTournament, stream starts with snapshot unless it is resumed from
+// Last-Event-ID still kept by hub. Clients which fall behind get lagged
+// event and should reconnect.
+func (s *Server) tournamentEvents(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := s.feedContext(req.Context())
+	defer cancel()
+
+	id := mux.Vars(req)["id"]
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("lastEventId")
+	}
+
+	var v validation.Validator
+	v.ObjectID("id", id)
+	from, err := events.ParsePosition(lastEventID)
+	if err != nil {
+		v.Add("Last-Event-ID", "is malformed")
+	}
+	if !validate(w, req, "tournamentEvents", &v) {
+		return
+	}
+
+	// subscribe before reading snapshot, so no change is lost in between
+	sub := s.hub.Subscribe(from, events.ForTournament(id))
+	defer sub.Close()
+
+	var snapshot *liveEvent
+	if !sub.Resumed {
+		tournament, err := s.db.GetTournament(ctx, id)
+		if err != nil {
+			w.WriteHeader(runtime.HTTPStatusFromCode(service.Status(err).Code()))
+			slog.WarnContext(ctx, "request failed", "handler", "tournamentEvents", "err", err)
+			return
+		}
+		snapshot = &liveEvent{
+			Position:     sub.Position.String(),
+			Type:         liveSnapshot,
+			TournamentID: id,
+			Tournament:   tournament,
+			Time:         time.Now(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	feed := &sseFeed{w: w, rc: http.NewResponseController(w)}
+
+	if snapshot != nil {
+		if err = feed.send(*snapshot); err != nil {
+			slog.WarnContext(ctx, "error writing event", "handler", "tournamentEvents", "err", err)
+			return
+		}
+	}
+
+	err = s.pump(ctx, sub, feed.send, func(e events.Event) bool {
+		return e.Type != events.TypeCanceled
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.WarnContext(ctx, "event stream closed", "handler", "tournamentEvents", "err", err)
+	}
+}
+
+// feedContext returns ctx which is also canceled by CloseFeeds.
+func (s *Server) feedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.feeds, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// sseFeed writes messages in text/event-stream format.
+type sseFeed struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (f *sseFeed) send(e liveEvent) error {
+	// recorders used in tests don't support deadlines
+	_ = f.rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+
+	var err error
+	if e.Type == liveHeartbeat {
+		_, err = fmt.Fprint(f.w, ": heartbeat\n\n")
+	} else {
+		var data []byte
+		if data, err = json.Marshal(e); err != nil {
+			return errors.Wrap(err, "encode event")
+		}
+		if e.Position != "" {
+			_, err = fmt.Fprintf(f.w, "id: %s\n", e.Position)
+		}
+		if err == nil {
+			_, err = fmt.Fprintf(f.w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// pump sends events of sub until ctx is done, sending fails or more
+// returns false. Heartbeat is sent when there were no events for heartbeat
+// interval. Lagged subscriber is told so before returning events.ErrLagged.
+func (s *Server) pump(ctx context.Context, sub *events.Subscription, send func(liveEvent) error,
+	more func(events.Event) bool) error {
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, s.heartbeat)
+		e, err := sub.Next(waitCtx)
+		cancel()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			if err = send(liveEvent{Type: liveHeartbeat, Time: time.Now()}); err != nil {
+				return err
+			}
+			continue
+		case errors.Is(err, events.ErrLagged):
+			_ = send(liveEvent{Type: liveLagged, Message: "client is too slow, resume from last received position",
+				Time: time.Now()})
+			return err
+		case err != nil:
+			return err
+		}
+
+		if err = send(newLiveEvent(e)); err != nil {
+			return err
+		}
+		if !more(e) {
+			return nil
+		}
+	}
+}
+
+func newLiveEvent(e events.Event) liveEvent {
+	return liveEvent{
+		Position:     e.Position.String(),
+		Type:         string(e.Type),
+		TournamentID: e.TournamentID,
+		UserID:       e.UserID,
+		Prize:        e.Prize,
+		Status:       string(e.Status),
+		Balance:      e.Balance,
+		Time:         e.Time,
+	}
+}
+
+// liveSocket upgrades request to WebSocket feed. Browsers can't set headers
+// on WebSocket requests, so access token may be passed in access_token query
+// parameter. It is needed only for balance subscription.
+func (s *Server) liveSocket(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if _, err := auth.FromContext(ctx); err != nil && s.authn != nil {
+		if token := req.URL.Query().Get("access_token"); token != "" {
+			p, err := s.authn.Authenticate(ctx, token)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				slog.WarnContext(ctx, "authentication failed", "handler", "liveSocket", "err", err)
+				return
+			}
+			req = req.WithContext(auth.NewContext(ctx, p))
+		}
+	}
+
+	// clients authenticate with bearer tokens rather than cookies,
+	// so origin of cross-site connections doesn't need checking
+	websocket.Server{Handler: s.serveLiveSocket}.ServeHTTP(w, req)
+}
+
+func (s *Server) serveLiveSocket(ws *websocket.Conn) {
+	ctx, cancel := s.feedContext(ws.Request().Context())
+	defer cancel()
+
+	topics := &liveTopics{tournaments: make(map[string]bool)}
+	sub := s.hub.Subscribe(events.Position{}, topics.match)
+	defer sub.Close()
+
+	send := func(e liveEvent) error {
+		_ = ws.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		return websocket.JSON.Send(ws, e)
+	}
+
+	go func() {
+		// connection is closed by client or after failed write
+		defer cancel()
+		for {
+			var cmd liveCommand
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			if err := send(s.handleLiveCommand(ctx, topics, cmd)); err != nil {
+				return
+			}
+		}
+	}()
+
+	err := s.pump(ctx, sub, send, func(events.Event) bool { return true })
+	if err != nil && ctx.Err() == nil {
+		slog.WarnContext(ctx, "event stream closed", "handler", "liveSocket", "err", err)
+	}
+	_ = ws.Close()
+}
+
+// handleLiveCommand changes topics and returns reply to client.
+func (s *Server) handleLiveCommand(ctx context.Context, topics *liveTopics, cmd liveCommand) liveEvent {
+	reply := liveEvent{TournamentID: cmd.TournamentID, Time: time.Now()}
+
+	var v validation.Validator
+	if cmd.Action != "subscribe" && cmd.Action != "unsubscribe" {
+		v.Add("action", "must be one of subscribe, unsubscribe")
+	}
+	if cmd.Balance == (cmd.TournamentID != "") {
+		v.Add("tournamentId", "must be provided unless balance is set")
+	}
+	if cmd.TournamentID != "" {
+		v.ObjectID("tournamentId", cmd.TournamentID)
+	}
+	if err := v.Err(); err != nil {
+		reply.Type, reply.Message = liveError, err.Error()
+		return reply
+	}
+
+	if cmd.Balance {
+		return s.handleBalanceCommand(ctx, topics, cmd.Action == "subscribe", reply)
+	}
+
+	if cmd.Action == "unsubscribe" {
+		topics.setTournament(cmd.TournamentID, false)
+		reply.Type = liveUnsubscribed
+		return reply
+	}
+
+	if !topics.setTournament(cmd.TournamentID, true) {
+		reply.Type = liveError
+		reply.Message = fmt.Sprintf("can't watch more than %d tournaments", maxLiveTournaments)
+		return reply
+	}
+
+	tournament, err := s.db.GetTournament(ctx, cmd.TournamentID)
+	if err != nil {
+		topics.setTournament(cmd.TournamentID, false)
+		slog.WarnContext(ctx, "request failed", "handler", "liveSocket", "err", err)
+		reply.Type, reply.Message = liveError, service.Status(err).Message()
+		return reply
+	}
+
+	reply.Type, reply.Tournament = liveSnapshot, tournament
+	return reply
+}
+
+func (s *Server) handleBalanceCommand(ctx context.Context, topics *liveTopics, subscribe bool,
+	reply liveEvent) liveEvent {
+	if !subscribe {
+		topics.setUser("")
+		reply.Type = liveUnsubscribed
+		return reply
+	}
+
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		reply.Type, reply.Message = liveError, err.Error()
+		return reply
+	}
+
+	topics.setUser(p.UserID)
+	user, err := s.db.GetUser(ctx, p.UserID)
+	if err != nil {
+		topics.setUser("")
+		slog.WarnContext(ctx, "request failed", "handler", "liveSocket", "err", err)
+		reply.Type, reply.Message = liveError, service.Status(err).Message()
+		return reply
+	}
+
+	reply.Type, reply.UserID, reply.Balance = liveSnapshot, p.UserID, user.Balance
+	return reply
+}
+
+// liveTopics is set of tournaments and user balance watched over
+// WebSocket. It is changed by client while hub matches events against it.
+type liveTopics struct {
+	mu          sync.RWMutex
+	tournaments map[string]bool
+	userID      string
+}
+
+func (t *liveTopics) match(e events.Event) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if e.Type == events.TypeBalanceChanged {
+		return t.userID != "" && e.UserID == t.userID
+	}
+
+	return t.tournaments[e.TournamentID]
+}
+
+// setTournament adds or removes tournament. It returns false if limit is reached.
+func (t *liveTopics) setTournament(id string, watch bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !watch {
+		delete(t.tournaments, id)
+		return true
+	}
+	if !t.tournaments[id] && len(t.tournaments) >= maxLiveTournaments {
+		return false
+	}
+
+	t.tournaments[id] = true
+	return true
+}
+
+func (t *liveTopics) setUser(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.userID = id
+}
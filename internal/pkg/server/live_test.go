@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/websocket"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+type tokenAuthenticator map[string]*auth.Principal
+
+func (a tokenAuthenticator) Authenticate(_ context.Context, token string) (*auth.Principal, error) {
+	if p, ok := a[token]; ok {
+		return p, nil
+	}
+
+	return nil, errors.New("unknown token")
+}
+
+// readSSE reads next message of event stream skipping heartbeats.
+func readSSE(t *testing.T, r *bufio.Reader) (id string, e liveEvent) {
+	for {
+		var data string
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				break
+			}
+			if v, ok := strings.CutPrefix(line, "id: "); ok {
+				id = v
+			}
+			if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+		}
+		if data == "" {
+			continue
+		}
+
+		require.NoError(t, json.Unmarshal([]byte(data), &e))
+		return id, e
+	}
+}
+
+func TestTournamentEvents_Resume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID.Hex())).Times(1).
+		Return(&storage2.Tournament{ID: tournamentID, Name: "Tournament_1", Deposit: 100}, nil)
+
+	hub := events.NewHub(16, 16)
+	srv := httptest.NewServer(NewServer(mock, WithEvents(hub, 10*time.Millisecond)))
+	defer srv.Close()
+	require := require.New(t)
+
+	resp, err := http.Get(srv.URL + "/tournament/" + tournamentID.Hex() + "/events")
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	require.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	_, snapshot := readSSE(t, r)
+	require.Equal(liveSnapshot, snapshot.Type)
+	require.Equal("Tournament_1", snapshot.Tournament.Name)
+
+	hub.Publish(events.Event{Type: events.TypePlayerJoined, TournamentID: tournamentID.Hex(), UserID: "u1"},
+		events.Event{Type: events.TypePlayerJoined, TournamentID: primitive.NewObjectID().Hex()})
+	id, joined := readSSE(t, r)
+	require.Equal(string(events.TypePlayerJoined), joined.Type)
+	require.Equal("u1", joined.UserID)
+	require.NoError(resp.Body.Close())
+
+	// events published while client was away are replayed without snapshot
+	hub.Publish(events.Event{Type: events.TypeCanceled, TournamentID: tournamentID.Hex()})
+	req, err := http.NewRequest("GET", srv.URL+"/tournament/"+tournamentID.Hex()+"/events", nil)
+	require.NoError(err)
+	req.Header.Set("Last-Event-ID", id)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer resp.Body.Close()
+
+	_, canceled := readSSE(t, bufio.NewReader(resp.Body))
+	require.Equal(string(events.TypeCanceled), canceled.Type)
+}
+
+func TestTournamentEvents_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID().Hex()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).
+		Return(nil, errors.Wrap(mongo.ErrNoDocuments, "find doc"))
+
+	s := NewServer(mock, WithEvents(events.NewHub(16, 16), time.Second))
+	require := require.New(t)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/tournament/not-an-id/events", nil))
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode)
+
+	req := httptest.NewRequest("GET", "/tournament/"+tournamentID+"/events", nil)
+	req.Header.Set("Last-Event-ID", "garbage")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/tournament/"+tournamentID+"/events", nil))
+	require.Equal(http.StatusNotFound, w.Result().StatusCode)
+}
+
+func receive(t *testing.T, ws *websocket.Conn) liveEvent {
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second)))
+	for {
+		var e liveEvent
+		require.NoError(t, websocket.JSON.Receive(ws, &e))
+		if e.Type != liveHeartbeat {
+			return e
+		}
+	}
+}
+
+func TestLiveSocket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID()
+	userID := primitive.NewObjectID().Hex()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID.Hex())).Times(1).
+		Return(&storage2.Tournament{ID: tournamentID}, nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(1).Return(&storage2.User{Balance: 30}, nil)
+
+	hub := events.NewHub(16, 16)
+	authn := tokenAuthenticator{"token": {UserID: userID, Role: storage2.RolePlayer}}
+	srv := httptest.NewServer(NewServer(mock, WithAuthenticator(authn), WithEvents(hub, 10*time.Millisecond)))
+	defer srv.Close()
+	require := require.New(t)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events/ws?access_token=token"
+	ws, err := websocket.Dial(url, "", srv.URL)
+	require.NoError(err)
+	defer ws.Close()
+
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "subscribe", TournamentID: tournamentID.Hex()}))
+	e := receive(t, ws)
+	require.Equal(liveSnapshot, e.Type)
+	require.Equal(tournamentID, e.Tournament.ID)
+
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "subscribe", Balance: true}))
+	e = receive(t, ws)
+	require.Equal(liveSnapshot, e.Type)
+	require.Equal(30.0, e.Balance)
+
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "watch"}))
+	require.Equal(liveError, receive(t, ws).Type)
+
+	hub.Publish(
+		events.Event{Type: events.TypeBalanceChanged, UserID: primitive.NewObjectID().Hex(), Balance: 1},
+		events.Event{Type: events.TypePrizeChanged, TournamentID: tournamentID.Hex(), Prize: 200},
+		events.Event{Type: events.TypeBalanceChanged, UserID: userID, Balance: 50},
+	)
+	require.Equal(200.0, receive(t, ws).Prize)
+	e = receive(t, ws)
+	require.Equal(string(events.TypeBalanceChanged), e.Type)
+	require.Equal(50.0, e.Balance, "Balance of other users should not be delivered")
+
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "unsubscribe", TournamentID: tournamentID.Hex()}))
+	require.Equal(liveUnsubscribed, receive(t, ws).Type)
+	hub.Publish(
+		events.Event{Type: events.TypePrizeChanged, TournamentID: tournamentID.Hex()},
+		events.Event{Type: events.TypeBalanceChanged, UserID: userID, Balance: 60},
+	)
+	require.Equal(60.0, receive(t, ws).Balance, "Unsubscribed tournament should not be delivered")
+}
+
+func TestLiveSocket_Lagged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournamentID := primitive.NewObjectID()
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID.Hex())).Times(1).
+		Return(&storage2.Tournament{ID: tournamentID}, nil)
+
+	hub := events.NewHub(16, 1)
+	srv := httptest.NewServer(NewServer(mock, WithEvents(hub, time.Second)))
+	defer srv.Close()
+	require := require.New(t)
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/events/ws", "", srv.URL)
+	require.NoError(err)
+	defer ws.Close()
+
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "subscribe", Balance: true}))
+	require.Equal(liveError, receive(t, ws).Type, "Balance should require authentication")
+	require.NoError(websocket.JSON.Send(ws, liveCommand{Action: "subscribe", TournamentID: tournamentID.Hex()}))
+	require.Equal(liveSnapshot, receive(t, ws).Type)
+
+	// publisher is never blocked, subscriber overflowing its buffer is dropped
+	burst := make([]events.Event, 1000)
+	for i := range burst {
+		burst[i] = events.Event{Type: events.TypePrizeChanged, TournamentID: tournamentID.Hex()}
+	}
+	hub.Publish(burst...)
+	for {
+		e := receive(t, ws)
+		if e.Type == liveLagged {
+			break
+		}
+		require.Equal(string(events.TypePrizeChanged), e.Type)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/metrics"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestMetrics_Endpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	userID := primitive.NewObjectID().Hex()
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(1).Return(&storage2.User{}, nil)
+
+	s := NewServer(mock, WithMetrics(metrics.New()))
+	require := require.New(t)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/user/"+userID, nil))
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+	require.True(strings.Contains(w.Body.String(),
+		`sts_http_requests_total{code="200",method="GET",route="/user/{id}"} 1`), w.Body.String())
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/notification"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 200
+)
+
+type notificationList struct {
+	Unread        int64                  `json:"unread"`
+	Notifications []storage.Notification `json:"notifications"`
+}
+
+type notificationsMarked struct {
+	Marked int64 `json:"marked"`
+}
+
+// WithNotifications enables endpoints reading and marking notifications of user inbox.
+func WithNotifications(inbox *notification.Inbox) Option {
+	return func(s *Server) {
+		s.inbox = inbox
+	}
+}
+
+func (s *Server) registerNotificationRoutes(router *mux.Router) {
+	router.HandleFunc("/user/{id}/notifications", s.listNotifications).Methods("GET")
+	router.HandleFunc("/user/{id}/notifications/read", s.markAllNotificationsRead).Methods("POST")
+	router.HandleFunc("/user/{id}/notifications/{notificationID}/read", s.markNotificationRead).Methods("POST")
+}
+
+func (s *Server) listNotifications(w http.ResponseWriter, req *http.Request) {
+	userID := mux.Vars(req)["id"]
+	var v validation.Validator
+	v.ObjectID("id", userID)
+	unread := false
+	if raw := req.URL.Query().Get("unread"); raw != "" {
+		var err error
+		if unread, err = strconv.ParseBool(raw); err != nil {
+			v.Add("unread", "must be true or false")
+		}
+	}
+	limit := queryLimit(&v, req, defaultNotificationsLimit, maxNotificationsLimit)
+	if !validate(w, req, "listNotifications", &v) {
+		return
+	}
+
+	if _, err := auth.RequireSelf(req.Context(), userID); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "listNotifications", "err", err)
+		return
+	}
+
+	notifications, err := s.inbox.ListNotifications(req.Context(), userID, unread, limit)
+	if err != nil {
+		writeStorageError(w, req, "listNotifications", err)
+		return
+	}
+	count, err := s.inbox.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		writeStorageError(w, req, "listNotifications", err)
+		return
+	}
+
+	writeJSON(w, req, "listNotifications", notificationList{Unread: count, Notifications: notifications})
+}
+
+func (s *Server) markNotificationRead(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	userID, notificationID := vars["id"], vars["notificationID"]
+	var v validation.Validator
+	v.ObjectID("id", userID)
+	v.ObjectID("notificationID", notificationID)
+	if !validate(w, req, "markNotificationRead", &v) {
+		return
+	}
+
+	if _, err := auth.RequireSelf(req.Context(), userID); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "markNotificationRead", "err", err)
+		return
+	}
+
+	if err := s.inbox.MarkNotificationRead(req.Context(), userID, notificationID); err != nil {
+		writeStorageError(w, req, "markNotificationRead", err)
+		return
+	}
+}
+
+func (s *Server) markAllNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	userID := mux.Vars(req)["id"]
+	var v validation.Validator
+	v.ObjectID("id", userID)
+	if !validate(w, req, "markAllNotificationsRead", &v) {
+		return
+	}
+
+	if _, err := auth.RequireSelf(req.Context(), userID); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "markAllNotificationsRead", "err", err)
+		return
+	}
+
+	marked, err := s.inbox.MarkAllNotificationsRead(req.Context(), userID)
+	if err != nil {
+		writeStorageError(w, req, "markAllNotificationsRead", err)
+		return
+	}
+
+	writeJSON(w, req, "markAllNotificationsRead", notificationsMarked{Marked: marked})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/notification"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// notificationStore keeps notifications of users in memory.
+type notificationStore struct {
+	notification.Store
+	notifications []storage2.Notification
+}
+
+func (s *notificationStore) ListNotifications(_ context.Context, userID string, unread bool,
+	limit int64) ([]storage2.Notification, error) {
+	list := []storage2.Notification{}
+	for _, n := range s.notifications {
+		if n.UserID == userID && (!unread || n.ReadAt == nil) && int64(len(list)) < limit {
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
+
+func (s *notificationStore) CountUnreadNotifications(ctx context.Context, userID string) (int64, error) {
+	unread, _ := s.ListNotifications(ctx, userID, true, 1000)
+	return int64(len(unread)), nil
+}
+
+func (s *notificationStore) MarkNotificationRead(_ context.Context, userID, id string) error {
+	for i, n := range s.notifications {
+		if n.UserID == userID && n.ID.Hex() == id {
+			now := time.Now()
+			s.notifications[i].ReadAt = &now
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (s *notificationStore) MarkAllNotificationsRead(_ context.Context, userID string) (int64, error) {
+	var marked int64
+	for i, n := range s.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			now := time.Now()
+			s.notifications[i].ReadAt = &now
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func TestNotifications(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID().Hex()
+	store := &notificationStore{notifications: []storage2.Notification{
+		{ID: primitive.NewObjectID(), UserID: userID, Kind: storage2.NotificationTournamentWon, Amount: 50},
+		{ID: primitive.NewObjectID(), UserID: userID, Kind: storage2.NotificationTournamentStarted},
+		{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID().Hex(), Kind: storage2.NotificationBalanceFunded},
+	}}
+	s := NewServer(storage2.NewMockService(ctrl), WithNotifications(notification.NewInbox(store, notification.Config{})))
+	require := require.New(t)
+
+	list := func(query string) notificationList {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/user/%s/notifications%s", userID, query), nil)
+		req = withPrincipal(req, userID, storage2.RolePlayer)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		require.Equal(http.StatusOK, w.Code)
+
+		var resp notificationList
+		require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	resp := list("?limit=1")
+	require.Equal(int64(2), resp.Unread)
+	require.Len(resp.Notifications, 1)
+	require.Equal(storage2.NotificationTournamentWon, resp.Notifications[0].Kind)
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/user/%s/notifications/%s/read",
+		userID, store.notifications[0].ID.Hex()), nil)
+	req = withPrincipal(req, userID, storage2.RolePlayer)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+
+	resp = list("?unread=true")
+	require.Equal(int64(1), resp.Unread)
+	require.Len(resp.Notifications, 1)
+	require.Equal(storage2.NotificationTournamentStarted, resp.Notifications[0].Kind)
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/user/%s/notifications/read", userID), nil)
+	req = withPrincipal(req, userID, storage2.RolePlayer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.JSONEq(`{"marked":1}`, w.Body.String())
+	require.Zero(list("").Unread)
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/user/%s/notifications/%s/read",
+		userID, store.notifications[2].ID.Hex()), nil)
+	req = withPrincipal(req, userID, storage2.RolePlayer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusNotFound, w.Code, "Notification of other user should not be found")
+}
+
+func TestNotifications_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := NewServer(storage2.NewMockService(ctrl),
+		WithNotifications(notification.NewInbox(&notificationStore{}, notification.Config{})))
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/user/%s/notifications", primitive.NewObjectID().Hex()), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusForbidden, w.Code, "Notifications of other user should not be read")
+
+	req = httptest.NewRequest("GET", "/user/bad/notifications?unread=maybe&limit=1000", nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code)
+
+	var resp validationErrors
+	require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(resp.Errors, 3)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+)
+
+// routeClasses assigns rate limit class to routes which are neither plain reads nor plain writes.
+var routeClasses = map[string]ratelimit.Class{
+	"POST /user/{id}/take":                               ratelimit.ClassMoney,
+	"POST /user/{id}/fund":                               ratelimit.ClassMoney,
+	"POST /tournament/{id}/join":                         ratelimit.ClassMoney,
+	"POST /tournament/{id}/leave":                        ratelimit.ClassMoney,
+	"POST /tournament/{id}/finish":                       ratelimit.ClassMoney,
+	"DELETE /user/{id}":                                  ratelimit.ClassAdmin,
+	"PUT /user/{id}/role":                                ratelimit.ClassAdmin,
+	"POST /webhooks":                                     ratelimit.ClassAdmin,
+	"DELETE /webhooks/{id}":                              ratelimit.ClassAdmin,
+	"POST /webhooks/{id}/deliveries/{deliveryID}/replay": ratelimit.ClassAdmin,
+	"GET /audit/export":                                  ratelimit.ClassAdmin,
+	"GET /user/{id}/export":                              ratelimit.ClassAdmin,
+	"GET /healthz":                                       ratelimit.ClassProbe,
+	"GET /readyz":                                        ratelimit.ClassProbe,
+	"GET /metrics":                                       ratelimit.ClassProbe,
+}
+
+// WithRateLimiter limits requests per principal and per client IP.
+func WithRateLimiter(l *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.limiter = l
+	}
+}
+
+func classifyRoute(req *http.Request) ratelimit.Class {
+	if route := mux.CurrentRoute(req); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			if class, ok := routeClasses[req.Method+" "+tpl]; ok {
+				return class
+			}
+		}
+	}
+
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return ratelimit.ClassRead
+	}
+
+	return ratelimit.ClassWrite
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestRateLimit_Money_Route(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	userID := primitive.NewObjectID().Hex()
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(2).Return(&storage2.User{}, nil)
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassMoney: {Rate: 0.001, Burst: 1},
+	})
+	s := NewServer(mock, WithRateLimiter(limiter))
+	require := require.New(t)
+
+	takeURLPath := fmt.Sprintf("/user/%s/take", userID)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", takeURLPath, bytes.NewBufferString(`{"points":1}`)))
+	require.Equal(http.StatusUnauthorized, w.Result().StatusCode, "The two http codes should be the same")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", takeURLPath, bytes.NewBufferString(`{"points":1}`)))
+	require.Equal(http.StatusTooManyRequests, w.Result().StatusCode, "The two http codes should be the same")
+	require.NotEmpty(w.Result().Header.Get("Retry-After"))
+
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/user/"+userID, nil))
+		require.Equal(http.StatusOK, w.Result().StatusCode, "Reads should not share money limit")
+	}
+}
@@ -1,295 +1,170 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
-
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/audit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/export"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/health"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/logging"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/mail"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/metrics"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/notification"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+	service "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/service/v1"
 	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tracing"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/webhook"
 )
 
 type Server struct {
 	http.Handler
-	service storage.Service
-}
+	db       storage.Service
+	authn    auth.Authenticator
+	certs    auth.CertPrincipals
+	sessions *auth.Sessions
+	limiter  *ratelimit.Limiter
+	metrics  *metrics.Metrics
+	health   *health.Health
+	webhooks *webhook.Webhooks
+	inbox    *notification.Inbox
+	mailer   *mail.Mailer
+	audit    audit.Store
+	exporter *export.Exporter
 
-type userID struct {
-	ID string `json:"userID"`
+	hub       *events.Hub
+	heartbeat time.Duration
+	feeds     context.Context
+	closeFeed context.CancelFunc
 }
 
-type winnerUserID struct {
-	ID string `json:"winnerUserID"`
-}
+// Option configures optional Server dependencies.
+type Option func(*Server)
 
-type userName struct {
-	Name string `json:"name"`
+// WithAuthenticator makes server resolve principals from bearer tokens.
+func WithAuthenticator(authn auth.Authenticator) Option {
+	return func(s *Server) {
+		s.authn = authn
+	}
 }
 
-type userPoints struct {
-	Points float64 `json:"points"`
+// WithCertPrincipals authenticates callers presenting verified client
+// certificates with principals mapped from certificate common names.
+func WithCertPrincipals(certs auth.CertPrincipals) Option {
+	return func(s *Server) {
+		s.certs = certs
+	}
 }
 
-type tournament struct {
-	Name    string  `json:"name"`
-	Deposit float64 `json:"deposit"`
+// WithMetrics exposes /metrics endpoint and measures every request.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
 }
 
-type tournamentID struct {
-	ID string `json:"id"`
+// WithHealth exposes /healthz liveness and /readyz readiness probes.
+func WithHealth(h *health.Health) Option {
+	return func(s *Server) {
+		s.health = h
+	}
 }
 
-// NewServer initializes router and entrypoints
-func NewServer(db storage.Service) *Server {
+// NewServer initializes router and entrypoints. User and tournament
+// endpoints are served by REST gateway generated from tournament.proto.
+func NewServer(db storage.Service, opts ...Option) *Server {
 	router := mux.NewRouter()
 
 	s := Server{
-		service: db,
-		Handler: router,
+		Handler: logging.Middleware(router),
+		db:      db,
 	}
-	router.HandleFunc("/user", s.createNewUser).Methods("POST")
-	router.HandleFunc("/user/{id}", s.getUserInfo).Methods("GET")
-	router.HandleFunc("/user/{id}", s.removeUser).Methods("DELETE")
-	router.HandleFunc("/user/{id}/take", s.takeUserBonusPoints).Methods("POST")
-	router.HandleFunc("/user/{id}/fund", s.addUserBonusPoints).Methods("POST")
-
-	router.HandleFunc("/tournament", s.createNewTournament).Methods("POST")
-	router.HandleFunc("/tournament/{id}", s.getTournamentInfo).Methods("GET")
-	router.HandleFunc("/tournament/{id}/join", s.joinTournament).Methods("POST")
-	router.HandleFunc("/tournament/{id}/finish", s.finishTournament).Methods("POST")
-	router.HandleFunc("/tournament/{id}", s.cancelTournament).Methods("DELETE")
-
-	return &s
-}
-
-func (s *Server) createNewUser(w http.ResponseWriter, req *http.Request) {
-	var user userName
-	err := json.NewDecoder(req.Body).Decode(&user)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("createNewUser: can't decode request body: %v", err)
-		return
+	s.feeds, s.closeFeed = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(&s)
 	}
-
-	usrID, err := s.service.AddUser(req.Context(), user.Name)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("error createNewUser: %v", err)
-		return
+	router.Use(otelmux.Middleware(tracing.DefaultServiceName))
+	if s.metrics != nil {
+		router.Use(s.metrics.Middleware)
+		router.Handle("/metrics", s.metrics.Handler()).Methods("GET")
 	}
-
-	err = json.NewEncoder(w).Encode(userID{
-		ID: usrID,
-	})
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("createNewUser: error encoding json: %v", err)
-		return
+	if s.health != nil {
+		router.Handle("/healthz", health.LivenessHandler()).Methods("GET")
+		router.Handle("/readyz", s.health.ReadinessHandler()).Methods("GET")
 	}
-}
-
-func (s *Server) getUserInfo(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	userID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("getUserInfo: user id is not provided")
-		return
+	if len(s.certs) > 0 {
+		router.Use(auth.CertMiddleware(s.certs))
 	}
-
-	userData, err := s.service.GetUser(req.Context(), userID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("getUserInfo: %v", err)
-		return
+	if s.authn != nil {
+		router.Use(auth.Middleware(s.authn))
 	}
-	enc := json.NewEncoder(w)
-	if err = enc.Encode(&userData); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("error getting user info: error encoding json: %v\n", err)
-		return
+	if s.limiter != nil {
+		router.Use(ratelimit.Middleware(s.limiter, classifyRoute))
 	}
-}
-
-func (s *Server) removeUser(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	userID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("removeUser: user id is not provided")
-		return
+	if s.audit != nil {
+		router.Use(audit.Middleware)
 	}
-	err := s.service.DeleteUser(req.Context(), userID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("removeUser: %v", err)
-		return
+	if s.sessions != nil {
+		s.registerAccountRoutes(router)
 	}
-}
-
-func (s *Server) takeUserBonusPoints(w http.ResponseWriter, req *http.Request) {
-	var points userPoints
-	err := json.NewDecoder(req.Body).Decode(&points)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("takeUserBonusPoints: can't decode request body: %v", err)
-		return
+	if s.hub != nil {
+		s.registerLiveRoutes(router)
 	}
-
-	vars := mux.Vars(req)
-	userID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("takeUserBonusPoints: user id is not provided")
-		return
+	if s.webhooks != nil {
+		s.registerWebhookRoutes(router)
 	}
-
-	err = s.service.TakeUserBalance(req.Context(), userID, points.Points)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("takeUserBonusPoints: %v", err)
-		return
+	if s.inbox != nil {
+		s.registerNotificationRoutes(router)
 	}
-}
-
-func (s *Server) addUserBonusPoints(w http.ResponseWriter, req *http.Request) {
-	var points userPoints
-	err := json.NewDecoder(req.Body).Decode(&points)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("addUserBonusPoints: can't decode request body: %v", err)
-		return
+	if s.mailer != nil {
+		s.registerEmailRoutes(router)
 	}
-
-	vars := mux.Vars(req)
-	userID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("addUserBonusPoints: user id is not provided")
-		return
+	if s.audit != nil {
+		s.registerAuditRoutes(router)
 	}
-
-	err = s.service.FundUserBalance(req.Context(), userID, points.Points)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("addUserBonusPoints: %v", err)
-		return
-	}
-}
-
-func (s *Server) createNewTournament(w http.ResponseWriter, req *http.Request) {
-	var tourney tournament
-	err := json.NewDecoder(req.Body).Decode(&tourney)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("createNewTournament: can't decode request body: %s", err)
-		return
+	if s.exporter != nil {
+		s.registerExportRoutes(router)
 	}
 
-	tourneyID, err := s.service.AddTournament(req.Context(), tourney.Name, tourney.Deposit)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("createNewTournament: %s", err)
-		return
+	gateway := newGateway(service.NewToDoServiceServer(db))
+	for _, route := range gatewayRoutes {
+		router.Handle(route.path, gateway).Methods(route.method)
 	}
 
-	err = json.NewEncoder(w).Encode(tournamentID{
-		ID: tourneyID,
-	})
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("createNewTournament: error encoding json: %s", err)
-		return
-	}
-}
-
-func (s *Server) getTournamentInfo(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	tournamentID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Print("getTournamentInfo: tournament id is not provided")
-		return
-	}
-
-	tournament, err := s.service.GetTournament(req.Context(), tournamentID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("getTournamentInfo: %s", err)
-		return
-	}
-
-	if err = json.NewEncoder(w).Encode(&tournament); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("getTournamentInfo: error encoding json: %s", err)
-		return
-	}
+	return &s
 }
 
-func (s *Server) joinTournament(w http.ResponseWriter, req *http.Request) {
-	var usrID userID
-	err := json.NewDecoder(req.Body).Decode(&usrID)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("joinTournament: can't decode request body: %s", err)
-		return
-	}
-
-	vars := mux.Vars(req)
-	tournamentID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Print("joinTournament: tournament id is not provided")
-		return
-	}
-
-	err = s.service.JoinTournament(req.Context(), tournamentID, usrID.ID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("joinTournament: %s", err)
-		return
-	}
+// CloseFeeds ends all live event feeds. Graceful shutdown of HTTP server
+// waits for active requests, so it should be called when shutdown starts.
+func (s *Server) CloseFeeds() {
+	s.closeFeed()
 }
 
-func (s *Server) finishTournament(w http.ResponseWriter, req *http.Request) {
-	var winnerUsrID winnerUserID
-	err := json.NewDecoder(req.Body).Decode(&winnerUsrID)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf("finishTournament: can't decode request body: %s", err)
-		return
-	}
-
-	vars := mux.Vars(req)
-	tournamentID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Print("finishTournament: tournament id is not provided")
-		return
-	}
-
-	err = s.service.FinishTournament(req.Context(), tournamentID, winnerUsrID.ID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("finishTournament: %s", err)
+// writeStorageError writes status matching error returned by storage.
+func writeStorageError(w http.ResponseWriter, req *http.Request, op string, err error) {
+	code := runtime.HTTPStatusFromCode(service.Status(err).Code())
+	w.WriteHeader(code)
+	if code == http.StatusInternalServerError {
+		slog.ErrorContext(req.Context(), "request failed", "handler", op, "err", err)
 		return
 	}
+	slog.WarnContext(req.Context(), "request failed", "handler", op, "err", err)
 }
 
-func (s *Server) cancelTournament(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	tournamentID, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Print("cancelTournament: tournament id is not provided")
-		return
-	}
-
-	err := s.service.DeleteTournament(req.Context(), tournamentID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("cancelTournament: %s", err)
-		return
+// writeJSON writes v as JSON response body.
+func writeJSON(w http.ResponseWriter, req *http.Request, op string, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(req.Context(), "error encoding json", "handler", op, "err", err)
 	}
 }
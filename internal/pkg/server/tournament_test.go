@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
-	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,9 +23,10 @@ func TestCreateNewTournament_Success(t *testing.T) {
 	expectedTournamentName := "Tournament_1"
 	expectedTournamentDeposit := 1500.0
 
+	organizerID := primitive.NewObjectID().Hex()
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().AddTournament(gomock.Any(), gomock.Eq(expectedTournamentName),
-		gomock.Eq(expectedTournamentDeposit)).Times(1).Return(expectedTournamentID, nil)
+		gomock.Eq(expectedTournamentDeposit), gomock.Eq(organizerID)).Times(1).Return(expectedTournamentID, nil)
 
 	enc, err := json.Marshal(tournament{
 		Name:    expectedTournamentName,
@@ -37,11 +37,12 @@ func TestCreateNewTournament_Success(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", "/tournament", b)
+	req = withPrincipal(req, organizerID, storage2.RoleOrganizer)
 
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -60,9 +61,10 @@ func TestCreateNewTournament_DB_Fail(t *testing.T) {
 	expectedTournamentDeposit := 1500.0
 	expectedError := errors.New("add doc to collection")
 
+	organizerID := primitive.NewObjectID().Hex()
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().AddTournament(gomock.Any(), gomock.Eq(expectedTournamentName),
-		gomock.Eq(expectedTournamentDeposit)).Times(1).Return("", expectedError)
+		gomock.Eq(expectedTournamentDeposit), gomock.Eq(organizerID)).Times(1).Return("", expectedError)
 
 	enc, err := json.Marshal(tournament{
 		Name:    expectedTournamentName,
@@ -73,11 +75,12 @@ func TestCreateNewTournament_DB_Fail(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", "/tournament", b)
+	req = withPrincipal(req, organizerID, storage2.RoleOrganizer)
 
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -88,13 +91,13 @@ func TestCreateNewTournament_Bad_Req(t *testing.T) {
 	defer ctrl.Finish()
 
 	mock := storage2.NewMockService(ctrl)
-	mock.EXPECT().AddTournament(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mock.EXPECT().AddTournament(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 	req := httptest.NewRequest("POST", "/tournament", nil)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -111,17 +114,17 @@ func TestGetTournamentInfo_Success(t *testing.T) {
 	expectedTournament := &storage2.Tournament{
 		ID:      expectedTournamentID,
 		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit}
+		Deposit: expectedTournamentDeposit,
+		Users:   []primitive.ObjectID{primitive.NewObjectID()}}
 	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(expectedTournamentID.Hex())).
 		Times(1).Return(expectedTournament, nil)
 
 	expectedURLPath := fmt.Sprintf("/tournament/%s", expectedTournamentID.Hex())
 	req := httptest.NewRequest("GET", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedTournamentID.Hex()})
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.getTournamentInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require := require.New(t)
@@ -145,11 +148,10 @@ func TestGetTournamentInfo_DB_Fail(t *testing.T) {
 
 	expectedURLPath := fmt.Sprintf("/tournament/%s", expectedTournamentID.Hex())
 	req := httptest.NewRequest("GET", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedTournamentID.Hex()})
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.getTournamentInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require := require.New(t)
@@ -167,12 +169,12 @@ func TestGetTournamentInfo_Bad_Req(t *testing.T) {
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().GetTournament(gomock.Any(), gomock.Any()).Times(0)
 
-	badURLPath := fmt.Sprint("/tournament/")
+	badURLPath := fmt.Sprintf("/tournament/%s", "garbage")
 	req := httptest.NewRequest("GET", badURLPath, nil)
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.getTournamentInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -197,11 +199,11 @@ func TestJoinTournament_Success(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedTournamentID})
+	req = withPrincipal(req, expectedUserID, storage2.RolePlayer)
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.joinTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -227,11 +229,11 @@ func TestJoinTournament_DB_Fail(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedTournamentID})
+	req = withPrincipal(req, expectedUserID, storage2.RolePlayer)
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.joinTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -244,7 +246,7 @@ func TestJoinTournament_Bad_Req(t *testing.T) {
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().JoinTournament(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
-	badURLPath := fmt.Sprint("/tournament/")
+	badURLPath := fmt.Sprintf("/tournament/%s/join", "garbage")
 	expectedUserID := primitive.NewObjectID().Hex()
 	enc, err := json.Marshal(userID{
 		ID: expectedUserID,
@@ -257,7 +259,7 @@ func TestJoinTournament_Bad_Req(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	s := NewServer(mock)
-	s.joinTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -269,6 +271,9 @@ func TestFinishTournament_Success(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	tournamentID := primitive.NewObjectID().Hex()
+	organizerID := primitive.NewObjectID()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: organizerID}, nil)
 	winnerUsrID := primitive.NewObjectID().Hex()
 	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(winnerUsrID)).
 		Times(1).Return(nil)
@@ -282,11 +287,11 @@ func TestFinishTournament_Success(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": tournamentID})
+	req = withPrincipal(req, organizerID.Hex(), storage2.RoleOrganizer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.finishTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -298,6 +303,9 @@ func TestFinishTournament_DB_Fail(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	tournamentID := primitive.NewObjectID().Hex()
+	organizerID := primitive.NewObjectID()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: organizerID}, nil)
 	winnerUsrID := primitive.NewObjectID().Hex()
 	expectedError := errors.New("any error cause it's transaction")
 	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Eq(tournamentID), gomock.Eq(winnerUsrID)).
@@ -312,11 +320,11 @@ func TestFinishTournament_DB_Fail(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": tournamentID})
+	req = withPrincipal(req, organizerID.Hex(), storage2.RoleOrganizer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.finishTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -329,7 +337,7 @@ func TestFinishTournament_Bad_Req(t *testing.T) {
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().FinishTournament(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
-	badURLPath := "/tournament/finish"
+	badURLPath := fmt.Sprintf("/tournament/%s/finish", "garbage")
 	winnerUsrID := primitive.NewObjectID().Hex()
 	enc, err := json.Marshal(winnerUserID{
 		ID: winnerUsrID,
@@ -339,11 +347,10 @@ func TestFinishTournament_Bad_Req(t *testing.T) {
 
 	b := bytes.NewBuffer(enc)
 	req := httptest.NewRequest("POST", badURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{})
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.finishTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -355,15 +362,18 @@ func TestCancelTournament_Success(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	tournamentID := primitive.NewObjectID().Hex()
+	organizerID := primitive.NewObjectID()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: organizerID}, nil)
 	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).Return(nil)
 
 	expectedURLPath := fmt.Sprintf("/tournament/%s", tournamentID)
 	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": tournamentID})
+	req = withPrincipal(req, organizerID.Hex(), storage2.RoleOrganizer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.cancelTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusOK, actualCode, "The two http codes should be the same")
@@ -375,16 +385,19 @@ func TestCancelTournament_DB_Fail(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	tournamentID := primitive.NewObjectID().Hex()
+	organizerID := primitive.NewObjectID()
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournamentID)).
+		Times(1).Return(&storage2.Tournament{Organizer: organizerID}, nil)
 	expectedError := errors.New("any error cause it's transaction")
 	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Eq(tournamentID)).Times(1).Return(expectedError)
 
 	expectedURLPath := fmt.Sprintf("/tournament/%s", tournamentID)
 	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": tournamentID})
+	req = withPrincipal(req, organizerID.Hex(), storage2.RoleOrganizer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.cancelTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -397,14 +410,63 @@ func TestCancelTournament_Bad_Req(t *testing.T) {
 	mock := storage2.NewMockService(ctrl)
 	mock.EXPECT().DeleteTournament(gomock.Any(), gomock.Any()).Times(0)
 
-	badURLPath := "/tournament"
+	badURLPath := fmt.Sprintf("/tournament/%s", "garbage")
 	req := httptest.NewRequest("DELETE", badURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{})
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.cancelTournament(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
 }
+
+func TestListTournaments_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	expectedTournament := storage2.Tournament{
+		ID:     primitive.NewObjectID(),
+		Name:   "Tournament_1",
+		Status: storage2.StatusStarted,
+		Users:  []primitive.ObjectID{}}
+	mock.EXPECT().ListTournaments(gomock.Any(), gomock.Eq(storage2.StatusStarted), gomock.Eq(int64(10))).
+		Times(1).Return([]storage2.Tournament{expectedTournament}, nil)
+
+	req := httptest.NewRequest("GET", "/tournament?status=started&limit=10", nil)
+
+	w := httptest.NewRecorder()
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require := require.New(t)
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+
+	var actual struct {
+		Tournaments []storage2.Tournament `json:"tournaments"`
+	}
+	err := json.NewDecoder(w.Result().Body).Decode(&actual)
+	require.NoError(err)
+	require.Equal([]storage2.Tournament{expectedTournament}, actual.Tournaments, "The two lists shoud be the same")
+}
+
+func TestListTournaments_Bad_Req(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().ListTournaments(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	req := httptest.NewRequest("GET", "/tournament?status=garbage&limit=1000", nil)
+
+	w := httptest.NewRecorder()
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require := require.New(t)
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+	var actual validationErrors
+	require.NoError(json.NewDecoder(w.Result().Body).Decode(&actual))
+	require.Equal([]string{"status", "limit"}, fields(actual.Errors), "The two fields should be the same")
+}
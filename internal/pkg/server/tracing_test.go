@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/tracing"
+)
+
+func TestTracing_Span_Per_Route(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewProvider(tracing.Config{}, sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(defaultProvider)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	userID := primitive.NewObjectID().Hex()
+	var storageSpan trace.SpanContext
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID)).Times(1).
+		DoAndReturn(func(ctx context.Context, id string) (*storage2.User, error) {
+			storageSpan = trace.SpanContextFromContext(ctx)
+			return &storage2.User{}, nil
+		})
+
+	s := NewServer(mock)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/user/"+userID, nil))
+
+	require := require.New(t)
+	require.Equal(http.StatusOK, w.Result().StatusCode, "The two http codes should be the same")
+	require.Len(recorder.Ended(), 1)
+
+	span := recorder.Ended()[0]
+	require.Equal("/user/{id}", span.Name())
+	require.Equal(span.SpanContext().TraceID(), storageSpan.TraceID(),
+		"Storage should be called with request span in context")
+}
@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
-	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -42,7 +41,7 @@ func TestCreateNewUser_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -71,7 +70,7 @@ func TestCreateNewUser_DB_Fail(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -88,7 +87,7 @@ func TestCreateNewUser_Bad_Req(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.createNewUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -105,12 +104,11 @@ func TestGetUserInfo_Success(t *testing.T) {
 
 	expectedURLPath := fmt.Sprintf("/user/%s", expectedUserID.Hex())
 	req := httptest.NewRequest("GET", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedUserID.Hex()})
 
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.getUserInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require := require.New(t)
@@ -133,11 +131,10 @@ func TestGetUserInfo_DB_Fail(t *testing.T) {
 
 	expectedURLPath := fmt.Sprintf("/user/%s", expectedUserID.Hex())
 	req := httptest.NewRequest("GET", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": expectedUserID.Hex()})
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.getUserInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -155,7 +152,7 @@ func TestGetUserInfo_Bad_Req(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.getUserInfo(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -167,15 +164,16 @@ func TestRemoveUser_Success(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	userID := primitive.NewObjectID()
-	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).Return(nil)
+	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Eq(storage2.DeleteUserOptions{})).
+		Times(1).Return(nil)
 
 	expectedURLPath := fmt.Sprintf("/user/%s", userID.Hex())
 	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.removeUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusOK, actualCode, "The two http codes should be the same")
@@ -187,33 +185,61 @@ func TestRemoveUser_DB_Fail(t *testing.T) {
 
 	mock := storage2.NewMockService(ctrl)
 	userID := primitive.NewObjectID()
-	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).Return(errors.New("delete doc from collection"))
+	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Any()).Times(1).
+		Return(errors.New("delete doc from collection"))
 
 	expectedURLPath := fmt.Sprintf("/user/%s", userID.Hex())
 	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.removeUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusInternalServerError, actualCode, "The two http codes should be the same")
 }
 
+func TestRemoveUser_Active(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	userID := primitive.NewObjectID()
+	gomock.InOrder(
+		mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Eq(storage2.DeleteUserOptions{})).
+			Times(1).Return(errors.Wrap(storage2.ErrUserActive, "1 active tournaments, balance 0")),
+		mock.EXPECT().DeleteUser(gomock.Any(), gomock.Eq(userID.Hex()),
+			gomock.Eq(storage2.DeleteUserOptions{Force: true, Anonymize: true})).Times(1).Return(nil),
+	)
+	s := NewServer(mock)
+
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/user/%s", userID.Hex()), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode, "Active user should not be deleted without force")
+
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/user/%s?force=true&anonymize=true", userID.Hex()), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
 func TestRemoveUser_Bad_Req(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mock := storage2.NewMockService(ctrl)
-	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).Times(0)
+	mock.EXPECT().DeleteUser(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 	expectedURLPath := fmt.Sprintf("/user/%s", "garbage")
 	req := httptest.NewRequest("DELETE", expectedURLPath, nil)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.removeUser(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -238,11 +264,11 @@ func TestTakeUserBonusPoints_Success(t *testing.T) {
 	b := bytes.NewBuffer(enc)
 	expectedURLPath := fmt.Sprintf("/user/%s/take", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, userID.Hex(), storage2.RolePlayer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.takeUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -267,11 +293,11 @@ func TestTakeUserBonusPoints_DB_Fail(t *testing.T) {
 	b := bytes.NewBuffer(enc)
 	expectedURLPath := fmt.Sprintf("/user/%s/take", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, userID.Hex(), storage2.RolePlayer)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.takeUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -287,11 +313,10 @@ func TestTakeUserBonusPoints_Bad_Req_Body(t *testing.T) {
 	userID := primitive.NewObjectID()
 	expectedURLPath := fmt.Sprintf("/user/%s/take", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.takeUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -317,7 +342,7 @@ func TestTakeUserBonusPoints_Bad_Req_URL(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.takeUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -342,11 +367,11 @@ func TestAddUserBonusPoints_Success(t *testing.T) {
 	b := bytes.NewBuffer(enc)
 	expectedURLPath := fmt.Sprintf("/user/%s/fund", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.addUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusOK, actualCode, "The two http codes should be the same")
@@ -371,11 +396,11 @@ func TestAddUserBonusPoints_DB_Fail(t *testing.T) {
 	b := bytes.NewBuffer(enc)
 	expectedURLPath := fmt.Sprintf("/user/%s/fund", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, b)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.addUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusInternalServerError, actualCode, "The two http codes should be the same")
@@ -391,11 +416,10 @@ func TestAddUserBonusPoints_Bad_Req_Body(t *testing.T) {
 	userID := primitive.NewObjectID()
 	expectedURLPath := fmt.Sprintf("/user/%s/fund", userID.Hex())
 	req := httptest.NewRequest("POST", expectedURLPath, nil)
-	req = mux.SetURLVars(req, map[string]string{"id": userID.Hex()})
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.addUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(t, http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -421,7 +445,7 @@ func TestAddUserBonusPoints_Bad_Req_URL(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	s := NewServer(mock)
-	s.addUserBonusPoints(w, req)
+	s.ServeHTTP(w, req)
 
 	actualCode := w.Result().StatusCode
 	require.Equal(http.StatusBadRequest, actualCode, "The two http codes should be the same")
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+// maxBodySize limits size of request body handlers are ready to decode.
+const maxBodySize = 64 << 10
+
+type validationErrors struct {
+	Errors validation.Errors `json:"errors"`
+}
+
+// decodeBody decodes single JSON object from limited request body into v
+// rejecting unknown fields. Returned error is always validation.Errors.
+func decodeBody(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	req.Body = http.MaxBytesReader(w, req.Body, maxBodySize)
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return validation.Errors{{Field: "body", Message: err.Error()}}
+	}
+	if dec.More() {
+		return validation.Errors{{Field: "body", Message: "must contain single JSON object"}}
+	}
+
+	return nil
+}
+
+// validate writes bad request response with field errors and returns false if v has errors.
+func validate(w http.ResponseWriter, req *http.Request, op string, v *validation.Validator) bool {
+	if err := v.Err(); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "invalid request", "handler", op, "err", err)
+		return false
+	}
+
+	return true
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		errs = validation.Errors{{Field: "body", Message: err.Error()}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err = json.NewEncoder(w).Encode(validationErrors{Errors: errs}); err != nil {
+		slog.Error("error encoding json", "handler", "writeValidationError", "err", err)
+	}
+}
+
+// queryLimit returns limit query parameter of request or def if it's not set.
+// Limit which is not integer from 1 to max is reported to v.
+func queryLimit(v *validation.Validator, req *http.Request, def, max int64) int64 {
+	raw := req.URL.Query().Get("limit")
+	if raw == "" {
+		return def
+	}
+
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit <= 0 || limit > max {
+		v.Add("limit", "must be integer from 1 to "+strconv.FormatInt(max, 10))
+	}
+
+	return limit
+}
+
+// queryTime parses optional RFC 3339 time of query parameter.
+func queryTime(v *validation.Validator, req *http.Request, param string) time.Time {
+	raw := req.URL.Query().Get(param)
+	if raw == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		v.Add(param, "must be RFC 3339 time")
+	}
+	return t
+}
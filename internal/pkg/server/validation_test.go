@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+func TestTakeUserBonusPoints_Negative_Points(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().TakeUserBalance(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	userID := primitive.NewObjectID()
+	enc, err := json.Marshal(userPoints{Points: -200})
+	require := require.New(t)
+	require.NoError(err)
+
+	expectedURLPath := fmt.Sprintf("/user/%s/take", userID.Hex())
+	req := httptest.NewRequest("POST", expectedURLPath, bytes.NewBuffer(enc))
+	req = withPrincipal(req, userID.Hex(), storage2.RolePlayer)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+
+	var actual validationErrors
+	err = json.NewDecoder(w.Result().Body).Decode(&actual)
+	require.NoError(err)
+	require.Equal(validation.Errors{{Field: "points", Message: "must be positive finite number"}}, actual.Errors,
+		"The two errors should be the same")
+}
+
+func TestCreateNewUser_Unknown_Field(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().AddUser(gomock.Any(), gomock.Any()).Times(0)
+
+	req := httptest.NewRequest("POST", "/user", bytes.NewBufferString(`{"name":"Gennadiy","balance":1000}`))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestCreateNewUser_Empty_Name(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().AddUser(gomock.Any(), gomock.Any()).Times(0)
+
+	req := httptest.NewRequest("POST", "/user", bytes.NewBufferString(`{"name":""}`))
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+}
+
+func TestGetUserInfo_Bad_ObjectID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage2.NewMockService(ctrl)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+	req := httptest.NewRequest("GET", "/user/garbage", nil)
+	w := httptest.NewRecorder()
+
+	s := NewServer(mock)
+	s.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode, "The two http codes should be the same")
+}
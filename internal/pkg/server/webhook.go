@@ -0,0 +1,203 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/webhook"
+)
+
+const (
+	// minWebhookSecretLength is minimal length of secret provided by partner.
+	minWebhookSecretLength = 16
+
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+type webhookRegistration struct {
+	URL        string              `json:"url"`
+	EventTypes []storage.EventType `json:"eventTypes"`
+	Secret     string              `json:"secret"`
+}
+
+// webhookCreated is the only response which reveals webhook secret.
+type webhookCreated struct {
+	*storage.Webhook
+	Secret string `json:"secret"`
+}
+
+// WithWebhooks enables admin endpoints managing webhook subscriptions
+// and inspecting and replaying their deliveries.
+func WithWebhooks(w *webhook.Webhooks) Option {
+	return func(s *Server) {
+		s.webhooks = w
+	}
+}
+
+func (s *Server) registerWebhookRoutes(router *mux.Router) {
+	router.HandleFunc("/webhooks", s.createWebhook).Methods("POST")
+	router.HandleFunc("/webhooks", s.listWebhooks).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", s.getWebhook).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", s.deleteWebhook).Methods("DELETE")
+	router.HandleFunc("/webhooks/{id}/deliveries", s.listWebhookDeliveries).Methods("GET")
+	router.HandleFunc("/webhooks/{id}/deliveries/{deliveryID}/replay", s.replayWebhookDelivery).Methods("POST")
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, req *http.Request) {
+	p, err := auth.RequireAdmin(req.Context())
+	if err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "createWebhook", "err", err)
+		return
+	}
+
+	var reg webhookRegistration
+	if err = decodeBody(w, req, &reg); err != nil {
+		writeValidationError(w, err)
+		slog.WarnContext(req.Context(), "can't decode request body", "handler", "createWebhook", "err", err)
+		return
+	}
+
+	var v validation.Validator
+	v.URL("url", reg.URL)
+	for _, typ := range reg.EventTypes {
+		if !typ.Valid() {
+			v.Add("eventTypes", "must contain only known event types")
+			break
+		}
+	}
+	if reg.Secret != "" && len(reg.Secret) < minWebhookSecretLength {
+		v.Add("secret", "must be at least 16 characters long")
+	}
+	if !validate(w, req, "createWebhook", &v) {
+		return
+	}
+
+	created, err := s.webhooks.Register(req.Context(), reg.URL, reg.EventTypes, reg.Secret, p.UserID)
+	if errors.Is(err, webhook.ErrForbiddenURL) {
+		writeValidationError(w, validation.Errors{{Field: "url", Message: "must be https URL of public host"}})
+		slog.WarnContext(req.Context(), "request rejected", "handler", "createWebhook", "err", err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(req.Context(), "request failed", "handler", "createWebhook", "err", err)
+		return
+	}
+
+	writeJSON(w, req, "createWebhook", webhookCreated{Webhook: created, Secret: created.Secret})
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, req *http.Request) {
+	if _, err := auth.RequireAdmin(req.Context()); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", "listWebhooks", "err", err)
+		return
+	}
+
+	webhooks, err := s.webhooks.ListWebhooks(req.Context())
+	if err != nil {
+		writeStorageError(w, req, "listWebhooks", err)
+		return
+	}
+
+	writeJSON(w, req, "listWebhooks", webhooks)
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, req *http.Request) {
+	id, ok := s.webhookID(w, req, "getWebhook")
+	if !ok {
+		return
+	}
+
+	hook, err := s.webhooks.GetWebhook(req.Context(), id)
+	if err != nil {
+		writeStorageError(w, req, "getWebhook", err)
+		return
+	}
+
+	writeJSON(w, req, "getWebhook", hook)
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, req *http.Request) {
+	id, ok := s.webhookID(w, req, "deleteWebhook")
+	if !ok {
+		return
+	}
+
+	if err := s.webhooks.DeleteWebhook(req.Context(), id); err != nil {
+		writeStorageError(w, req, "deleteWebhook", err)
+		return
+	}
+}
+
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, req *http.Request) {
+	id, ok := s.webhookID(w, req, "listWebhookDeliveries")
+	if !ok {
+		return
+	}
+
+	status := storage.DeliveryStatus(req.URL.Query().Get("status"))
+	var v validation.Validator
+	switch status {
+	case "", storage.DeliveryPending, storage.DeliveryDelivered, storage.DeliveryDead:
+	default:
+		v.Add("status", "must be one of pending, delivered, dead")
+	}
+	limit := queryLimit(&v, req, defaultDeliveriesLimit, maxDeliveriesLimit)
+	if !validate(w, req, "listWebhookDeliveries", &v) {
+		return
+	}
+
+	deliveries, err := s.webhooks.ListWebhookDeliveries(req.Context(), id, status, limit)
+	if err != nil {
+		writeStorageError(w, req, "listWebhookDeliveries", err)
+		return
+	}
+
+	writeJSON(w, req, "listWebhookDeliveries", deliveries)
+}
+
+func (s *Server) replayWebhookDelivery(w http.ResponseWriter, req *http.Request) {
+	id, ok := s.webhookID(w, req, "replayWebhookDelivery")
+	if !ok {
+		return
+	}
+
+	deliveryID := mux.Vars(req)["deliveryID"]
+	var v validation.Validator
+	v.ObjectID("deliveryID", deliveryID)
+	if !validate(w, req, "replayWebhookDelivery", &v) {
+		return
+	}
+
+	if err := s.webhooks.ReplayWebhookDelivery(req.Context(), id, deliveryID); err != nil {
+		writeStorageError(w, req, "replayWebhookDelivery", err)
+		return
+	}
+}
+
+// webhookID checks that caller is admin and returns valid webhook id of request path.
+func (s *Server) webhookID(w http.ResponseWriter, req *http.Request, op string) (string, bool) {
+	if _, err := auth.RequireAdmin(req.Context()); err != nil {
+		w.WriteHeader(auth.HTTPStatus(err))
+		slog.WarnContext(req.Context(), "request rejected", "handler", op, "err", err)
+		return "", false
+	}
+
+	id := mux.Vars(req)["id"]
+	var v validation.Validator
+	v.ObjectID("id", id)
+	if !validate(w, req, op, &v) {
+		return "", false
+	}
+
+	return id, true
+}
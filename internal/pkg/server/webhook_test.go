@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	storage2 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/webhook"
+)
+
+// webhookStore implements part of webhook.Store used by handlers.
+type webhookStore struct {
+	webhook.Store
+	webhooks map[string]storage2.Webhook
+	replayed []string
+}
+
+func (s *webhookStore) AddWebhook(_ context.Context, w storage2.Webhook) (string, error) {
+	w.ID = primitive.NewObjectID()
+	s.webhooks[w.ID.Hex()] = w
+	return w.ID.Hex(), nil
+}
+
+func (s *webhookStore) GetWebhook(_ context.Context, id string) (*storage2.Webhook, error) {
+	w, ok := s.webhooks[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &w, nil
+}
+
+func (s *webhookStore) ListWebhookDeliveries(_ context.Context, webhookID string, status storage2.DeliveryStatus,
+	limit int64) ([]storage2.WebhookDelivery, error) {
+	return []storage2.WebhookDelivery{{ID: primitive.NewObjectID(), Status: status, Attempts: int(limit)}}, nil
+}
+
+func (s *webhookStore) ReplayWebhookDelivery(_ context.Context, webhookID, id string) error {
+	if _, ok := s.webhooks[webhookID]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	s.replayed = append(s.replayed, id)
+	return nil
+}
+
+func newWebhookServer(t *testing.T) (*Server, *webhookStore) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	store := &webhookStore{webhooks: make(map[string]storage2.Webhook)}
+	return NewServer(storage2.NewMockService(ctrl), WithWebhooks(webhook.New(store, webhook.NewURLPolicy([]string{"partner.example"})))), store
+}
+
+func TestCreateWebhook(t *testing.T) {
+	s, store := newWebhookServer(t)
+	adminID := primitive.NewObjectID().Hex()
+	require := require.New(t)
+
+	body := `{"url":"https://partner.example/hook","eventTypes":["TournamentFinished"]}`
+	req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	req = withPrincipal(req, adminID, storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+
+	var created struct {
+		ID         string   `json:"id"`
+		URL        string   `json:"url"`
+		EventTypes []string `json:"eventTypes"`
+		Secret     string   `json:"secret"`
+		CreatedBy  string   `json:"createdBy"`
+	}
+	require.NoError(json.NewDecoder(w.Body).Decode(&created))
+	require.Equal("https://partner.example/hook", created.URL)
+	require.Equal([]string{"TournamentFinished"}, created.EventTypes)
+	require.Equal(adminID, created.CreatedBy)
+	require.Equal(store.webhooks[created.ID].Secret, created.Secret, "Generated secret should be returned once")
+	require.NotEmpty(created.Secret)
+
+	req = httptest.NewRequest("GET", "/webhooks/"+created.ID, nil)
+	req = withPrincipal(req, adminID, storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.NotContains(w.Body.String(), created.Secret, "Secret should not be returned again")
+}
+
+func TestCreateWebhook_Invalid(t *testing.T) {
+	s, store := newWebhookServer(t)
+	require := require.New(t)
+
+	body := `{"url":"/hook","eventTypes":["Unknown"],"secret":"short"}`
+	req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code)
+
+	var resp validationErrors
+	require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(resp.Errors, 3)
+
+	body = `{"url":"https://partner.example/hook"}`
+	req = httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RolePlayer)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusForbidden, w.Code, "Only admin should manage webhooks")
+
+	body = `{"url":"http://127.0.0.1:8080/user"}`
+	req = httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code, "Webhook should not target internal address")
+	require.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal("url", resp.Errors[0].Field)
+	require.Empty(store.webhooks)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	s, store := newWebhookServer(t)
+	require := require.New(t)
+	webhookID := primitive.NewObjectID()
+	store.webhooks[webhookID.Hex()] = storage2.Webhook{ID: webhookID}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/webhooks/%s/deliveries?status=dead&limit=10", webhookID.Hex()), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+
+	var log []storage2.WebhookDelivery
+	require.NoError(json.NewDecoder(w.Body).Decode(&log))
+	require.Equal(storage2.DeliveryDead, log[0].Status)
+	require.Equal(10, log[0].Attempts, "Limit should be passed to store")
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/webhooks/%s/deliveries?status=lost&limit=0", webhookID.Hex()), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusBadRequest, w.Code)
+
+	deliveryID := primitive.NewObjectID().Hex()
+	req = httptest.NewRequest("POST", fmt.Sprintf("/webhooks/%s/deliveries/%s/replay", webhookID.Hex(), deliveryID), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusOK, w.Code)
+	require.Equal([]string{deliveryID}, store.replayed)
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/webhooks/%s/deliveries/%s/replay",
+		primitive.NewObjectID().Hex(), deliveryID), nil)
+	req = withPrincipal(req, primitive.NewObjectID().Hex(), storage2.RoleAdmin)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(http.StatusNotFound, w.Code)
+}
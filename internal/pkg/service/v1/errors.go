@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// Status translates error returned by service method to gRPC status.
+// Statuses are kept as is, known storage errors get matching codes
+// and details of the rest are hidden behind Internal status.
+func Status(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return status.New(codes.NotFound, "not found")
+	case errors.Is(err, storage.ErrLoginTaken), mongo.IsDuplicateKeyError(err):
+		return status.New(codes.AlreadyExists, "already exists")
+	case errors.Is(err, storage.ErrUserActive):
+		return status.New(codes.FailedPrecondition, storage.ErrUserActive.Error())
+	case errors.Is(err, storage.ErrTournamentStatus):
+		return status.New(codes.FailedPrecondition, storage.ErrTournamentStatus.Error())
+	case errors.Is(err, storage.ErrWinnerNotJoined):
+		return status.New(codes.FailedPrecondition, storage.ErrWinnerNotJoined.Error())
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return status.New(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, "canceled")
+	default:
+		return status.New(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,27 @@
+package v1
+
+import (
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+)
+
+// methodClasses assigns rate limit class to methods which aren't plain reads.
+// Classes match the ones of corresponding REST routes.
+var methodClasses = map[string]ratelimit.Class{
+	v1.Tournament_TakeUserBalance_FullMethodName:  ratelimit.ClassMoney,
+	v1.Tournament_FundUserBalance_FullMethodName:  ratelimit.ClassMoney,
+	v1.Tournament_JoinTournament_FullMethodName:   ratelimit.ClassMoney,
+	v1.Tournament_LeaveTournament_FullMethodName:  ratelimit.ClassMoney,
+	v1.Tournament_FinishTournament_FullMethodName: ratelimit.ClassMoney,
+	v1.Tournament_DeleteUser_FullMethodName:       ratelimit.ClassAdmin,
+	v1.Tournament_SetUserRole_FullMethodName:      ratelimit.ClassAdmin,
+}
+
+// RateLimitClass returns rate limit class of gRPC method with provided full name.
+func RateLimitClass(fullMethod string) ratelimit.Class {
+	if class, ok := methodClasses[fullMethod]; ok {
+		return class
+	}
+
+	return ratelimit.ClassRead
+}
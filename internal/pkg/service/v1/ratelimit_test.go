@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/ratelimit"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestRateLimitClass(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := primitive.NewObjectID()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Eq(userID.Hex()), gomock.Eq(10.0)).Times(1).Return(nil)
+	mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(userID.Hex())).Times(1).
+		Return(&storage.User{ID: userID, Name: "Gena", Role: storage.RolePlayer}, nil)
+
+	admin := &auth.Principal{UserID: primitive.NewObjectID().Hex(), Role: storage.RoleAdmin}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[ratelimit.Class]ratelimit.Limit{ratelimit.ClassMoney: {Rate: 0.01, Burst: 1}})
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(auth.NewContext(ctx, admin), req)
+		},
+		ratelimit.UnaryServerInterceptor(limiter, RateLimitClass),
+	))
+	v1.RegisterTournamentServer(srv, NewToDoServiceServer(mock))
+	l := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }))
+	require := require.New(t)
+	require.NoError(err)
+	defer conn.Close()
+	client := v1.NewTournamentClient(conn)
+
+	req := &v1.UserPointsRequest{Id: userID.Hex(), Points: 10}
+	_, err = client.FundUserBalance(context.TODO(), req)
+	require.NoError(err)
+	_, err = client.FundUserBalance(context.TODO(), req)
+	require.Equal(codes.ResourceExhausted, status.Code(err), "Call over money limit should be rejected")
+	_, err = client.GetUser(context.TODO(), &v1.GetUserRequest{Id: userID.Hex()})
+	require.NoError(err, "Read should not be limited by money limit")
+
+	require.Equal(ratelimit.ClassAdmin, RateLimitClass(v1.Tournament_DeleteUser_FullMethodName))
+	require.Equal(ratelimit.ClassRead, RateLimitClass(v1.Tournament_WatchTournament_FullMethodName))
+}
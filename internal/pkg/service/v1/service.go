@@ -2,44 +2,390 @@ package v1
 
 import (
 	"context"
+	"log/slog"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
 	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
 )
 
 const (
 	// apiVersion is version of API is provided by server
 	apiVersion = "v1"
+
+	defaultTournamentsLimit = 50
+	maxTournamentsLimit     = 500
 )
 
 // TournamentService is implementation of v1.Tournament proto interface.
+// It is served both over gRPC and as REST API through generated gateway.
 type TournamentService struct {
 	v1.UnimplementedTournamentServer
-	db *storage.DB
+	db  storage.Service
+	hub *events.Hub
+}
+
+// Option configures TournamentService.
+type Option func(*TournamentService)
+
+// WithEvents enables WatchTournament streaming events from hub.
+func WithEvents(hub *events.Hub) Option {
+	return func(t *TournamentService) {
+		t.hub = hub
+	}
 }
 
 // NewToDoServiceServer creates ToDo service
-func NewToDoServiceServer(db *storage.DB) v1.TournamentServer {
-	return &TournamentService{db: db}
+func NewToDoServiceServer(db storage.Service, opts ...Option) v1.TournamentServer {
+	t := &TournamentService{db: db}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
 }
 
 func (t TournamentService) CreateUser(ctx context.Context, r *v1.CreateUserRequest) (*v1.CreateUserResponse, error) {
-	return &v1.CreateUserResponse{Id: "newID"}, nil
+	var v validation.Validator
+	v.Name("name", r.GetName())
+	if err := invalid(ctx, "CreateUser", &v); err != nil {
+		return nil, err
+	}
+
+	userID, err := t.db.AddUser(ctx, r.GetName())
+	if err != nil {
+		return nil, failed(ctx, "CreateUser", err)
+	}
+
+	return &v1.CreateUserResponse{Id: userID}, nil
+}
+
+func (t TournamentService) GetUser(ctx context.Context, r *v1.GetUserRequest) (*v1.User, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if err := invalid(ctx, "GetUser", &v); err != nil {
+		return nil, err
+	}
+
+	user, err := t.db.GetUser(ctx, r.GetId())
+	if err != nil {
+		return nil, failed(ctx, "GetUser", err)
+	}
+
+	resp := &v1.User{
+		Id:      user.ID.Hex(),
+		Name:    user.Name,
+		Balance: user.Balance,
+		Role:    string(user.Role),
+	}
+	if user.DeletedAt != nil {
+		resp.DeletedAt = timestamppb.New(*user.DeletedAt)
+	}
+
+	return resp, nil
+}
+
+func (t TournamentService) DeleteUser(ctx context.Context, r *v1.DeleteUserRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if err := invalid(ctx, "DeleteUser", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, rejected(ctx, "DeleteUser", err)
+	}
+
+	opts := storage.DeleteUserOptions{Force: r.GetForce(), Anonymize: r.GetAnonymize()}
+	if err := t.db.DeleteUser(ctx, r.GetId(), opts); err != nil {
+		return nil, failed(ctx, "DeleteUser", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) TakeUserBalance(ctx context.Context, r *v1.UserPointsRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	v.Amount("points", r.GetPoints())
+	if err := invalid(ctx, "TakeUserBalance", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireSelf(ctx, r.GetId()); err != nil {
+		return nil, rejected(ctx, "TakeUserBalance", err)
+	}
+
+	if err := t.db.TakeUserBalance(ctx, r.GetId(), r.GetPoints()); err != nil {
+		return nil, failed(ctx, "TakeUserBalance", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) FundUserBalance(ctx context.Context, r *v1.UserPointsRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	v.Amount("points", r.GetPoints())
+	if err := invalid(ctx, "FundUserBalance", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, rejected(ctx, "FundUserBalance", err)
+	}
+
+	if err := t.db.FundUserBalance(ctx, r.GetId(), r.GetPoints()); err != nil {
+		return nil, failed(ctx, "FundUserBalance", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) SetUserRole(ctx context.Context, r *v1.SetUserRoleRequest) (*emptypb.Empty, error) {
+	role := storage.Role(r.GetRole())
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if !role.Valid() {
+		v.Add("role", "must be one of admin, organizer, player")
+	}
+	if err := invalid(ctx, "SetUserRole", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, rejected(ctx, "SetUserRole", err)
+	}
+
+	if err := t.db.SetUserRole(ctx, r.GetId(), role); err != nil {
+		return nil, failed(ctx, "SetUserRole", err)
+	}
+
+	return &emptypb.Empty{}, nil
 }
 
-func (t TournamentService) UserList(ctx context.Context, r *v1.GetUserListRequest) (*v1.GetUserListResponse, error) {
-	return &v1.GetUserListResponse{Users: getUserList()}, nil
+func (t TournamentService) CreateTournament(ctx context.Context,
+	r *v1.CreateTournamentRequest) (*v1.CreateTournamentResponse, error) {
+	var v validation.Validator
+	v.Name("name", r.GetName())
+	v.Deposit("deposit", r.GetDeposit())
+	if err := invalid(ctx, "CreateTournament", &v); err != nil {
+		return nil, err
+	}
+
+	organizer, err := auth.RequireOrganizer(ctx)
+	if err != nil {
+		return nil, rejected(ctx, "CreateTournament", err)
+	}
+
+	tournamentID, err := t.db.AddTournament(ctx, r.GetName(), r.GetDeposit(), organizer.UserID)
+	if err != nil {
+		return nil, failed(ctx, "CreateTournament", err)
+	}
+
+	return &v1.CreateTournamentResponse{Id: tournamentID}, nil
 }
 
-func getUserList() []*v1.User {
-	return []*v1.User{
-		{
-			Name: "Stas",
-			Age:  12,
-		},
-		{
-			Name: "Vlad",
-			Age:  25,
-		},
+func (t TournamentService) GetTournament(ctx context.Context, r *v1.GetTournamentRequest) (*v1.TournamentInfo, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if err := invalid(ctx, "GetTournament", &v); err != nil {
+		return nil, err
+	}
+
+	tournament, err := t.db.GetTournament(ctx, r.GetId())
+	if err != nil {
+		return nil, failed(ctx, "GetTournament", err)
+	}
+
+	return tournamentInfo(tournament), nil
+}
+
+func (t TournamentService) ListTournaments(ctx context.Context,
+	r *v1.ListTournamentsRequest) (*v1.ListTournamentsResponse, error) {
+	status := storage.TournamentStatus(r.GetStatus())
+	var v validation.Validator
+	if status != "" && !status.Valid() {
+		v.Add("status", "must be one of signIn, started, finished")
+	}
+	if r.GetLimit() < 0 || r.GetLimit() > maxTournamentsLimit {
+		v.Add("limit", "must be integer from 1 to "+strconv.Itoa(maxTournamentsLimit))
+	}
+	if err := invalid(ctx, "ListTournaments", &v); err != nil {
+		return nil, err
 	}
+
+	limit := r.GetLimit()
+	if limit == 0 {
+		limit = defaultTournamentsLimit
+	}
+	tournaments, err := t.db.ListTournaments(ctx, status, limit)
+	if err != nil {
+		return nil, failed(ctx, "ListTournaments", err)
+	}
+
+	resp := &v1.ListTournamentsResponse{Tournaments: make([]*v1.TournamentInfo, 0, len(tournaments))}
+	for i := range tournaments {
+		resp.Tournaments = append(resp.Tournaments, tournamentInfo(&tournaments[i]))
+	}
+
+	return resp, nil
+}
+
+func tournamentInfo(tournament *storage.Tournament) *v1.TournamentInfo {
+	users := make([]string, 0, len(tournament.Users))
+	for _, id := range tournament.Users {
+		users = append(users, id.Hex())
+	}
+
+	return &v1.TournamentInfo{
+		Id:        tournament.ID.Hex(),
+		Name:      tournament.Name,
+		Deposit:   tournament.Deposit,
+		Status:    string(tournament.Status),
+		Prize:     tournament.Prize,
+		Users:     users,
+		Winner:    tournament.Winner.Hex(),
+		Organizer: tournament.Organizer.Hex(),
+	}
+}
+
+func (t TournamentService) JoinTournament(ctx context.Context, r *v1.TournamentMemberRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	v.ObjectID("userID", r.GetUserId())
+	if err := invalid(ctx, "JoinTournament", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireSelf(ctx, r.GetUserId()); err != nil {
+		return nil, rejected(ctx, "JoinTournament", err)
+	}
+
+	if err := t.db.JoinTournament(ctx, r.GetId(), r.GetUserId()); err != nil {
+		return nil, failed(ctx, "JoinTournament", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) LeaveTournament(ctx context.Context, r *v1.TournamentMemberRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	v.ObjectID("userID", r.GetUserId())
+	if err := invalid(ctx, "LeaveTournament", &v); err != nil {
+		return nil, err
+	}
+
+	if _, err := auth.RequireSelf(ctx, r.GetUserId()); err != nil {
+		return nil, rejected(ctx, "LeaveTournament", err)
+	}
+
+	if err := t.db.LeaveTournament(ctx, r.GetId(), r.GetUserId()); err != nil {
+		return nil, failed(ctx, "LeaveTournament", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) StartTournament(ctx context.Context, r *v1.StartTournamentRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if err := invalid(ctx, "StartTournament", &v); err != nil {
+		return nil, err
+	}
+
+	if err := t.authorizeOrganizer(ctx, "StartTournament", r.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := t.db.SetTournamentStatus(ctx, r.GetId(), storage.StatusStarted); err != nil {
+		return nil, failed(ctx, "StartTournament", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) FinishTournament(ctx context.Context, r *v1.FinishTournamentRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	v.ObjectID("winnerUserID", r.GetWinnerUserId())
+	if err := invalid(ctx, "FinishTournament", &v); err != nil {
+		return nil, err
+	}
+
+	if err := t.authorizeOrganizer(ctx, "FinishTournament", r.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := t.db.FinishTournament(ctx, r.GetId(), r.GetWinnerUserId()); err != nil {
+		return nil, failed(ctx, "FinishTournament", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (t TournamentService) CancelTournament(ctx context.Context, r *v1.CancelTournamentRequest) (*emptypb.Empty, error) {
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	if err := invalid(ctx, "CancelTournament", &v); err != nil {
+		return nil, err
+	}
+
+	if err := t.authorizeOrganizer(ctx, "CancelTournament", r.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := t.db.DeleteTournament(ctx, r.GetId()); err != nil {
+		return nil, failed(ctx, "CancelTournament", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// authorizeOrganizer checks that caller is organizer of tournament with provided id or admin.
+func (t TournamentService) authorizeOrganizer(ctx context.Context, method, tournamentID string) error {
+	if _, err := auth.FromContext(ctx); err != nil {
+		return rejected(ctx, method, err)
+	}
+
+	tournament, err := t.db.GetTournament(ctx, tournamentID)
+	if err != nil {
+		return failed(ctx, method, err)
+	}
+
+	if _, err = auth.RequireTournamentOrganizer(ctx, tournament); err != nil {
+		return rejected(ctx, method, err)
+	}
+
+	return nil
+}
+
+// invalid returns collected validation errors, which carry InvalidArgument status.
+func invalid(ctx context.Context, method string, v *validation.Validator) error {
+	err := v.Err()
+	if err != nil {
+		slog.WarnContext(ctx, "invalid request", "method", method, "err", err)
+	}
+
+	return err
+}
+
+// rejected converts authorization error to gRPC status.
+func rejected(ctx context.Context, method string, err error) error {
+	slog.WarnContext(ctx, "request rejected", "method", method, "err", err)
+	return auth.GRPCStatus(err)
+}
+
+// failed logs storage error. It is translated to status by Status
+// in gRPC interceptor chain or in REST gateway.
+func failed(ctx context.Context, method string, err error) error {
+	slog.ErrorContext(ctx, "request failed", "method", method, "err", err)
+	return err
 }
@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/auth"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+func TestGetTournament_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := storage.NewMockService(ctrl)
+	tournament := &storage.Tournament{
+		ID:        primitive.NewObjectID(),
+		Name:      "Tournament_1",
+		Deposit:   1500,
+		Status:    storage.StatusSignIn,
+		Users:     []primitive.ObjectID{primitive.NewObjectID()},
+		Organizer: primitive.NewObjectID(),
+	}
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(tournament.ID.Hex())).Times(1).Return(tournament, nil)
+
+	s := NewToDoServiceServer(mock)
+	actual, err := s.GetTournament(context.Background(), &v1.GetTournamentRequest{Id: tournament.ID.Hex()})
+	require := require.New(t)
+	require.NoError(err)
+	require.Equal(tournament.Name, actual.GetName())
+	require.Equal(string(storage.StatusSignIn), actual.GetStatus())
+	require.Equal([]string{tournament.Users[0].Hex()}, actual.GetUsers())
+	require.Equal(tournament.Organizer.Hex(), actual.GetOrganizer())
+}
+
+func TestFundUserBalance_Codes(t *testing.T) {
+	userID := primitive.NewObjectID().Hex()
+	tests := []struct {
+		name     string
+		req      *v1.UserPointsRequest
+		role     storage.Role
+		dbErr    error
+		dbCalls  int
+		expected codes.Code
+	}{
+		{"success", &v1.UserPointsRequest{Id: userID, Points: 200}, storage.RoleAdmin, nil, 1, codes.OK},
+		{"invalid", &v1.UserPointsRequest{Id: "garbage", Points: 200}, storage.RoleAdmin, nil, 0, codes.InvalidArgument},
+		{"unauthenticated", &v1.UserPointsRequest{Id: userID, Points: 200}, "", nil, 0, codes.Unauthenticated},
+		{"forbidden", &v1.UserPointsRequest{Id: userID, Points: 200}, storage.RolePlayer, nil, 0, codes.PermissionDenied},
+		{"unknown user", &v1.UserPointsRequest{Id: userID, Points: 200}, storage.RoleAdmin,
+			errors.Wrap(mongo.ErrNoDocuments, "update doc in collection"), 1, codes.NotFound},
+		{"db fail", &v1.UserPointsRequest{Id: userID, Points: 200}, storage.RoleAdmin,
+			errors.New("update doc in collection"), 1, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mock := storage.NewMockService(ctrl)
+			mock.EXPECT().FundUserBalance(gomock.Any(), gomock.Eq(userID), gomock.Eq(200.0)).
+				Times(tt.dbCalls).Return(tt.dbErr)
+
+			ctx := context.Background()
+			if tt.role != "" {
+				ctx = auth.NewContext(ctx, &auth.Principal{UserID: primitive.NewObjectID().Hex(), Role: tt.role})
+			}
+
+			_, err := NewToDoServiceServer(mock).FundUserBalance(ctx, tt.req)
+			require.Equal(t, tt.expected, Status(err).Code(), "The two codes should be the same")
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected codes.Code
+	}{
+		{status.Error(codes.PermissionDenied, "denied"), codes.PermissionDenied},
+		{errors.Wrap(mongo.ErrNoDocuments, "get doc from collection"), codes.NotFound},
+		{errors.Wrap(storage.ErrLoginTaken, "AddCredentials"), codes.AlreadyExists},
+		{errors.Wrap(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, "insert doc to collection"),
+			codes.AlreadyExists},
+		{errors.Wrap(storage.ErrUserActive, "1 active tournaments, balance 0"), codes.FailedPrecondition},
+		{errors.Wrap(storage.ErrTournamentStatus, `set status "started"`), codes.FailedPrecondition},
+		{errors.Wrap(storage.ErrWinnerNotJoined, "SetTournamentWinner"), codes.FailedPrecondition},
+		{errors.Wrap(context.DeadlineExceeded, "update doc in collection"), codes.DeadlineExceeded},
+		{errors.Wrap(mongo.ErrNoDocuments, "update doc in collection"), codes.NotFound},
+		{errors.New("update doc in collection"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		st := Status(tt.err)
+		require.Equal(t, tt.expected, st.Code(), tt.err.Error())
+		if tt.expected == codes.Internal {
+			require.Equal(t, "internal error", st.Message(), "Details should be hidden")
+		}
+	}
+}
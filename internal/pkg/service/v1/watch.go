@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/validation"
+)
+
+// EventSnapshot is type of first event of stream which wasn't resumed.
+const EventSnapshot = "snapshot"
+
+// WatchTournament streams changes of tournament. Unless stream is resumed from
+// position still kept by hub, it starts with snapshot of whole tournament.
+// Clients which fall behind get Unavailable and should resume from position
+// of last received event.
+func (t TournamentService) WatchTournament(r *v1.WatchTournamentRequest,
+	stream v1.Tournament_WatchTournamentServer) error {
+	ctx := stream.Context()
+	var v validation.Validator
+	v.ObjectID("id", r.GetId())
+	from, err := events.ParsePosition(r.GetResumePosition())
+	if err != nil {
+		v.Add("resumePosition", "is malformed")
+	}
+	if err = invalid(ctx, "WatchTournament", &v); err != nil {
+		return err
+	}
+
+	if t.hub == nil {
+		return status.Error(codes.Unimplemented, "tournament events are not enabled")
+	}
+
+	// subscribe before reading snapshot, so no change is lost in between
+	sub := t.hub.Subscribe(from, events.ForTournament(r.GetId()))
+	defer sub.Close()
+
+	if !sub.Resumed {
+		tournament, err := t.db.GetTournament(ctx, r.GetId())
+		if err != nil {
+			return failed(ctx, "WatchTournament", err)
+		}
+
+		err = stream.Send(&v1.TournamentEvent{
+			Position:     sub.Position.String(),
+			Type:         EventSnapshot,
+			TournamentId: r.GetId(),
+			Snapshot:     tournamentInfo(tournament),
+			Time:         timestamppb.New(time.Now()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		e, err := sub.Next(ctx)
+		if errors.Is(err, events.ErrLagged) {
+			return status.Error(codes.Unavailable, "client is too slow, resume from last received position")
+		}
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		if err = stream.Send(tournamentEvent(e)); err != nil {
+			return err
+		}
+		if e.Type == events.TypeCanceled {
+			return nil
+		}
+	}
+}
+
+func tournamentEvent(e events.Event) *v1.TournamentEvent {
+	return &v1.TournamentEvent{
+		Position:     e.Position.String(),
+		Type:         string(e.Type),
+		TournamentId: e.TournamentID,
+		UserId:       e.UserID,
+		Prize:        e.Prize,
+		Status:       string(e.Status),
+		Time:         timestamppb.New(e.Time),
+	}
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/api/v1"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/events"
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// watchStream passes sent events to channel.
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *v1.TournamentEvent
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(e *v1.TournamentEvent) error {
+	s.sent <- e
+	return nil
+}
+
+func TestWatchTournament(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tournament := &storage.Tournament{ID: primitive.NewObjectID(), Name: "Tournament_1", Status: storage.StatusSignIn}
+	id := tournament.ID.Hex()
+	userID := primitive.NewObjectID().Hex()
+	mock := storage.NewMockService(ctrl)
+	mock.EXPECT().GetTournament(gomock.Any(), gomock.Eq(id)).Times(1).Return(tournament, nil)
+
+	hub := events.NewHub(16, 16)
+	s := NewToDoServiceServer(mock, WithEvents(hub))
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := &watchStream{ctx: ctx, sent: make(chan *v1.TournamentEvent, 16)}
+	done := make(chan error, 1)
+	go func() { done <- s.WatchTournament(&v1.WatchTournamentRequest{Id: id}, stream) }()
+
+	snapshot := <-stream.sent
+	require.Equal(EventSnapshot, snapshot.GetType())
+	require.Equal(tournament.Name, snapshot.GetSnapshot().GetName())
+
+	hub.Publish(events.Event{Type: events.TypePlayerJoined, TournamentID: id, UserID: userID})
+	hub.Publish(events.Event{Type: events.TypeCanceled, TournamentID: id})
+	joined := <-stream.sent
+	require.Equal(string(events.TypePlayerJoined), joined.GetType())
+	require.Equal(userID, joined.GetUserId())
+	require.Equal(string(events.TypeCanceled), (<-stream.sent).GetType())
+	require.NoError(<-done, "Stream should end after tournament is canceled")
+
+	// resumed stream gets missed events without snapshot
+	resumed := &watchStream{ctx: ctx, sent: make(chan *v1.TournamentEvent, 16)}
+	err := s.WatchTournament(&v1.WatchTournamentRequest{Id: id, ResumePosition: joined.GetPosition()}, resumed)
+	require.NoError(err)
+	require.Len(resumed.sent, 1)
+	require.Equal(string(events.TypeCanceled), (<-resumed.sent).GetType())
+
+	err = s.WatchTournament(&v1.WatchTournamentRequest{Id: id, ResumePosition: "garbage"}, resumed)
+	require.Equal(codes.InvalidArgument, status.Code(err))
+	err = NewToDoServiceServer(mock).WatchTournament(&v1.WatchTournamentRequest{Id: id}, resumed)
+	require.Equal(codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditAction is kind of privileged mutation recorded in audit log.
+type AuditAction string
+
+const (
+	AuditBalanceFunded       AuditAction = "balance.funded"
+	AuditBalanceTaken        AuditAction = "balance.taken"
+	AuditRoleChanged         AuditAction = "user.roleChanged"
+	AuditUserDeleted         AuditAction = "user.deleted"
+	AuditTournamentStarted   AuditAction = "tournament.started"
+	AuditTournamentFinished  AuditAction = "tournament.finished"
+	AuditTournamentCancelled AuditAction = "tournament.cancelled"
+)
+
+// Valid reports whether action is one of the known actions.
+func (a AuditAction) Valid() bool {
+	switch a {
+	case AuditBalanceFunded, AuditBalanceTaken, AuditRoleChanged, AuditUserDeleted,
+		AuditTournamentStarted, AuditTournamentFinished, AuditTournamentCancelled:
+		return true
+	}
+	return false
+}
+
+// AuditEntry records who performed privileged mutation, what it changed and
+// where request came from. Entries are never updated or deleted.
+// Privileged mutation records entry in its own transaction if its context
+// carries actor and request details added by WithAudit.
+type AuditEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Time      time.Time          `json:"time" bson:"time"`
+	ActorID   string             `json:"actorId" bson:"actorId"`
+	ActorRole Role               `json:"actorRole" bson:"actorRole"`
+	Action    AuditAction        `json:"action" bson:"action"`
+	TargetIDs []string           `json:"targetIds" bson:"targetIds"`
+	// Amount is number of points funded or taken.
+	Amount float64 `json:"amount,omitempty" bson:"amount,omitempty"`
+	// Before and After are snapshots of target without personal data, After
+	// is empty if tournament is deleted.
+	Before    map[string]interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After     map[string]interface{} `json:"after,omitempty" bson:"after,omitempty"`
+	RequestID string                 `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	ClientIP  string                 `json:"clientIp,omitempty" bson:"clientIp,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match any entry.
+type AuditFilter struct {
+	ActorID  string
+	Action   AuditAction
+	TargetID string
+	From     time.Time
+	To       time.Time
+	// BeforeID returns entries older than entry with this id, it's used to read next page.
+	BeforeID primitive.ObjectID
+	Limit    int64
+}
+
+type auditKey struct{}
+
+// WithAudit returns copy of ctx which makes privileged mutation record audit
+// entry with actor and request details of e.
+func WithAudit(ctx context.Context, e AuditEntry) context.Context {
+	return context.WithValue(ctx, auditKey{}, e)
+}
+
+// AuditFromContext returns audit details added to ctx by WithAudit.
+func AuditFromContext(ctx context.Context) (AuditEntry, bool) {
+	e, ok := ctx.Value(auditKey{}).(AuditEntry)
+	return e, ok
+}
+
+// inAuditedTransaction runs fn in transaction like inTransaction and records
+// entry about it if ctx carries audit details. Snapshots of doc with the first
+// target id in collection are read before and after fn in the same
+// transaction, so entry is committed or rolled back together with the change.
+// Mutations nested in fn aren't recorded.
+func (db *DB) inAuditedTransaction(ctx context.Context, e AuditEntry, collection string,
+	fn func(sc context.Context) error) error {
+	details, ok := AuditFromContext(ctx)
+	if !ok {
+		return db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+			return fn(sc)
+		})
+	}
+	ctx = context.WithValue(ctx, auditKey{}, nil)
+
+	return db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		return db.audit(sc, e, details, collection, fn)
+	})
+}
+
+// audit runs fn and records entry with actor and request details.
+func (db *DB) audit(ctx context.Context, e, details AuditEntry, collection string,
+	fn func(sc context.Context) error) error {
+
+	before, err := db.auditSnapshot(ctx, collection, e.TargetIDs[0])
+	if err != nil {
+		return err
+	}
+	if err = fn(ctx); err != nil {
+		return err
+	}
+	after, err := db.auditSnapshot(ctx, collection, e.TargetIDs[0])
+	if err != nil {
+		return err
+	}
+
+	e.ActorID, e.ActorRole = details.ActorID, details.ActorRole
+	e.RequestID, e.ClientIP = details.RequestID, details.ClientIP
+	e.Before, e.After = before, after
+	if err = db.AddAuditEntry(ctx, e); err != nil {
+		return errors.Wrap(err, "AddAuditEntry")
+	}
+
+	return nil
+}
+
+// auditSnapshot returns snapshot of user or tournament with provided id. It
+// holds only ids, balances, role and status, so audit log, which is never
+// scrubbed, keeps no personal data of anonymized users. It returns nil if doc
+// doesn't exist.
+func (db *DB) auditSnapshot(ctx context.Context, collection, id string) (map[string]interface{}, error) {
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	if collection == tournamentsCollectionName {
+		var t Tournament
+		err = db.conn.Collection(collection).FindOne(ctx, bson.M{"_id": primID}).Decode(&t)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "get doc from collection")
+		}
+
+		users := make([]string, 0, len(t.Users))
+		for _, u := range t.Users {
+			users = append(users, u.Hex())
+		}
+		return map[string]interface{}{"id": t.ID.Hex(), "status": string(t.Status), "deposit": t.Deposit,
+			"prize": t.Prize, "users": users, "winner": t.Winner.Hex(), "organizer": t.Organizer.Hex()}, nil
+	}
+
+	var u User
+	err = db.conn.Collection(collection).FindOne(ctx, bson.M{"_id": primID}).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	snapshot := map[string]interface{}{"id": u.ID.Hex(), "balance": u.Balance, "role": string(u.Role)}
+	if u.DeletedAt != nil {
+		snapshot["deletedAt"] = *u.DeletedAt
+	}
+
+	return snapshot, nil
+}
+
+// AddAuditEntry func appends entry to audit log. Entry time is set if it's zero.
+func (db *DB) AddAuditEntry(ctx context.Context, e AuditEntry) (err error) {
+	ctx, span := startSpan(ctx, "AddAuditEntry")
+	defer func() { endSpan(span, err) }()
+
+	e.ID = primitive.ObjectID{}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if _, err = db.conn.Collection(auditCollectionName).InsertOne(ctx, e); err != nil {
+		return errors.Wrap(err, "insert doc to collection")
+	}
+
+	return nil
+}
+
+// ListAuditEntries func returns entries matching filter, newest first.
+func (db *DB) ListAuditEntries(ctx context.Context, f AuditFilter) (_ []AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "ListAuditEntries")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{}
+	if f.ActorID != "" {
+		filter["actorId"] = f.ActorID
+	}
+	if f.Action != "" {
+		filter["action"] = f.Action
+	}
+	if f.TargetID != "" {
+		filter["targetIds"] = f.TargetID
+	}
+	period := bson.M{}
+	if !f.From.IsZero() {
+		period["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		period["$lt"] = f.To
+	}
+	if len(period) > 0 {
+		filter["time"] = period
+	}
+	if !f.BeforeID.IsZero() {
+		filter["_id"] = bson.M{"$lt": f.BeforeID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(f.Limit)
+	cursor, err := db.conn.Collection(auditCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	entries := []AuditEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return entries, nil
+}
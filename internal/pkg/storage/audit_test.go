@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditEntries(t *testing.T) {
+	require := require.New(t)
+	start := time.Now().Add(-time.Minute)
+
+	require.NoError(db.AddAuditEntry(context.TODO(), AuditEntry{ActorID: "admin", ActorRole: RoleAdmin,
+		Action: AuditBalanceFunded, TargetIDs: []string{"u1"}, Amount: 100,
+		Before: map[string]interface{}{"balance": 0.0}, After: map[string]interface{}{"balance": 100.0},
+		RequestID: "req-1", ClientIP: "10.0.0.1"}))
+	require.NoError(db.AddAuditEntry(context.TODO(), AuditEntry{ActorID: "admin", ActorRole: RoleAdmin,
+		Action: AuditUserDeleted, TargetIDs: []string{"u2"}}))
+	require.NoError(db.AddAuditEntry(context.TODO(), AuditEntry{ActorID: "org", ActorRole: RoleOrganizer,
+		Action: AuditTournamentFinished, TargetIDs: []string{"t1", "u1"}}))
+
+	entries, err := db.ListAuditEntries(context.TODO(), AuditFilter{})
+	require.NoError(err)
+	require.Len(entries, 3)
+	require.Equal(AuditTournamentFinished, entries[0].Action, "Newest entry should go first")
+
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{TargetID: "u1", From: start})
+	require.NoError(err)
+	require.Len(entries, 2)
+	require.Equal(100.0, entries[1].After["balance"])
+	require.Equal("10.0.0.1", entries[1].ClientIP)
+
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{ActorID: "admin", Limit: 1})
+	require.NoError(err)
+	require.Len(entries, 1)
+	require.Equal(AuditUserDeleted, entries[0].Action)
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{ActorID: "admin", BeforeID: entries[0].ID})
+	require.NoError(err)
+	require.Len(entries, 1)
+	require.Equal(AuditBalanceFunded, entries[0].Action, "Next page should continue after the last entry")
+
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{Action: AuditBalanceTaken})
+	require.NoError(err)
+	require.Empty(entries)
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{To: start})
+	require.NoError(err)
+	require.Empty(entries)
+
+	cleanUp(t)
+}
+
+func TestAuditedMutation(t *testing.T) {
+	require := require.New(t)
+	ctx := WithAudit(context.TODO(), AuditEntry{ActorID: "admin", ActorRole: RoleAdmin,
+		RequestID: "req-1", ClientIP: "10.0.0.1"})
+
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	require.NoError(db.FundUserBalance(context.TODO(), userID, 10))
+	require.NoError(db.FundUserBalance(ctx, userID, 100))
+	require.Error(db.TakeUserBalance(ctx, primitive.NewObjectID().Hex(), 10))
+
+	entries, err := db.ListAuditEntries(context.TODO(), AuditFilter{})
+	require.NoError(err)
+	require.Len(entries, 1, "Only successful mutation with audit details should be recorded")
+	e := entries[0]
+	require.Equal(AuditBalanceFunded, e.Action)
+	require.Equal("admin", e.ActorID)
+	require.Equal([]string{userID}, e.TargetIDs)
+	require.Equal(100.0, e.Amount)
+	require.Equal("req-1", e.RequestID)
+	require.Equal(10.0, e.Before["balance"])
+	require.Equal(110.0, e.After["balance"])
+	require.NotContains(e.After, "name", "Snapshot should not contain personal data")
+
+	tournamentID, err := db.AddTournament(context.TODO(), "Cup", 100, userID)
+	require.NoError(err)
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.SetTournamentStatus(ctx, tournamentID, StatusStarted))
+	require.NoError(db.FinishTournament(ctx, tournamentID, userID))
+
+	entries, err = db.ListAuditEntries(context.TODO(), AuditFilter{})
+	require.NoError(err)
+	require.Len(entries, 3, "Mutations nested in finish should not be recorded")
+	require.Equal(AuditTournamentFinished, entries[0].Action)
+	require.Equal([]string{tournamentID, userID}, entries[0].TargetIDs)
+	require.Equal(string(StatusStarted), entries[0].Before["status"])
+	require.Equal(string(StatusFinished), entries[0].After["status"])
+	require.Equal(AuditTournamentStarted, entries[1].Action)
+
+	cleanUp(t)
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrLoginTaken is returned when credentials with the same login already exist.
+var ErrLoginTaken = errors.New("login is already taken")
+
+// Credentials holds user login and password hash. They are stored
+// separately from users collection so user info never exposes secrets.
+// Login is used as document id which makes it unique.
+type Credentials struct {
+	Login        string             `bson:"_id"`
+	UserID       primitive.ObjectID `bson:"user_id"`
+	PasswordHash []byte             `bson:"password_hash"`
+}
+
+// Session represents issued refresh token. Only hash of token is stored.
+type Session struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash string             `bson:"token_hash"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	Revoked   bool               `bson:"revoked"`
+}
+
+// AddCredentials func saves credentials for user with provided id.
+// It returns ErrLoginTaken if login is already in use.
+func (db *DB) AddCredentials(ctx context.Context, userID, login string, passwordHash []byte) (err error) {
+	ctx, span := startSpan(ctx, "AddCredentials")
+	defer func() { endSpan(span, err) }()
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	_, err = db.conn.Collection(credentialsCollectionName).InsertOne(ctx, Credentials{
+		Login:        login,
+		UserID:       primUserID,
+		PasswordHash: passwordHash,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLoginTaken
+	}
+	if err != nil {
+		return errors.Wrap(err, "insert doc to collection")
+	}
+
+	return nil
+}
+
+// GetCredentialsByLogin func tries to find credentials with provided login.
+func (db *DB) GetCredentialsByLogin(ctx context.Context, login string) (_ *Credentials, err error) {
+	ctx, span := startSpan(ctx, "GetCredentialsByLogin")
+	defer func() { endSpan(span, err) }()
+
+	return db.findCredentials(ctx, bson.M{"_id": login})
+}
+
+// GetCredentialsByUserID func tries to find credentials of user with provided id.
+func (db *DB) GetCredentialsByUserID(ctx context.Context, userID string) (_ *Credentials, err error) {
+	ctx, span := startSpan(ctx, "GetCredentialsByUserID")
+	defer func() { endSpan(span, err) }()
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	return db.findCredentials(ctx, bson.M{"user_id": primUserID})
+}
+
+func (db *DB) findCredentials(ctx context.Context, filter bson.M) (*Credentials, error) {
+	docReturned := db.conn.Collection(credentialsCollectionName).FindOne(ctx, filter)
+	if err := docReturned.Err(); err != nil {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	var creds Credentials
+	if err := docReturned.Decode(&creds); err != nil {
+		return nil, errors.Wrap(err, "decode returned doc")
+	}
+
+	return &creds, nil
+}
+
+// SetPasswordHash func replaces password hash of user with provided id.
+func (db *DB) SetPasswordHash(ctx context.Context, userID string, passwordHash []byte) (err error) {
+	ctx, span := startSpan(ctx, "SetPasswordHash")
+	defer func() { endSpan(span, err) }()
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"password_hash", passwordHash},
+		}},
+	}
+	updateResult, err := db.conn.Collection(credentialsCollectionName).UpdateOne(ctx,
+		bson.M{"user_id": primUserID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// AddSession func saves refresh token hash for user with provided id.
+func (db *DB) AddSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "AddSession")
+	defer func() { endSpan(span, err) }()
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	_, err = db.conn.Collection(sessionsCollectionName).InsertOne(ctx, Session{
+		TokenHash: tokenHash,
+		UserID:    primUserID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "insert doc to collection")
+	}
+
+	return nil
+}
+
+// GetSession func tries to find session by refresh token hash.
+func (db *DB) GetSession(ctx context.Context, tokenHash string) (_ *Session, err error) {
+	ctx, span := startSpan(ctx, "GetSession")
+	defer func() { endSpan(span, err) }()
+
+	docReturned := db.conn.Collection(sessionsCollectionName).FindOne(ctx, bson.M{"token_hash": tokenHash})
+	if err := docReturned.Err(); err != nil {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	var session Session
+	if err := docReturned.Decode(&session); err != nil {
+		return nil, errors.Wrap(err, "decode returned doc")
+	}
+
+	return &session, nil
+}
+
+// RevokeSession func marks session with provided refresh token hash as revoked.
+func (db *DB) RevokeSession(ctx context.Context, tokenHash string) (err error) {
+	ctx, span := startSpan(ctx, "RevokeSession")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"revoked", true},
+		}},
+	}
+	updateResult, err := db.conn.Collection(sessionsCollectionName).UpdateOne(ctx,
+		bson.M{"token_hash": tokenHash, "revoked": false}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.ModifiedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// RevokeUserSessions func marks all sessions of user with provided id as revoked.
+func (db *DB) RevokeUserSessions(ctx context.Context, userID string) (err error) {
+	ctx, span := startSpan(ctx, "RevokeUserSessions")
+	defer func() { endSpan(span, err) }()
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"revoked", true},
+		}},
+	}
+	_, err = db.conn.Collection(sessionsCollectionName).UpdateMany(ctx,
+		bson.M{"user_id": primUserID, "revoked": false}, update)
+	if err != nil {
+		return errors.Wrap(err, "update docs in collection")
+	}
+
+	return nil
+}
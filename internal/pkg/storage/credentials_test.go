@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterUser(t *testing.T) {
+	userID, err := db.RegisterUser(context.TODO(), "Vasya", "vasya", []byte("hash"))
+	require := require.New(t)
+	require.NoError(err)
+
+	actualUser, err := db.GetUser(context.TODO(), userID)
+	require.NoError(err)
+	require.Equal("Vasya", actualUser.Name, "The two names should be the same")
+	require.Equal(RolePlayer, actualUser.Role, "The two roles should be the same")
+
+	creds, err := db.GetCredentialsByLogin(context.TODO(), "vasya")
+	require.NoError(err)
+	require.Equal(userID, creds.UserID.Hex(), "The two IDs should be the same")
+	require.Equal([]byte("hash"), creds.PasswordHash, "The two hashes should be the same")
+
+	_, err = db.RegisterUser(context.TODO(), "Other Vasya", "vasya", []byte("hash"))
+	require.Equal(ErrLoginTaken, err, "The two errors should be the same")
+
+	err = db.SetPasswordHash(context.TODO(), userID, []byte("new hash"))
+	require.NoError(err)
+
+	creds, err = db.GetCredentialsByUserID(context.TODO(), userID)
+	require.NoError(err)
+	require.Equal([]byte("new hash"), creds.PasswordHash, "The two hashes should be the same")
+
+	cleanUp(t)
+}
+
+func TestSessions(t *testing.T) {
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require := require.New(t)
+	require.NoError(err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	err = db.AddSession(context.TODO(), userID, "hash-1", expiresAt)
+	require.NoError(err)
+	err = db.AddSession(context.TODO(), userID, "hash-2", expiresAt)
+	require.NoError(err)
+
+	session, err := db.GetSession(context.TODO(), "hash-1")
+	require.NoError(err)
+	require.Equal(userID, session.UserID.Hex(), "The two IDs should be the same")
+	require.Equal(expiresAt, session.ExpiresAt, "The two expiration times should be the same")
+	require.False(session.Revoked, "Session should not be revoked")
+
+	err = db.RevokeSession(context.TODO(), "hash-1")
+	require.NoError(err)
+	err = db.RevokeSession(context.TODO(), "hash-1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
+
+	err = db.RevokeUserSessions(context.TODO(), userID)
+	require.NoError(err)
+
+	session, err = db.GetSession(context.TODO(), "hash-2")
+	require.NoError(err)
+	require.True(session.Revoked, "Session should be revoked")
+
+	cleanUp(t)
+}
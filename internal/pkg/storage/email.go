@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EmailVerification is pending confirmation of user email address.
+// Only hash of token sent to the address is stored.
+type EmailVerification struct {
+	TokenHash string    `bson:"tokenHash"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// Email is single message queued for sending. Status and attempts
+// follow the same rules as webhook deliveries.
+type Email struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"userId" bson:"userId"`
+	// EventID is outbox event email is sent about. It's zero for verification emails.
+	EventID       primitive.ObjectID `json:"eventId,omitempty" bson:"eventId,omitempty"`
+	Template      string             `json:"template" bson:"template"`
+	To            string             `json:"to" bson:"to"`
+	Subject       string             `json:"subject" bson:"subject"`
+	Body          string             `json:"-" bson:"body"`
+	Status        DeliveryStatus     `json:"status" bson:"status"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LastError     string             `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	SentAt        *time.Time         `json:"sentAt,omitempty" bson:"sentAt,omitempty"`
+}
+
+// SetUserEmail func replaces email of user with provided id string with
+// unverified address and starts its verification.
+func (db *DB) SetUserEmail(ctx context.Context, userID, email string, v EmailVerification) (err error) {
+	ctx, span := startSpan(ctx, "SetUserEmail")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"email", email},
+			{"emailVerification", v},
+		}},
+		{"$unset", bson.D{
+			{"emailVerified", ""},
+		}},
+	}
+	updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(ctx, bson.M{"_id": primID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// VerifyUserEmail func marks email of user with provided id string as verified
+// if token hash matches pending verification which isn't expired at provided time.
+// It returns wrapped mongo.ErrNoDocuments otherwise.
+func (db *DB) VerifyUserEmail(ctx context.Context, userID, tokenHash string, now time.Time) (err error) {
+	ctx, span := startSpan(ctx, "VerifyUserEmail")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	filter := bson.M{
+		"_id":                         primID,
+		"emailVerification.tokenHash": tokenHash,
+		"emailVerification.expiresAt": bson.M{"$gt": now},
+	}
+	update := bson.D{
+		{"$set", bson.D{
+			{"emailVerified", true},
+		}},
+		{"$unset", bson.D{
+			{"emailVerification", ""},
+		}},
+	}
+	updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// SetUserEmailOptOut func replaces kinds of notifications user with
+// provided id string doesn't want to get by email.
+func (db *DB) SetUserEmailOptOut(ctx context.Context, userID string, kinds []NotificationKind) (err error) {
+	ctx, span := startSpan(ctx, "SetUserEmailOptOut")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"emailOptOut", kinds},
+		}},
+	}
+	updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(ctx, bson.M{"_id": primID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// AddEmail func queues email for sending. Email about outbox event which is
+// already queued for the same user is left as is, so event handled more
+// than once is emailed once.
+func (db *DB) AddEmail(ctx context.Context, e Email) (err error) {
+	ctx, span := startSpan(ctx, "AddEmail")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now().UTC()
+	e.ID = primitive.ObjectID{}
+	e.Status, e.Attempts = DeliveryPending, 0
+	e.NextAttemptAt, e.CreatedAt = now, now
+	if e.EventID.IsZero() {
+		if _, err = db.conn.Collection(emailsCollectionName).InsertOne(ctx, e); err != nil {
+			return errors.Wrap(err, "insert doc to collection")
+		}
+		return nil
+	}
+
+	filter := bson.M{"userId": e.UserID, "eventId": e.EventID, "template": e.Template}
+	_, err = db.conn.Collection(emailsCollectionName).UpdateOne(ctx, filter, bson.M{"$setOnInsert": e},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.Wrap(err, "upsert doc in collection")
+	}
+
+	return nil
+}
+
+// DueEmails func returns up to limit pending emails which next
+// attempt is due at provided time, oldest first.
+func (db *DB) DueEmails(ctx context.Context, now time.Time, limit int64) (_ []Email, err error) {
+	ctx, span := startSpan(ctx, "DueEmails")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{"status": DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{"nextAttemptAt", 1}, {"_id", 1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(emailsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	var due []Email
+	if err = cursor.All(ctx, &due); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return due, nil
+}
+
+// UpdateEmail func saves status and result of the last attempt of sending email.
+func (db *DB) UpdateEmail(ctx context.Context, e *Email) (err error) {
+	ctx, span := startSpan(ctx, "UpdateEmail")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", e.Status},
+			{"attempts", e.Attempts},
+			{"nextAttemptAt", e.NextAttemptAt},
+			{"lastError", e.LastError},
+			{"sentAt", e.SentAt},
+		}},
+	}
+	updateResult, err := db.conn.Collection(emailsCollectionName).UpdateOne(ctx, bson.M{"_id": e.ID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUserEmail(t *testing.T) {
+	require := require.New(t)
+	userID, err := db.AddUser(context.TODO(), "Gena")
+	require.NoError(err)
+
+	now := time.Now()
+	require.NoError(db.SetUserEmail(context.TODO(), userID, "gena@example.com",
+		EmailVerification{TokenHash: "hash", ExpiresAt: now.Add(time.Hour)}))
+	user, err := db.GetUser(context.TODO(), userID)
+	require.NoError(err)
+	require.Equal("gena@example.com", user.Email)
+	require.False(user.EmailVerified)
+	require.NotNil(user.Verification)
+
+	err = db.VerifyUserEmail(context.TODO(), userID, "other", now)
+	require.True(errors.Is(err, mongo.ErrNoDocuments), "Wrong token should not verify email")
+	err = db.VerifyUserEmail(context.TODO(), userID, "hash", now.Add(2*time.Hour))
+	require.True(errors.Is(err, mongo.ErrNoDocuments), "Expired token should not verify email")
+	require.NoError(db.VerifyUserEmail(context.TODO(), userID, "hash", now))
+	err = db.VerifyUserEmail(context.TODO(), userID, "hash", now)
+	require.True(errors.Is(err, mongo.ErrNoDocuments), "Token should be used once")
+
+	require.NoError(db.SetUserEmailOptOut(context.TODO(), userID, []NotificationKind{NotificationTournamentStarted}))
+	user, err = db.GetUser(context.TODO(), userID)
+	require.NoError(err)
+	require.True(user.EmailVerified)
+	require.Nil(user.Verification)
+	require.Equal([]NotificationKind{NotificationTournamentStarted}, user.EmailOptOut)
+
+	err = db.SetUserEmailOptOut(context.TODO(), primitive.NewObjectID().Hex(), nil)
+	require.True(errors.Is(err, mongo.ErrNoDocuments))
+
+	cleanUp(t)
+}
+
+func TestEmails(t *testing.T) {
+	require := require.New(t)
+	eventID := primitive.NewObjectID()
+
+	won := Email{UserID: "u1", EventID: eventID, Template: "tournamentWon", To: "u1@example.com", Subject: "You won"}
+	require.NoError(db.AddEmail(context.TODO(), won))
+	require.NoError(db.AddEmail(context.TODO(), won), "Email about the same event should be queued once")
+	require.NoError(db.AddEmail(context.TODO(), Email{UserID: "u1", Template: "verification", To: "u1@example.com"}))
+
+	due, err := db.DueEmails(context.TODO(), time.Now(), 10)
+	require.NoError(err)
+	require.Len(due, 2)
+	require.Equal(DeliveryPending, due[0].Status)
+
+	sentAt := time.Now()
+	due[0].Status, due[0].Attempts, due[0].SentAt = DeliveryDelivered, 1, &sentAt
+	require.NoError(db.UpdateEmail(context.TODO(), &due[0]))
+	due[1].Attempts, due[1].LastError, due[1].NextAttemptAt = 1, "connection refused", time.Now().Add(time.Hour)
+	require.NoError(db.UpdateEmail(context.TODO(), &due[1]))
+
+	due, err = db.DueEmails(context.TODO(), time.Now(), 10)
+	require.NoError(err)
+	require.Empty(due, "Sent and rescheduled emails should not be due")
+
+	cleanUp(t)
+}
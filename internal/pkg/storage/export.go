@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportsBucketName is GridFS bucket archives of personal data are stored in.
+const exportsBucketName = "exportArchives"
+
+// ExportStatus is state of personal data export.
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// Export is request of user for archive of his personal data. Archive
+// is built in background and is kept in GridFS under export id.
+type Export struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      string             `json:"userId" bson:"userId"`
+	Status      ExportStatus       `json:"status" bson:"status"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	// ExpiresAt is set once export is completed, then archive is removed.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	LastError string     `json:"-" bson:"lastError,omitempty"`
+}
+
+// RequestExport func returns pending or ready and not expired export of user
+// with provided id. New pending export is added if there is none.
+func (db *DB) RequestExport(ctx context.Context, userID string, now time.Time) (_ *Export, err error) {
+	ctx, span := startSpan(ctx, "RequestExport")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{
+		"userId": userID,
+		"$or": bson.A{
+			bson.M{"status": ExportPending},
+			bson.M{"status": ExportReady, "expiresAt": bson.M{"$gt": now}},
+		},
+	}
+	var export Export
+	err = db.conn.Collection(exportsCollectionName).FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{"_id", -1}})).Decode(&export)
+	if err == nil {
+		return &export, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	// unique index keeps concurrent request from queueing second pending
+	// export, it gets duplicate key error reported as already existing
+	export = Export{UserID: userID, Status: ExportPending, CreatedAt: now.UTC()}
+	insertResult, err := db.conn.Collection(exportsCollectionName).InsertOne(ctx, export)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert doc to collection")
+	}
+
+	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("convert inserted id to primitive.ObjectID")
+	}
+	export.ID = insertedID
+
+	return &export, nil
+}
+
+// GetExport func returns export with provided id if it belongs to user with provided id.
+func (db *DB) GetExport(ctx context.Context, userID, id string) (_ *Export, err error) {
+	ctx, span := startSpan(ctx, "GetExport")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	var export Export
+	err = db.conn.Collection(exportsCollectionName).FindOne(ctx,
+		bson.M{"_id": primID, "userId": userID}).Decode(&export)
+	if err != nil {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	return &export, nil
+}
+
+// PendingExports func returns up to limit pending exports, oldest first.
+func (db *DB) PendingExports(ctx context.Context, limit int64) (_ []Export, err error) {
+	ctx, span := startSpan(ctx, "PendingExports")
+	defer func() { endSpan(span, err) }()
+
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(exportsCollectionName).Find(ctx, bson.M{"status": ExportPending}, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	exports := []Export{}
+	if err = cursor.All(ctx, &exports); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return exports, nil
+}
+
+// UpdateExport func saves status of export after it's built.
+func (db *DB) UpdateExport(ctx context.Context, e *Export) (err error) {
+	ctx, span := startSpan(ctx, "UpdateExport")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", e.Status},
+			{"completedAt", e.CompletedAt},
+			{"expiresAt", e.ExpiresAt},
+			{"lastError", e.LastError},
+		}},
+	}
+	updateResult, err := db.conn.Collection(exportsCollectionName).UpdateOne(ctx, bson.M{"_id": e.ID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// DeleteExpiredExports func removes completed exports which expired
+// before provided time together with their archives.
+func (db *DB) DeleteExpiredExports(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteExpiredExports")
+	defer func() { endSpan(span, err) }()
+
+	cursor, err := db.conn.Collection(exportsCollectionName).Find(ctx, bson.M{"expiresAt": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	var exports []Export
+	if err = cursor.All(ctx, &exports); err != nil {
+		return 0, errors.Wrap(err, "decode returned docs")
+	}
+
+	bucket, err := db.exportsBucket()
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, e := range exports {
+		err = bucket.DeleteContext(ctx, e.ID)
+		if err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return deleted, errors.Wrapf(err, "delete archive %s", e.ID.Hex())
+		}
+		if _, err = db.conn.Collection(exportsCollectionName).DeleteOne(ctx, bson.M{"_id": e.ID}); err != nil {
+			return deleted, errors.Wrap(err, "delete doc from collection")
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// SaveExportArchive func stores archive read from r under export id.
+// Archive left by interrupted attempt is replaced.
+func (db *DB) SaveExportArchive(ctx context.Context, id primitive.ObjectID, r io.Reader) (err error) {
+	ctx, span := startSpan(ctx, "SaveExportArchive")
+	defer func() { endSpan(span, err) }()
+
+	bucket, err := db.exportsBucket()
+	if err != nil {
+		return err
+	}
+	if err = bucket.DeleteContext(ctx, id); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return errors.Wrap(err, "delete previous archive")
+	}
+	// GridFS of this driver version takes deadlines instead of context
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = bucket.SetWriteDeadline(deadline); err != nil {
+			return errors.Wrap(err, "set write deadline")
+		}
+	}
+	if err = bucket.UploadFromStreamWithID(id, id.Hex()+".zip", r); err != nil {
+		return errors.Wrap(err, "upload archive")
+	}
+
+	return nil
+}
+
+// OpenExportArchive func returns reader of archive stored under export id.
+func (db *DB) OpenExportArchive(ctx context.Context, id primitive.ObjectID) (_ io.ReadCloser, err error) {
+	_, span := startSpan(ctx, "OpenExportArchive")
+	defer func() { endSpan(span, err) }()
+
+	bucket, err := db.exportsBucket()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := bucket.OpenDownloadStream(id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, errors.Wrap(mongo.ErrNoDocuments, "open archive")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "open archive")
+	}
+
+	return stream, nil
+}
+
+// ListUserTournaments func returns tournaments user with provided id
+// joined, won or organized, oldest first.
+func (db *DB) ListUserTournaments(ctx context.Context, userID string) (_ []Tournament, err error) {
+	ctx, span := startSpan(ctx, "ListUserTournaments")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"users": primID},
+		bson.M{"winner": primID},
+		bson.M{"organizer": primID},
+	}}
+	opts := options.Find().SetSort(bson.D{{"_id", 1}})
+	cursor, err := db.conn.Collection(tournamentsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	tournaments := []Tournament{}
+	if err = cursor.All(ctx, &tournaments); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return tournaments, nil
+}
+
+// deleteUserExports removes all exports of user with provided id together
+// with their archives. Files and chunks of archives are removed directly,
+// so that removal joins transaction carried by ctx.
+func (db *DB) deleteUserExports(ctx context.Context, userID string) error {
+	cursor, err := db.conn.Collection(exportsCollectionName).Find(ctx, bson.M{"userId": userID},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return errors.Wrap(err, "find docs in collection")
+	}
+	var exports []Export
+	if err = cursor.All(ctx, &exports); err != nil {
+		return errors.Wrap(err, "decode returned docs")
+	}
+	if len(exports) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(exports))
+	for _, e := range exports {
+		ids = append(ids, e.ID)
+	}
+	_, err = db.conn.Collection(exportsBucketName+".chunks").DeleteMany(ctx, bson.M{"files_id": bson.M{"$in": ids}})
+	if err != nil {
+		return errors.Wrap(err, "delete archive chunks")
+	}
+	_, err = db.conn.Collection(exportsBucketName+".files").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return errors.Wrap(err, "delete archive files")
+	}
+	if _, err = db.conn.Collection(exportsCollectionName).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return errors.Wrap(err, "delete docs from collection")
+	}
+
+	return nil
+}
+
+func (db *DB) exportsBucket() (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(db.conn, options.GridFSBucket().SetName(exportsBucketName))
+	if err != nil {
+		return nil, errors.Wrap(err, "create gridfs bucket")
+	}
+
+	return bucket, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestExports(t *testing.T) {
+	require := require.New(t)
+	userID := primitive.NewObjectID().Hex()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	e, err := db.RequestExport(context.TODO(), userID, now)
+	require.NoError(err)
+	require.Equal(ExportPending, e.Status)
+	again, err := db.RequestExport(context.TODO(), userID, now)
+	require.NoError(err)
+	require.Equal(e.ID, again.ID, "Pending export should be reused")
+
+	pending, err := db.PendingExports(context.TODO(), 10)
+	require.NoError(err)
+	require.Len(pending, 1)
+
+	require.NoError(db.SaveExportArchive(context.TODO(), e.ID, strings.NewReader("first")))
+	require.NoError(db.SaveExportArchive(context.TODO(), e.ID, strings.NewReader("zip")),
+		"Archive of interrupted attempt should be replaced")
+	expiresAt := now.Add(time.Hour)
+	e.Status, e.CompletedAt, e.ExpiresAt = ExportReady, &now, &expiresAt
+	require.NoError(db.UpdateExport(context.TODO(), e))
+
+	got, err := db.GetExport(context.TODO(), userID, e.ID.Hex())
+	require.NoError(err)
+	require.Equal(ExportReady, got.Status)
+	require.Equal(expiresAt, *got.ExpiresAt)
+	_, err = db.GetExport(context.TODO(), primitive.NewObjectID().Hex(), e.ID.Hex())
+	require.ErrorIs(err, mongo.ErrNoDocuments, "Export should be visible only to its user")
+
+	archive, err := db.OpenExportArchive(context.TODO(), e.ID)
+	require.NoError(err)
+	data, err := io.ReadAll(archive)
+	require.NoError(err)
+	require.NoError(archive.Close())
+	require.Equal("zip", string(data))
+
+	again, err = db.RequestExport(context.TODO(), userID, now)
+	require.NoError(err)
+	require.Equal(e.ID, again.ID, "Ready export should be reused until it expires")
+	again, err = db.RequestExport(context.TODO(), userID, expiresAt)
+	require.NoError(err)
+	require.NotEqual(e.ID, again.ID)
+
+	deleted, err := db.DeleteExpiredExports(context.TODO(), expiresAt.Add(time.Second))
+	require.NoError(err)
+	require.Equal(int64(1), deleted)
+	_, err = db.OpenExportArchive(context.TODO(), e.ID)
+	require.ErrorIs(err, mongo.ErrNoDocuments)
+
+	cleanUp(t)
+}
+
+func TestListUserTournaments(t *testing.T) {
+	require := require.New(t)
+	userID := primitive.NewObjectID().Hex()
+
+	joinedID, err := db.AddTournament(context.TODO(), "Cup", 10, primitive.NewObjectID().Hex())
+	require.NoError(err)
+	require.NoError(db.AddUserToTournamentList(context.TODO(), joinedID, userID))
+	_, err = db.AddTournament(context.TODO(), "League", 10, userID)
+	require.NoError(err)
+	_, err = db.AddTournament(context.TODO(), "Other", 10, primitive.NewObjectID().Hex())
+	require.NoError(err)
+
+	tournaments, err := db.ListUserTournaments(context.TODO(), userID)
+	require.NoError(err)
+	require.Len(tournaments, 2, "Joined and organized tournaments should be listed")
+	require.Equal("Cup", tournaments[0].Name)
+
+	cleanUp(t)
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Ping func checks that primary of MongoDB deployment is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	return errors.Wrap(db.conn.Client().Ping(ctx, readpref.Primary()), "ping primary")
+}
+
+// CheckTransactions func checks that deployment supports multi-document
+// transactions which JoinTournament, LeaveTournament, FinishTournament,
+// RegisterUser and outbox writes rely on. Standalone server doesn't support
+// them, so deployment must be a replica set or a sharded cluster.
+func (db *DB) CheckTransactions(ctx context.Context) error {
+	var hello struct {
+		SetName                      string `bson:"setName"`
+		Msg                          string `bson:"msg"`
+		LogicalSessionTimeoutMinutes *int64 `bson:"logicalSessionTimeoutMinutes"`
+	}
+	if err := db.conn.RunCommand(ctx, bson.D{{"hello", 1}}).Decode(&hello); err != nil {
+		return errors.Wrap(err, "run hello command")
+	}
+
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return errors.New("deployment is neither replica set nor sharded cluster")
+	}
+	if hello.LogicalSessionTimeoutMinutes == nil {
+		return errors.New("deployment doesn't support sessions")
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecks(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(db.Ping(context.TODO()))
+	require.NoError(db.CheckTransactions(context.TODO()), "Test container runs single node replica set")
+}
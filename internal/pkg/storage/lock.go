@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AcquireLock func takes lease with provided name for owner until ttl passes,
+// or extends it if owner already holds it. It returns false if lease is held
+// by other owner. Lease lets only one of service instances do background work.
+func (db *DB) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "AcquireLock")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.D{
+		{"$set", bson.D{
+			{"owner", owner},
+			{"expiresAt", now.Add(ttl)},
+		}},
+	}
+	// upsert conflicts on _id if lease exists and is held by other owner
+	_, err = db.conn.Collection(locksCollectionName).UpdateOne(ctx, filter, update,
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "upsert doc in collection")
+	}
+
+	return true, nil
+}
+
+// ReleaseLock func gives up lease with provided name if owner holds it.
+func (db *DB) ReleaseLock(ctx context.Context, name, owner string) (err error) {
+	ctx, span := startSpan(ctx, "ReleaseLock")
+	defer func() { endSpan(span, err) }()
+
+	_, err = db.conn.Collection(locksCollectionName).DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	if err != nil {
+		return errors.Wrap(err, "delete doc from collection")
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration changes indexes or shape of documents. Migrations are applied
+// once in order of their versions and must not be changed after release.
+// Up must be idempotent: migration is recorded after Up succeeds, so Up
+// is run again if instance stops in between.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, conn *mongo.Database) error
+}
+
+// AppliedMigration is record of migration applied to database.
+type AppliedMigration struct {
+	Version     int       `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"appliedAt" bson:"appliedAt"`
+}
+
+// Migrations lists schema migrations ordered by version. New migration
+// is appended with next version.
+var Migrations = []Migration{
+	{Version: 1, Description: "index users by name", Up: createIndex(usersCollectionName, "name")},
+	{Version: 2, Description: "index tournaments by status", Up: createIndex(tournamentsCollectionName, "status")},
+	{Version: 3, Description: "index tournaments by users", Up: createIndex(tournamentsCollectionName, "users")},
+	{Version: 4, Description: "set signIn status of tournaments without status", Up: backfillTournamentStatus},
+	{Version: 5, Description: "index outbox by sequence", Up: createIndex(outboxCollectionName, "seq")},
+	{Version: 6, Description: "index emails by status and next attempt",
+		Up: createIndex(emailsCollectionName, "status", "nextAttemptAt")},
+	{Version: 7, Description: "index notifications by user", Up: createIndex(notificationsCollectionName, "userId")},
+	{Version: 8, Description: "index audit by targets", Up: createIndex(auditCollectionName, "targetIds")},
+	{Version: 9, Description: "index exports by user", Up: createIndex(exportsCollectionName, "userId")},
+	{Version: 10, Description: "index exports by status", Up: createIndex(exportsCollectionName, "status")},
+	{Version: 11, Description: "index tournaments by status and start",
+		Up: createIndex(tournamentsCollectionName, "status", "startsAt")},
+	{Version: 12, Description: "index sessions by token hash uniquely",
+		Up: createIndexWith(sessionsCollectionName, options.Index().SetUnique(true), "token_hash")},
+	{Version: 13, Description: "index sessions by user", Up: createIndex(sessionsCollectionName, "user_id")},
+	{Version: 14, Description: "index credentials by user", Up: createIndex(credentialsCollectionName, "user_id")},
+	{Version: 15, Description: "allow single pending export per user", Up: uniquePendingExports},
+}
+
+// backfillTournamentStatus sets signIn status of tournaments stored before
+// status was introduced, which have no or empty status.
+func backfillTournamentStatus(ctx context.Context, conn *mongo.Database) error {
+	_, err := conn.Collection(tournamentsCollectionName).UpdateMany(ctx,
+		bson.M{"status": bson.M{"$in": bson.A{"", nil}}},
+		bson.D{{"$set", bson.D{{"status", StatusSignIn}}}})
+	if err != nil {
+		return errors.Wrap(err, "update docs in collection")
+	}
+
+	return nil
+}
+
+// uniquePendingExports fails all but the newest pending export of every
+// user, then creates index which doesn't allow user to have two of them.
+func uniquePendingExports(ctx context.Context, conn *mongo.Database) error {
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"status", ExportPending}}}},
+		{{"$sort", bson.D{{"_id", -1}}}},
+		{{"$group", bson.D{
+			{"_id", "$userId"},
+			{"ids", bson.D{{"$push", "$_id"}}},
+		}}},
+	}
+	cursor, err := conn.Collection(exportsCollectionName).Aggregate(ctx, pipeline)
+	if err != nil {
+		return errors.Wrap(err, "aggregate docs in collection")
+	}
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return errors.Wrap(err, "decode returned docs")
+	}
+
+	for _, g := range groups {
+		if len(g.IDs) < 2 {
+			continue
+		}
+		_, err = conn.Collection(exportsCollectionName).UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": g.IDs[1:]}},
+			bson.D{{"$set", bson.D{{"status", ExportFailed}, {"lastError", "superseded by newer export"}}}})
+		if err != nil {
+			return errors.Wrap(err, "update docs in collection")
+		}
+	}
+
+	opts := options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": ExportPending})
+	return createIndexWith(exportsCollectionName, opts, "userId", "status")(ctx, conn)
+}
+
+// createIndex returns migration step creating ascending index on fields
+// in provided order. Creating index which already exists succeeds.
+func createIndex(collection string, fields ...string) func(context.Context, *mongo.Database) error {
+	return createIndexWith(collection, nil, fields...)
+}
+
+// createIndexWith is createIndex creating index with provided options, e.g. unique one.
+func createIndexWith(collection string, opts *options.IndexOptions,
+	fields ...string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, conn *mongo.Database) error {
+		keys := make(bson.D, 0, len(fields))
+		for _, field := range fields {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+		_, err := conn.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+		if err != nil {
+			return errors.Wrapf(err, "create index on %s.%s", collection, strings.Join(fields, ","))
+		}
+
+		return nil
+	}
+}
+
+// AppliedMigrations func returns migrations applied to database ordered by version.
+func (db *DB) AppliedMigrations(ctx context.Context) (_ []AppliedMigration, err error) {
+	ctx, span := startSpan(ctx, "AppliedMigrations")
+	defer func() { endSpan(span, err) }()
+
+	opts := options.Find().SetSort(bson.D{{"_id", 1}})
+	cursor, err := db.conn.Collection(migrationsCollectionName).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	applied := []AppliedMigration{}
+	if err = cursor.All(ctx, &applied); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return applied, nil
+}
+
+// ApplyMigration func runs migration and records it as applied. Migration
+// recorded concurrently by other instance is not an error.
+func (db *DB) ApplyMigration(ctx context.Context, m Migration) (err error) {
+	ctx, span := startSpan(ctx, "ApplyMigration")
+	defer func() { endSpan(span, err) }()
+
+	if err = m.Up(ctx, db.conn); err != nil {
+		return err
+	}
+
+	_, err = db.conn.Collection(migrationsCollectionName).InsertOne(ctx, AppliedMigration{
+		Version:     m.Version,
+		Description: m.Description,
+		AppliedAt:   time.Now().UTC(),
+	})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return errors.Wrap(err, "insert doc to collection")
+	}
+
+	return nil
+}
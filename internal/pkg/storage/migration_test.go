@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestApplyMigration(t *testing.T) {
+	require := require.New(t)
+	// tournaments stored before status was introduced
+	_, err := tournaments.InsertMany(context.TODO(), []interface{}{
+		bson.M{"name": "without status"},
+		bson.M{"name": "empty status", "status": ""},
+	})
+	require.NoError(err)
+	// duplicate pending exports queued before they were made unique
+	_, err = exports.InsertMany(context.TODO(), []interface{}{
+		Export{UserID: "u1", Status: ExportPending},
+		Export{UserID: "u1", Status: ExportPending},
+	})
+	require.NoError(err)
+
+	for _, m := range Migrations {
+		require.NoError(db.ApplyMigration(context.TODO(), m))
+	}
+	require.NoError(db.ApplyMigration(context.TODO(), Migrations[0]), "Applied migration should be idempotent")
+
+	applied, err := db.AppliedMigrations(context.TODO())
+	require.NoError(err)
+	require.Len(applied, len(Migrations))
+	for i, a := range applied {
+		require.Equal(Migrations[i].Version, a.Version)
+		require.Equal(Migrations[i].Description, a.Description)
+	}
+
+	require.Contains(indexNames(t, users), "name_1")
+	require.Contains(indexNames(t, tournaments), "status_1")
+	require.Contains(indexNames(t, tournaments), "users_1")
+	require.Contains(indexNames(t, outbox), "seq_1")
+	require.Contains(indexNames(t, emails), "status_1_nextAttemptAt_1")
+	require.Contains(indexNames(t, notifications), "userId_1")
+	require.Contains(indexNames(t, audit), "targetIds_1")
+	require.Contains(indexNames(t, exports), "userId_1")
+	require.Contains(indexNames(t, exports), "status_1")
+	require.Contains(indexNames(t, tournaments), "status_1_startsAt_1")
+	require.Contains(indexNames(t, sessions), "token_hash_1")
+	require.Contains(indexNames(t, sessions), "user_id_1")
+	require.Contains(indexNames(t, credentials), "user_id_1")
+	require.Contains(indexNames(t, exports), "userId_1_status_1")
+
+	pending, err := db.PendingExports(context.TODO(), 10)
+	require.NoError(err)
+	require.Len(pending, 1, "Older duplicate export should be failed")
+	_, err = exports.InsertOne(context.TODO(), Export{UserID: "u1", Status: ExportPending})
+	require.True(mongo.IsDuplicateKeyError(err), "Second pending export should be rejected")
+	_, err = exports.InsertOne(context.TODO(), Export{UserID: "u1", Status: ExportFailed})
+	require.NoError(err, "Only pending exports should be unique")
+
+	counts, err := db.CountTournamentsByStatus(context.TODO())
+	require.NoError(err)
+	require.Equal(map[TournamentStatus]int64{StatusSignIn: 2}, counts, "Status should be backfilled")
+
+	cleanUp(t)
+}
+
+func indexNames(t *testing.T, c *mongo.Collection) []string {
+	cursor, err := c.Indexes().List(context.TODO())
+	require.NoError(t, err)
+	var specs []bson.M
+	require.NoError(t, cursor.All(context.TODO(), &specs))
+
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec["name"].(string))
+	}
+	return names
+}
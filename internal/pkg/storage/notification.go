@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationKind is reason user is notified.
+type NotificationKind string
+
+const (
+	NotificationTournamentStarting  NotificationKind = "tournamentStarting"
+	NotificationTournamentStarted   NotificationKind = "tournamentStarted"
+	NotificationTournamentFinished  NotificationKind = "tournamentFinished"
+	NotificationTournamentCancelled NotificationKind = "tournamentCancelled"
+	NotificationTournamentWon       NotificationKind = "tournamentWon"
+	NotificationBalanceFunded       NotificationKind = "balanceFunded"
+)
+
+// Notification is message in user's in-app inbox.
+type Notification struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"userId" bson:"userId"`
+
+	// EventID is id of outbox event notification is created for.
+	// User is notified about every event once.
+	EventID primitive.ObjectID `json:"-" bson:"eventId"`
+
+	Kind         NotificationKind `json:"kind" bson:"kind"`
+	TournamentID string           `json:"tournamentId,omitempty" bson:"tournamentId,omitempty"`
+
+	// Amount is prize of won tournament or points funded to balance.
+	Amount float64 `json:"amount,omitempty" bson:"amount,omitempty"`
+
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	ReadAt    *time.Time `json:"readAt,omitempty" bson:"readAt,omitempty"`
+}
+
+// AddNotifications func adds notifications with creation time. Notification
+// about the same event for the same user is added once.
+func (db *DB) AddNotifications(ctx context.Context, notifications []Notification) (err error) {
+	ctx, span := startSpan(ctx, "AddNotifications")
+	defer func() { endSpan(span, err) }()
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	models := make([]mongo.WriteModel, 0, len(notifications))
+	for _, n := range notifications {
+		n.ID = primitive.NilObjectID
+		n.CreatedAt = now
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"userId": n.UserID, "eventId": n.EventID}).
+			SetUpdate(bson.M{"$setOnInsert": n}).
+			SetUpsert(true))
+	}
+	_, err = db.conn.Collection(notificationsCollectionName).BulkWrite(ctx, models,
+		options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return errors.Wrap(err, "upsert docs in collection")
+	}
+
+	return nil
+}
+
+// ListNotifications func returns up to limit latest notifications of user
+// with provided id, newest first. Only unread ones are returned if unread is set.
+func (db *DB) ListNotifications(ctx context.Context, userID string, unread bool,
+	limit int64) (_ []Notification, err error) {
+	ctx, span := startSpan(ctx, "ListNotifications")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{"userId": userID}
+	if unread {
+		filter["readAt"] = bson.M{"$exists": false}
+	}
+	opts := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(notificationsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []Notification{}
+	if err = cursor.All(ctx, &notifications); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return notifications, nil
+}
+
+// CountUnreadNotifications func returns number of unread notifications of user with provided id.
+func (db *DB) CountUnreadNotifications(ctx context.Context, userID string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "CountUnreadNotifications")
+	defer func() { endSpan(span, err) }()
+
+	count, err := db.conn.Collection(notificationsCollectionName).CountDocuments(ctx,
+		bson.M{"userId": userID, "readAt": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, errors.Wrap(err, "count docs in collection")
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead func marks notification with provided id of user
+// with provided id as read. Notification read before keeps its read time.
+func (db *DB) MarkNotificationRead(ctx context.Context, userID, id string) (err error) {
+	ctx, span := startSpan(ctx, "MarkNotificationRead")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	filter := bson.M{"_id": primID, "userId": userID}
+	notification := db.conn.Collection(notificationsCollectionName).FindOne(ctx, filter)
+	if err = notification.Err(); err != nil {
+		return errors.Wrap(err, "get doc from collection")
+	}
+
+	filter["readAt"] = bson.M{"$exists": false}
+	update := bson.D{
+		{"$set", bson.D{
+			{"readAt", time.Now().UTC()},
+		}},
+	}
+	if _, err = db.conn.Collection(notificationsCollectionName).UpdateOne(ctx, filter, update); err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead func marks all unread notifications of user with
+// provided id as read. It returns number of marked notifications.
+func (db *DB) MarkAllNotificationsRead(ctx context.Context, userID string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "MarkAllNotificationsRead")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"readAt", time.Now().UTC()},
+		}},
+	}
+	updateResult, err := db.conn.Collection(notificationsCollectionName).UpdateMany(ctx,
+		bson.M{"userId": userID, "readAt": bson.M{"$exists": false}}, update)
+	if err != nil {
+		return 0, errors.Wrap(err, "update docs in collection")
+	}
+
+	return updateResult.ModifiedCount, nil
+}
+
+// DeleteNotifications func removes read notifications created before readBefore
+// and all notifications created before before. It returns number of removed notifications.
+func (db *DB) DeleteNotifications(ctx context.Context, readBefore, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteNotifications")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"readAt": bson.M{"$exists": true}, "createdAt": bson.M{"$lt": readBefore}},
+		bson.M{"createdAt": bson.M{"$lt": before}},
+	}}
+	deleteResult, err := db.conn.Collection(notificationsCollectionName).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "delete docs from collection")
+	}
+
+	return deleteResult.DeletedCount, nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNotifications(t *testing.T) {
+	require := require.New(t)
+	userID, otherID := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+
+	startedID, finishedID := primitive.NewObjectID(), primitive.NewObjectID()
+	require.NoError(db.AddNotifications(context.TODO(), []Notification{
+		{UserID: userID, EventID: startedID, Kind: NotificationTournamentStarted, TournamentID: "t1"},
+		{UserID: otherID, EventID: startedID, Kind: NotificationTournamentStarted, TournamentID: "t1"},
+	}))
+	require.NoError(db.AddNotifications(context.TODO(), []Notification{
+		{UserID: userID, EventID: startedID, Kind: NotificationTournamentStarted, TournamentID: "t1"},
+		{UserID: userID, EventID: finishedID, Kind: NotificationTournamentWon, TournamentID: "t1", Amount: 50},
+	}), "Notification about the same event should be added once")
+
+	list, err := db.ListNotifications(context.TODO(), userID, false, 10)
+	require.NoError(err)
+	require.Len(list, 2)
+	require.Equal(NotificationTournamentWon, list[0].Kind, "Newest notification should go first")
+	require.Equal(50.0, list[0].Amount)
+	require.Nil(list[0].ReadAt)
+
+	unread, err := db.CountUnreadNotifications(context.TODO(), userID)
+	require.NoError(err)
+	require.Equal(int64(2), unread)
+
+	require.NoError(db.MarkNotificationRead(context.TODO(), userID, list[1].ID.Hex()))
+	require.NoError(db.MarkNotificationRead(context.TODO(), userID, list[1].ID.Hex()), "Marking twice should succeed")
+	err = db.MarkNotificationRead(context.TODO(), otherID, list[1].ID.Hex())
+	require.True(errors.Is(err, mongo.ErrNoDocuments), "Notification of other user should not be marked")
+
+	list, err = db.ListNotifications(context.TODO(), userID, true, 10)
+	require.NoError(err)
+	require.Len(list, 1)
+	require.Equal(NotificationTournamentWon, list[0].Kind)
+
+	marked, err := db.MarkAllNotificationsRead(context.TODO(), userID)
+	require.NoError(err)
+	require.Equal(int64(1), marked)
+	unread, err = db.CountUnreadNotifications(context.TODO(), userID)
+	require.NoError(err)
+	require.Zero(unread)
+
+	// read notifications of user are removed, unread one of other user is kept
+	deleted, err := db.DeleteNotifications(context.TODO(), time.Now().Add(time.Minute), time.Now().Add(-time.Minute))
+	require.NoError(err)
+	require.Equal(int64(2), deleted)
+	unread, err = db.CountUnreadNotifications(context.TODO(), otherID)
+	require.NoError(err)
+	require.Equal(int64(1), unread)
+
+	cleanUp(t)
+}
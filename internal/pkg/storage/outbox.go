@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventType names domain event recorded in outbox.
+type EventType string
+
+// Domain events written by mutating DB methods.
+const (
+	EventUserCreated         EventType = "UserCreated"
+	EventUserDeleted         EventType = "UserDeleted"
+	EventUserRoleChanged     EventType = "UserRoleChanged"
+	EventBalanceFunded       EventType = "BalanceFunded"
+	EventBalanceTaken        EventType = "BalanceTaken"
+	EventTournamentCreated   EventType = "TournamentCreated"
+	EventPlayerJoined        EventType = "PlayerJoined"
+	EventPlayerLeft          EventType = "PlayerLeft"
+	EventPlayerRemoved       EventType = "PlayerRemoved"
+	EventTournamentStarted   EventType = "TournamentStarted"
+	EventTournamentFinished  EventType = "TournamentFinished"
+	EventTournamentCancelled EventType = "TournamentCancelled"
+)
+
+// Valid reports whether event type is one of the known types.
+func (t EventType) Valid() bool {
+	switch t {
+	case EventUserCreated, EventUserDeleted, EventUserRoleChanged, EventBalanceFunded, EventBalanceTaken,
+		EventTournamentCreated, EventPlayerJoined, EventPlayerLeft, EventPlayerRemoved, EventTournamentStarted,
+		EventTournamentFinished, EventTournamentCancelled:
+		return true
+	}
+	return false
+}
+
+// OutboxEvent is domain event stored in outbox collection
+// in the same transaction as mutation which caused it.
+type OutboxEvent struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type         EventType          `json:"type" bson:"type"`
+	UserID       string             `json:"userId,omitempty" bson:"userId,omitempty"`
+	TournamentID string             `json:"tournamentId,omitempty" bson:"tournamentId,omitempty"`
+	Name         string             `json:"name,omitempty" bson:"name,omitempty"`
+
+	// Amount is points funded or taken, deposit of created, joined or left
+	// tournament, prize paid to winner of finished one or balance forfeited
+	// by deleted user.
+	Amount float64 `json:"amount,omitempty" bson:"amount,omitempty"`
+
+	// Role is new role of user whose role is changed.
+	Role Role `json:"role,omitempty" bson:"role,omitempty"`
+
+	// UserIDs are players of tournament which is started, finished or cancelled.
+	UserIDs []string `json:"userIds,omitempty" bson:"userIds,omitempty"`
+
+	// Seq is position of event in outbox. It's assigned in transaction
+	// writing event, so events become visible in order of Seq.
+	Seq int64 `json:"seq" bson:"seq"`
+
+	OccurredAt time.Time `json:"occurredAt" bson:"occurredAt"`
+}
+
+// inTransaction runs fn in transaction. If ctx already carries session,
+// like inside JoinTournament, fn joins its transaction, so events are
+// committed or rolled back together with the whole operation.
+func (db *DB) inTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	session, err := db.conn.Client().StartSession()
+	if err != nil {
+		return errors.Wrap(err, "error start mongoDB session")
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// addOutboxEvent writes event to outbox. It must be called with
+// session context of transaction making the change.
+func (db *DB) addOutboxEvent(ctx context.Context, e OutboxEvent) error {
+	// transactions writing events conflict on counter, so they commit
+	// one by one in order of sequence they got
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := db.conn.Collection(countersCollectionName).FindOneAndUpdate(ctx,
+		bson.M{"_id": outboxCollectionName}, bson.D{{"$inc", bson.D{{"seq", 1}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return errors.Wrap(err, "increment outbox sequence")
+	}
+
+	e.Seq = counter.Seq
+	e.OccurredAt = time.Now().UTC()
+	if _, err := db.conn.Collection(outboxCollectionName).InsertOne(ctx, e); err != nil {
+		return errors.Wrap(err, "insert doc to outbox")
+	}
+
+	return nil
+}
+
+func hexIDs(ids []primitive.ObjectID) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	hexes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hexes = append(hexes, id.Hex())
+	}
+
+	return hexes
+}
+
+// outboxCursor is position of consumer in outbox.
+type outboxCursor struct {
+	Consumer string `bson:"_id"`
+
+	// Seq is sequence of the last event consumer processed.
+	Seq int64 `bson:"seq"`
+
+	// UpdatedAt is when consumer last moved cursor or purged outbox.
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// PendingOutboxEvents func returns up to limit events which consumer hasn't
+// processed yet in order of their sequence. New consumer starts from the
+// oldest event kept in outbox.
+func (db *DB) PendingOutboxEvents(ctx context.Context, consumer string, limit int64) (_ []OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "PendingOutboxEvents")
+	defer func() { endSpan(span, err) }()
+
+	var cursor outboxCursor
+	err = db.conn.Collection(cursorsCollectionName).FindOne(ctx, bson.M{"_id": consumer}).Decode(&cursor)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errors.Wrap(err, "find cursor in collection")
+	}
+
+	opts := options.Find().SetSort(bson.D{{"seq", 1}}).SetLimit(limit)
+	found, err := db.conn.Collection(outboxCollectionName).Find(ctx,
+		bson.M{"seq": bson.M{"$gt": cursor.Seq}}, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer found.Close(ctx)
+
+	var pending []OutboxEvent
+	if err = found.All(ctx, &pending); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return pending, nil
+}
+
+// MarkOutboxEventProcessed func moves cursor of consumer to event with provided sequence.
+func (db *DB) MarkOutboxEventProcessed(ctx context.Context, consumer string, seq int64) (err error) {
+	ctx, span := startSpan(ctx, "MarkOutboxEventProcessed")
+	defer func() { endSpan(span, err) }()
+
+	// cursor never moves back, even if other instance dispatched further
+	update := bson.D{
+		{"$max", bson.D{
+			{"seq", seq},
+		}},
+		{"$set", bson.D{
+			{"updatedAt", time.Now().UTC()},
+		}},
+	}
+	_, err = db.conn.Collection(cursorsCollectionName).UpdateOne(ctx, bson.M{"_id": consumer}, update,
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	return nil
+}
+
+// DeleteProcessedOutboxEvents func removes events which occurred before provided
+// time and were processed by every consumer. Consumer which hasn't moved its
+// cursor or purged outbox since then, e.g. sink disabled in config, is
+// skipped, so it doesn't keep events forever. It returns number of removed events.
+func (db *DB) DeleteProcessedOutboxEvents(ctx context.Context, consumer string, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteProcessedOutboxEvents")
+	defer func() { endSpan(span, err) }()
+
+	cursors := db.conn.Collection(cursorsCollectionName)
+	_, err = cursors.UpdateOne(ctx, bson.M{"_id": consumer},
+		bson.D{{"$set", bson.D{{"updatedAt", time.Now().UTC()}}}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return 0, errors.Wrap(err, "update doc in collection")
+	}
+
+	var slowest outboxCursor
+	err = cursors.FindOne(ctx, bson.M{"updatedAt": bson.M{"$gte": before}},
+		options.FindOne().SetSort(bson.D{{"seq", 1}})).Decode(&slowest)
+	if err != nil {
+		return 0, errors.Wrap(err, "find slowest cursor in collection")
+	}
+
+	deleteResult, err := db.conn.Collection(outboxCollectionName).DeleteMany(ctx, bson.M{
+		"occurredAt": bson.M{"$lt": before},
+		"seq":        bson.M{"$lte": slowest.Seq},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "delete docs from collection")
+	}
+
+	return deleteResult.DeletedCount, nil
+}
+
+// CreateCollections func creates collections written inside transactions.
+// MongoDB before 4.4 can't create collection implicitly in transaction.
+func (db *DB) CreateCollections(ctx context.Context) error {
+	names, err := db.conn.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return errors.Wrap(err, "list collections")
+	}
+
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+	for _, name := range []string{
+		usersCollectionName, tournamentsCollectionName, credentialsCollectionName, sessionsCollectionName,
+		outboxCollectionName, countersCollectionName, notificationsCollectionName, emailsCollectionName,
+	} {
+		if existing[name] {
+			continue
+		}
+		err := db.conn.CreateCollection(ctx, name)
+		var cmdErr mongo.CommandError
+		// collection may be created concurrently by other instance
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "create collection %s", name)
+		}
+	}
+
+	return nil
+}
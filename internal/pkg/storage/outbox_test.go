@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOutbox(t *testing.T) {
+	require := require.New(t)
+
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	require.NoError(db.FundUserBalance(context.TODO(), userID, 100))
+	require.NoError(db.TakeUserBalance(context.TODO(), userID, 30))
+	tournamentID, err := db.AddTournament(context.TODO(), "tournament-1", 50, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.SetTournamentStatus(context.TODO(), tournamentID, StatusStarted))
+	require.NoError(db.FinishTournament(context.TODO(), tournamentID, userID))
+	require.ErrorIs(db.DeleteTournament(context.TODO(), tournamentID), ErrTournamentStatus)
+	cancelledID, err := db.AddTournament(context.TODO(), "tournament-2", 10, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.DeleteTournament(context.TODO(), cancelledID))
+
+	// failed mutation is rolled back together with its event
+	require.Error(db.FundUserBalance(context.TODO(), primitive.NewObjectID().Hex(), 10))
+
+	pending, err := db.PendingOutboxEvents(context.TODO(), "webhooks", 100)
+	require.NoError(err)
+	expected := []OutboxEvent{
+		{Type: EventUserCreated, UserID: userID, Name: "Vasya"},
+		{Type: EventBalanceFunded, UserID: userID, Amount: 100},
+		{Type: EventBalanceTaken, UserID: userID, Amount: 30},
+		{Type: EventTournamentCreated, TournamentID: tournamentID, UserID: organizerID.Hex(),
+			Name: "tournament-1", Amount: 50},
+		{Type: EventPlayerJoined, TournamentID: tournamentID, UserID: userID, Amount: 50},
+		{Type: EventTournamentStarted, TournamentID: tournamentID, UserIDs: []string{userID}},
+		{Type: EventBalanceFunded, UserID: userID, Amount: 50},
+		{Type: EventTournamentFinished, TournamentID: tournamentID, UserID: userID, Amount: 50,
+			UserIDs: []string{userID}},
+		{Type: EventTournamentCreated, TournamentID: cancelledID, UserID: organizerID.Hex(),
+			Name: "tournament-2", Amount: 10},
+		{Type: EventTournamentCancelled, TournamentID: cancelledID},
+	}
+	require.Len(pending, len(expected))
+	for i, e := range expected {
+		require.False(pending[i].ID.IsZero())
+		require.False(pending[i].OccurredAt.IsZero())
+		require.Equal(int64(i+1), pending[i].Seq, "Event %d should get next sequence", i)
+		e.ID, e.Seq, e.OccurredAt = pending[i].ID, pending[i].Seq, pending[i].OccurredAt
+		require.Equal(e, pending[i], "Event %d should be recorded in order", i)
+	}
+
+	require.NoError(db.MarkOutboxEventProcessed(context.TODO(), "webhooks", pending[1].Seq))
+	require.NoError(db.MarkOutboxEventProcessed(context.TODO(), "webhooks", pending[0].Seq),
+		"Cursor should not move back")
+	pending, err = db.PendingOutboxEvents(context.TODO(), "webhooks", 2)
+	require.NoError(err)
+	require.Len(pending, 2)
+	require.Equal(EventBalanceTaken, pending[0].Type, "Processed event should not be pending")
+
+	pending, err = db.PendingOutboxEvents(context.TODO(), "broker", 100)
+	require.NoError(err)
+	require.Len(pending, len(expected), "Sinks should have own cursors")
+
+	require.NoError(db.MarkOutboxEventProcessed(context.TODO(), "broker", 1))
+	deleted, err := db.DeleteProcessedOutboxEvents(context.TODO(), "webhooks", time.Now().Add(time.Minute))
+	require.NoError(err)
+	require.Equal(int64(1), deleted, "Only events processed by every sink should be removed")
+
+	_, err = cursors.UpdateOne(context.TODO(), bson.M{"_id": "broker"},
+		bson.M{"$set": bson.M{"updatedAt": time.Now().Add(-time.Hour)}})
+	require.NoError(err)
+	deleted, err = db.DeleteProcessedOutboxEvents(context.TODO(), "webhooks", time.Now().Add(-time.Minute))
+	require.NoError(err)
+	require.Zero(deleted, "Events occurred after given time should be kept")
+	deleted, err = db.DeleteProcessedOutboxEvents(context.TODO(), "webhooks", time.Now().Add(time.Minute))
+	require.NoError(err)
+	require.Equal(int64(1), deleted, "Idle sink should not keep events")
+
+	cleanUp(t)
+}
+
+func TestOutbox_MembershipAndRole(t *testing.T) {
+	require := require.New(t)
+
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	tournamentID, err := db.AddTournament(context.TODO(), "tournament-1", 50, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.LeaveTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.SetUserRole(context.TODO(), userID, RoleOrganizer))
+	require.NoError(db.JoinTournament(context.TODO(), tournamentID, userID))
+	require.NoError(db.DeleteUser(context.TODO(), userID, DeleteUserOptions{Force: true}))
+
+	pending, err := db.PendingOutboxEvents(context.TODO(), "webhooks", 100)
+	require.NoError(err)
+	var types []EventType
+	for _, e := range pending {
+		types = append(types, e.Type)
+	}
+	require.Equal([]EventType{EventUserCreated, EventTournamentCreated, EventPlayerJoined, EventPlayerLeft,
+		EventUserRoleChanged, EventPlayerJoined, EventPlayerRemoved, EventUserDeleted}, types)
+	require.Equal(tournamentID, pending[3].TournamentID)
+	require.Equal(50.0, pending[3].Amount)
+	require.Equal(RoleOrganizer, pending[4].Role)
+	require.Equal(userID, pending[6].UserID)
+	require.Equal(tournamentID, pending[6].TournamentID)
+
+	// rejected leave is rolled back together with its event
+	require.Error(db.LeaveTournament(context.TODO(), tournamentID, userID))
+	pending, err = db.PendingOutboxEvents(context.TODO(), "webhooks", 100)
+	require.NoError(err)
+	require.Len(pending, len(types))
+
+	cleanUp(t)
+}
+
+func TestLock(t *testing.T) {
+	require := require.New(t)
+
+	ok, err := db.AcquireLock(context.TODO(), "outbox", "first", time.Minute)
+	require.NoError(err)
+	require.True(ok)
+
+	ok, err = db.AcquireLock(context.TODO(), "outbox", "second", time.Minute)
+	require.NoError(err)
+	require.False(ok, "Lease held by other owner should not be acquired")
+
+	ok, err = db.AcquireLock(context.TODO(), "outbox", "first", time.Minute)
+	require.NoError(err)
+	require.True(ok, "Owner should extend own lease")
+
+	require.NoError(db.ReleaseLock(context.TODO(), "outbox", "first"))
+	ok, err = db.AcquireLock(context.TODO(), "outbox", "second", -time.Second)
+	require.NoError(err)
+	require.True(ok, "Released lease should be acquired")
+
+	ok, err = db.AcquireLock(context.TODO(), "outbox", "first", time.Minute)
+	require.NoError(err)
+	require.True(ok, "Expired lease should be taken over")
+
+	cleanUp(t)
+}
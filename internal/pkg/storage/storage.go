@@ -4,8 +4,11 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -15,8 +18,21 @@ type DB struct {
 }
 
 const (
-	usersCollectionName       = "users"
-	tournamentsCollectionName = "tournaments"
+	usersCollectionName         = "users"
+	tournamentsCollectionName   = "tournaments"
+	credentialsCollectionName   = "credentials"
+	sessionsCollectionName      = "sessions"
+	outboxCollectionName        = "outbox"
+	locksCollectionName         = "locks"
+	webhooksCollectionName      = "webhooks"
+	deliveriesCollectionName    = "webhookDeliveries"
+	notificationsCollectionName = "notifications"
+	emailsCollectionName        = "emails"
+	auditCollectionName         = "audit"
+	exportsCollectionName       = "exports"
+	migrationsCollectionName    = "schema_migrations"
+	countersCollectionName      = "counters"
+	cursorsCollectionName       = "outboxCursors"
 )
 
 // CreateNew is constructor for db
@@ -26,19 +42,50 @@ func CreateNew(db *mongo.Database) *DB {
 	}
 }
 
-func (db *DB) JoinTournament(ctx context.Context, tournamentID, userID string) error {
-	session, err := db.conn.Client().StartSession()
-	if err != nil {
-		return errors.Wrap(err, "error start mongoDB session")
-	}
-	if err := session.StartTransaction(); err != nil {
-		return errors.Wrap(err, "error start transaction")
+// RegisterUser adds user with provided name and his credentials
+// in one transaction. Returns userID if succeed.
+func (db *DB) RegisterUser(ctx context.Context, name, login string, passwordHash []byte) (_ string, err error) {
+	ctx, span := startSpan(ctx, "RegisterUser")
+	defer func() { endSpan(span, err) }()
+
+	var userID string
+	if err := db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		userID, err = db.AddUser(sc, name)
+		if err != nil {
+			return errors.Wrap(err, "AddUser")
+		}
+
+		return db.AddCredentials(sc, userID, login, passwordHash)
+	}); err != nil {
+		if errors.Is(err, ErrLoginTaken) {
+			return "", err
+		}
+		return "", errors.Wrap(err, "error processing transaction")
 	}
-	if err := mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+
+	return userID, nil
+}
+
+func (db *DB) JoinTournament(ctx context.Context, tournamentID, userID string) (err error) {
+	ctx, span := startSpan(ctx, "JoinTournament")
+	defer func() { endSpan(span, err) }()
+
+	if err := db.inTransaction(ctx, func(sc mongo.SessionContext) error {
 		if err := db.AddUserToTournamentList(sc, tournamentID, userID); err != nil {
 			return errors.Wrap(err, "AddUserToTournamentList")
 		}
 
+		// id is already checked by AddUserToTournamentList
+		primUserID, _ := primitive.ObjectIDFromHex(userID)
+		active, err := db.conn.Collection(usersCollectionName).CountDocuments(sc,
+			bson.M{"_id": primUserID, "deletedAt": bson.M{"$exists": false}})
+		if err != nil {
+			return errors.Wrap(err, "count docs in collection")
+		}
+		if active == 0 {
+			return errors.Wrap(mongo.ErrNoDocuments, "user doesn't exist or is deleted")
+		}
+
 		tournament, err := db.GetTournament(sc, tournamentID)
 		if err != nil {
 			return errors.Wrap(err, "GetTournament")
@@ -48,8 +95,14 @@ func (db *DB) JoinTournament(ctx context.Context, tournamentID, userID string) e
 			return errors.Wrap(err, "IncreaseTournamentPrize")
 		}
 
-		if err := session.CommitTransaction(sc); err != nil {
-			return errors.Wrap(err, "commit transaction")
+		err = db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventPlayerJoined,
+			TournamentID: tournamentID,
+			UserID:       userID,
+			Amount:       tournament.Deposit,
+		})
+		if err != nil {
+			return errors.Wrap(err, "addOutboxEvent")
 		}
 
 		return nil
@@ -57,20 +110,65 @@ func (db *DB) JoinTournament(ctx context.Context, tournamentID, userID string) e
 		return errors.Wrap(err, "error processing transaction")
 	}
 
-	session.EndSession(ctx)
 	return nil
 }
 
-func (db *DB) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) error {
-	session, err := db.conn.Client().StartSession()
-	if err != nil {
-		return errors.Wrap(err, "error start mongoDB session")
-	}
-	if err := session.StartTransaction(); err != nil {
-		return errors.Wrap(err, "error start transaction")
+func (db *DB) LeaveTournament(ctx context.Context, tournamentID, userID string) (err error) {
+	ctx, span := startSpan(ctx, "LeaveTournament")
+	defer func() { endSpan(span, err) }()
+
+	if err := db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		primUserID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+		}
+
+		tournament, err := db.GetTournament(sc, tournamentID)
+		if err != nil {
+			return errors.Wrap(err, "GetTournament")
+		}
+
+		// joining doesn't take deposit from balance, so only prize is decreased
+		update := bson.D{
+			{"$pull", bson.D{{"users", primUserID}}},
+			{"$inc", bson.D{{"prize", -tournament.Deposit}}},
+		}
+		updateResult, err := db.conn.Collection(tournamentsCollectionName).UpdateOne(sc,
+			bson.M{"_id": tournament.ID, "users": primUserID, "status": StatusSignIn}, update)
+		if err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+		if updateResult.MatchedCount != 1 {
+			if tournament.Status != StatusSignIn {
+				return errors.Wrapf(ErrTournamentStatus, "leave %s tournament", tournament.Status)
+			}
+			return errors.New("update doc in collection: user isn't in tournament users list")
+		}
+
+		err = db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventPlayerLeft,
+			TournamentID: tournamentID,
+			UserID:       userID,
+			Amount:       tournament.Deposit,
+		})
+		if err != nil {
+			return errors.Wrap(err, "addOutboxEvent")
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "error processing transaction")
 	}
 
-	if err := mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+	return nil
+}
+
+func (db *DB) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) (err error) {
+	ctx, span := startSpan(ctx, "FinishTournament")
+	defer func() { endSpan(span, err) }()
+
+	entry := AuditEntry{Action: AuditTournamentFinished, TargetIDs: []string{tournamentID, winnerUserID}}
+	if err := db.inAuditedTransaction(ctx, entry, tournamentsCollectionName, func(sc context.Context) error {
 		if err := db.SetTournamentStatus(sc, tournamentID, StatusFinished); err != nil {
 			return errors.Wrap(err, "SetTournamentStatus")
 		}
@@ -89,8 +187,15 @@ func (db *DB) FinishTournament(ctx context.Context, tournamentID, winnerUserID s
 			return errors.Wrap(err, "FundUserBalance")
 		}
 
-		if err := session.CommitTransaction(sc); err != nil {
-			return errors.Wrap(err, "commit transaction")
+		err = db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventTournamentFinished,
+			TournamentID: tournamentID,
+			UserID:       winnerUserID,
+			Amount:       tournament.Prize,
+			UserIDs:      hexIDs(tournament.Users),
+		})
+		if err != nil {
+			return errors.Wrap(err, "addOutboxEvent")
 		}
 
 		return nil
@@ -98,7 +203,6 @@ func (db *DB) FinishTournament(ctx context.Context, tournamentID, winnerUserID s
 		return errors.Wrap(err, "error processing transaction")
 	}
 
-	session.EndSession(ctx)
 	return nil
 }
 
@@ -111,7 +215,9 @@ type Service interface {
 	// GetUser returns *User that contains all information about user with help of provided id
 	GetUser(ctx context.Context, id string) (*User, error)
 
-	DeleteUser(ctx context.Context, id string) error
+	// DeleteUser soft deletes user with provided id. It returns ErrUserActive
+	// if user has active tournaments or balance and deletion isn't forced.
+	DeleteUser(ctx context.Context, id string, opts DeleteUserOptions) error
 
 	// TakeUserBalance finds user with provided id and deducts from his balance provided points
 	TakeUserBalance(ctx context.Context, id string, points float64) error
@@ -119,7 +225,12 @@ type Service interface {
 	// FundUserBalance finds user with provided id and adds to his balance provided points
 	FundUserBalance(ctx context.Context, id string, points float64) error
 
-	AddTournament(ctx context.Context, name string, deposit float64) (string, error)
+	// SetUserRole finds user with provided id and sets his role
+	SetUserRole(ctx context.Context, id string, role Role) error
+
+	// AddTournament adds tournament to db with given name, deposit and
+	// organizer who created it. Returns tournamentID if succeed.
+	AddTournament(ctx context.Context, name string, deposit float64, organizerID string) (string, error)
 	GetTournament(ctx context.Context, id string) (*Tournament, error)
 	DeleteTournament(ctx context.Context, id string) error
 	IncreaseTournamentPrize(ctx context.Context, id string, amount float64) error
@@ -127,7 +238,26 @@ type Service interface {
 	SetTournamentWinner(ctx context.Context, tournamentID, userID string) error
 	SetTournamentStatus(ctx context.Context, tournamentID string, status TournamentStatus) error
 	AddUserToTournamentList(ctx context.Context, tournamentID, userID string) error
+	CountTournamentsByStatus(ctx context.Context) (map[TournamentStatus]int64, error)
+
+	// ListTournaments returns up to limit tournaments with provided status,
+	// newest first. Empty status matches all tournaments.
+	ListTournaments(ctx context.Context, status TournamentStatus, limit int64) ([]Tournament, error)
+	RemoveUserFromTournamentList(ctx context.Context, tournamentID, userID string) error
+
+	// RegisterUser adds user with provided name together with his credentials.
+	// Returns ErrLoginTaken if login is already in use.
+	RegisterUser(ctx context.Context, name, login string, passwordHash []byte) (string, error)
+	GetCredentialsByLogin(ctx context.Context, login string) (*Credentials, error)
+	GetCredentialsByUserID(ctx context.Context, userID string) (*Credentials, error)
+	SetPasswordHash(ctx context.Context, userID string, passwordHash []byte) error
+
+	AddSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	GetSession(ctx context.Context, tokenHash string) (*Session, error)
+	RevokeSession(ctx context.Context, tokenHash string) error
+	RevokeUserSessions(ctx context.Context, userID string) error
 
 	JoinTournament(ctx context.Context, tournamentID, userID string) error
+	LeaveTournament(ctx context.Context, tournamentID, userID string) error
 	FinishTournament(ctx context.Context, tournamentID, winnerUserID string) error
 }
@@ -1,217 +1,438 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: storage.go
 
+// Package storage is a generated GoMock package.
 package storage
 
 import (
 	context "context"
-	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
 )
 
-// MockService is a mock of Service interface
+// MockService is a mock of Service interface.
 type MockService struct {
 	ctrl     *gomock.Controller
 	recorder *MockServiceMockRecorder
 }
 
-// MockServiceMockRecorder is the mock recorder for MockService
+// MockServiceMockRecorder is the mock recorder for MockService.
 type MockServiceMockRecorder struct {
 	mock *MockService
 }
 
-// NewMockService creates a new mock instance
+// NewMockService creates a new mock instance.
 func NewMockService(ctrl *gomock.Controller) *MockService {
 	mock := &MockService{ctrl: ctrl}
 	mock.recorder = &MockServiceMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
-func (_m *MockService) EXPECT() *MockServiceMockRecorder {
-	return _m.recorder
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
 }
 
-// AddUser mocks base method
-func (_m *MockService) AddUser(ctx context.Context, name string) (string, error) {
-	ret := _m.ctrl.Call(_m, "AddUser", ctx, name)
+// AddSession mocks base method.
+func (m *MockService) AddSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSession", ctx, userID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddSession indicates an expected call of AddSession.
+func (mr *MockServiceMockRecorder) AddSession(ctx, userID, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSession", reflect.TypeOf((*MockService)(nil).AddSession), ctx, userID, tokenHash, expiresAt)
+}
+
+// AddTournament mocks base method.
+func (m *MockService) AddTournament(ctx context.Context, name string, deposit float64, organizerID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTournament", ctx, name, deposit, organizerID)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddUser indicates an expected call of AddUser
-func (_mr *MockServiceMockRecorder) AddUser(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "AddUser", reflect.TypeOf((*MockService)(nil).AddUser), arg0, arg1)
+// AddTournament indicates an expected call of AddTournament.
+func (mr *MockServiceMockRecorder) AddTournament(ctx, name, deposit, organizerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTournament", reflect.TypeOf((*MockService)(nil).AddTournament), ctx, name, deposit, organizerID)
 }
 
-// GetUser mocks base method
-func (_m *MockService) GetUser(ctx context.Context, id string) (*User, error) {
-	ret := _m.ctrl.Call(_m, "GetUser", ctx, id)
-	ret0, _ := ret[0].(*User)
+// AddUser mocks base method.
+func (m *MockService) AddUser(ctx context.Context, name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUser", ctx, name)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUser indicates an expected call of GetUser
-func (_mr *MockServiceMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetUser", reflect.TypeOf((*MockService)(nil).GetUser), arg0, arg1)
+// AddUser indicates an expected call of AddUser.
+func (mr *MockServiceMockRecorder) AddUser(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockService)(nil).AddUser), ctx, name)
 }
 
-// DeleteUser mocks base method
-func (_m *MockService) DeleteUser(ctx context.Context, id string) error {
-	ret := _m.ctrl.Call(_m, "DeleteUser", ctx, id)
+// AddUserToTournamentList mocks base method.
+func (m *MockService) AddUserToTournamentList(ctx context.Context, tournamentID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserToTournamentList", ctx, tournamentID, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteUser indicates an expected call of DeleteUser
-func (_mr *MockServiceMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), arg0, arg1)
+// AddUserToTournamentList indicates an expected call of AddUserToTournamentList.
+func (mr *MockServiceMockRecorder) AddUserToTournamentList(ctx, tournamentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserToTournamentList", reflect.TypeOf((*MockService)(nil).AddUserToTournamentList), ctx, tournamentID, userID)
 }
 
-// TakeUserBalance mocks base method
-func (_m *MockService) TakeUserBalance(ctx context.Context, id string, points float64) error {
-	ret := _m.ctrl.Call(_m, "TakeUserBalance", ctx, id, points)
+// CountTournamentsByStatus mocks base method.
+func (m *MockService) CountTournamentsByStatus(ctx context.Context) (map[TournamentStatus]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountTournamentsByStatus", ctx)
+	ret0, _ := ret[0].(map[TournamentStatus]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountTournamentsByStatus indicates an expected call of CountTournamentsByStatus.
+func (mr *MockServiceMockRecorder) CountTournamentsByStatus(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountTournamentsByStatus", reflect.TypeOf((*MockService)(nil).CountTournamentsByStatus), ctx)
+}
+
+// DecreaseTournamentPrize mocks base method.
+func (m *MockService) DecreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecreaseTournamentPrize", ctx, id, amount)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// TakeUserBalance indicates an expected call of TakeUserBalance
-func (_mr *MockServiceMockRecorder) TakeUserBalance(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "TakeUserBalance", reflect.TypeOf((*MockService)(nil).TakeUserBalance), arg0, arg1, arg2)
+// DecreaseTournamentPrize indicates an expected call of DecreaseTournamentPrize.
+func (mr *MockServiceMockRecorder) DecreaseTournamentPrize(ctx, id, amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecreaseTournamentPrize", reflect.TypeOf((*MockService)(nil).DecreaseTournamentPrize), ctx, id, amount)
 }
 
-// FundUserBalance mocks base method
-func (_m *MockService) FundUserBalance(ctx context.Context, id string, points float64) error {
-	ret := _m.ctrl.Call(_m, "FundUserBalance", ctx, id, points)
+// DeleteTournament mocks base method.
+func (m *MockService) DeleteTournament(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTournament", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// FundUserBalance indicates an expected call of FundUserBalance
-func (_mr *MockServiceMockRecorder) FundUserBalance(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "FundUserBalance", reflect.TypeOf((*MockService)(nil).FundUserBalance), arg0, arg1, arg2)
+// DeleteTournament indicates an expected call of DeleteTournament.
+func (mr *MockServiceMockRecorder) DeleteTournament(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTournament", reflect.TypeOf((*MockService)(nil).DeleteTournament), ctx, id)
 }
 
-// AddTournament mocks base method
-func (_m *MockService) AddTournament(ctx context.Context, name string, deposit float64) (string, error) {
-	ret := _m.ctrl.Call(_m, "AddTournament", ctx, name, deposit)
-	ret0, _ := ret[0].(string)
+// DeleteUser mocks base method.
+func (m *MockService) DeleteUser(ctx context.Context, id string, opts DeleteUserOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockServiceMockRecorder) DeleteUser(ctx, id, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), ctx, id, opts)
+}
+
+// FinishTournament mocks base method.
+func (m *MockService) FinishTournament(ctx context.Context, tournamentID, winnerUserID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishTournament", ctx, tournamentID, winnerUserID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishTournament indicates an expected call of FinishTournament.
+func (mr *MockServiceMockRecorder) FinishTournament(ctx, tournamentID, winnerUserID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishTournament", reflect.TypeOf((*MockService)(nil).FinishTournament), ctx, tournamentID, winnerUserID)
+}
+
+// FundUserBalance mocks base method.
+func (m *MockService) FundUserBalance(ctx context.Context, id string, points float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FundUserBalance", ctx, id, points)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FundUserBalance indicates an expected call of FundUserBalance.
+func (mr *MockServiceMockRecorder) FundUserBalance(ctx, id, points interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FundUserBalance", reflect.TypeOf((*MockService)(nil).FundUserBalance), ctx, id, points)
+}
+
+// GetCredentialsByLogin mocks base method.
+func (m *MockService) GetCredentialsByLogin(ctx context.Context, login string) (*Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialsByLogin", ctx, login)
+	ret0, _ := ret[0].(*Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredentialsByLogin indicates an expected call of GetCredentialsByLogin.
+func (mr *MockServiceMockRecorder) GetCredentialsByLogin(ctx, login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialsByLogin", reflect.TypeOf((*MockService)(nil).GetCredentialsByLogin), ctx, login)
+}
+
+// GetCredentialsByUserID mocks base method.
+func (m *MockService) GetCredentialsByUserID(ctx context.Context, userID string) (*Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialsByUserID", ctx, userID)
+	ret0, _ := ret[0].(*Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredentialsByUserID indicates an expected call of GetCredentialsByUserID.
+func (mr *MockServiceMockRecorder) GetCredentialsByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialsByUserID", reflect.TypeOf((*MockService)(nil).GetCredentialsByUserID), ctx, userID)
+}
+
+// GetSession mocks base method.
+func (m *MockService) GetSession(ctx context.Context, tokenHash string) (*Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, tokenHash)
+	ret0, _ := ret[0].(*Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddTournament indicates an expected call of AddTournament
-func (_mr *MockServiceMockRecorder) AddTournament(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "AddTournament", reflect.TypeOf((*MockService)(nil).AddTournament), arg0, arg1, arg2)
+// GetSession indicates an expected call of GetSession.
+func (mr *MockServiceMockRecorder) GetSession(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockService)(nil).GetSession), ctx, tokenHash)
 }
 
-// GetTournament mocks base method
-func (_m *MockService) GetTournament(ctx context.Context, id string) (*Tournament, error) {
-	ret := _m.ctrl.Call(_m, "GetTournament", ctx, id)
+// GetTournament mocks base method.
+func (m *MockService) GetTournament(ctx context.Context, id string) (*Tournament, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTournament", ctx, id)
 	ret0, _ := ret[0].(*Tournament)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTournament indicates an expected call of GetTournament
-func (_mr *MockServiceMockRecorder) GetTournament(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetTournament", reflect.TypeOf((*MockService)(nil).GetTournament), arg0, arg1)
+// GetTournament indicates an expected call of GetTournament.
+func (mr *MockServiceMockRecorder) GetTournament(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTournament", reflect.TypeOf((*MockService)(nil).GetTournament), ctx, id)
+}
+
+// GetUser mocks base method.
+func (m *MockService) GetUser(ctx context.Context, id string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, id)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockServiceMockRecorder) GetUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockService)(nil).GetUser), ctx, id)
+}
+
+// IncreaseTournamentPrize mocks base method.
+func (m *MockService) IncreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseTournamentPrize", ctx, id, amount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncreaseTournamentPrize indicates an expected call of IncreaseTournamentPrize.
+func (mr *MockServiceMockRecorder) IncreaseTournamentPrize(ctx, id, amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseTournamentPrize", reflect.TypeOf((*MockService)(nil).IncreaseTournamentPrize), ctx, id, amount)
+}
+
+// JoinTournament mocks base method.
+func (m *MockService) JoinTournament(ctx context.Context, tournamentID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JoinTournament", ctx, tournamentID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// JoinTournament indicates an expected call of JoinTournament.
+func (mr *MockServiceMockRecorder) JoinTournament(ctx, tournamentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JoinTournament", reflect.TypeOf((*MockService)(nil).JoinTournament), ctx, tournamentID, userID)
+}
+
+// LeaveTournament mocks base method.
+func (m *MockService) LeaveTournament(ctx context.Context, tournamentID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaveTournament", ctx, tournamentID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LeaveTournament indicates an expected call of LeaveTournament.
+func (mr *MockServiceMockRecorder) LeaveTournament(ctx, tournamentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveTournament", reflect.TypeOf((*MockService)(nil).LeaveTournament), ctx, tournamentID, userID)
+}
+
+// ListTournaments mocks base method.
+func (m *MockService) ListTournaments(ctx context.Context, status TournamentStatus, limit int64) ([]Tournament, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTournaments", ctx, status, limit)
+	ret0, _ := ret[0].([]Tournament)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTournaments indicates an expected call of ListTournaments.
+func (mr *MockServiceMockRecorder) ListTournaments(ctx, status, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTournaments", reflect.TypeOf((*MockService)(nil).ListTournaments), ctx, status, limit)
+}
+
+// RegisterUser mocks base method.
+func (m *MockService) RegisterUser(ctx context.Context, name, login string, passwordHash []byte) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterUser", ctx, name, login, passwordHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterUser indicates an expected call of RegisterUser.
+func (mr *MockServiceMockRecorder) RegisterUser(ctx, name, login, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockService)(nil).RegisterUser), ctx, name, login, passwordHash)
 }
 
-// DeleteTournament mocks base method
-func (_m *MockService) DeleteTournament(ctx context.Context, id string) error {
-	ret := _m.ctrl.Call(_m, "DeleteTournament", ctx, id)
+// RemoveUserFromTournamentList mocks base method.
+func (m *MockService) RemoveUserFromTournamentList(ctx context.Context, tournamentID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserFromTournamentList", ctx, tournamentID, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteTournament indicates an expected call of DeleteTournament
-func (_mr *MockServiceMockRecorder) DeleteTournament(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "DeleteTournament", reflect.TypeOf((*MockService)(nil).DeleteTournament), arg0, arg1)
+// RemoveUserFromTournamentList indicates an expected call of RemoveUserFromTournamentList.
+func (mr *MockServiceMockRecorder) RemoveUserFromTournamentList(ctx, tournamentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserFromTournamentList", reflect.TypeOf((*MockService)(nil).RemoveUserFromTournamentList), ctx, tournamentID, userID)
 }
 
-// IncreaseTournamentPrize mocks base method
-func (_m *MockService) IncreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
-	ret := _m.ctrl.Call(_m, "IncreaseTournamentPrize", ctx, id, amount)
+// RevokeSession mocks base method.
+func (m *MockService) RevokeSession(ctx context.Context, tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, tokenHash)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// IncreaseTournamentPrize indicates an expected call of IncreaseTournamentPrize
-func (_mr *MockServiceMockRecorder) IncreaseTournamentPrize(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "IncreaseTournamentPrize", reflect.TypeOf((*MockService)(nil).IncreaseTournamentPrize), arg0, arg1, arg2)
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockServiceMockRecorder) RevokeSession(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockService)(nil).RevokeSession), ctx, tokenHash)
 }
 
-// DecreaseTournamentPrize mocks base method
-func (_m *MockService) DecreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
-	ret := _m.ctrl.Call(_m, "DecreaseTournamentPrize", ctx, id, amount)
+// RevokeUserSessions mocks base method.
+func (m *MockService) RevokeUserSessions(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSessions", ctx, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DecreaseTournamentPrize indicates an expected call of DecreaseTournamentPrize
-func (_mr *MockServiceMockRecorder) DecreaseTournamentPrize(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "DecreaseTournamentPrize", reflect.TypeOf((*MockService)(nil).DecreaseTournamentPrize), arg0, arg1, arg2)
+// RevokeUserSessions indicates an expected call of RevokeUserSessions.
+func (mr *MockServiceMockRecorder) RevokeUserSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSessions", reflect.TypeOf((*MockService)(nil).RevokeUserSessions), ctx, userID)
 }
 
-// SetTournamentWinner mocks base method
-func (_m *MockService) SetTournamentWinner(ctx context.Context, tournamentID string, userID string) error {
-	ret := _m.ctrl.Call(_m, "SetTournamentWinner", ctx, tournamentID, userID)
+// SetPasswordHash mocks base method.
+func (m *MockService) SetPasswordHash(ctx context.Context, userID string, passwordHash []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPasswordHash", ctx, userID, passwordHash)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetTournamentWinner indicates an expected call of SetTournamentWinner
-func (_mr *MockServiceMockRecorder) SetTournamentWinner(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "SetTournamentWinner", reflect.TypeOf((*MockService)(nil).SetTournamentWinner), arg0, arg1, arg2)
+// SetPasswordHash indicates an expected call of SetPasswordHash.
+func (mr *MockServiceMockRecorder) SetPasswordHash(ctx, userID, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPasswordHash", reflect.TypeOf((*MockService)(nil).SetPasswordHash), ctx, userID, passwordHash)
 }
 
-// SetTournamentStatus mocks base method
-func (_m *MockService) SetTournamentStatus(ctx context.Context, tournamentID string, status TournamentStatus) error {
-	ret := _m.ctrl.Call(_m, "SetTournamentStatus", ctx, tournamentID, status)
+// SetTournamentStatus mocks base method.
+func (m *MockService) SetTournamentStatus(ctx context.Context, tournamentID string, status TournamentStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTournamentStatus", ctx, tournamentID, status)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetTournamentStatus indicates an expected call of SetTournamentStatus
-func (_mr *MockServiceMockRecorder) SetTournamentStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "SetTournamentStatus", reflect.TypeOf((*MockService)(nil).SetTournamentStatus), arg0, arg1, arg2)
+// SetTournamentStatus indicates an expected call of SetTournamentStatus.
+func (mr *MockServiceMockRecorder) SetTournamentStatus(ctx, tournamentID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTournamentStatus", reflect.TypeOf((*MockService)(nil).SetTournamentStatus), ctx, tournamentID, status)
 }
 
-// AddUserToTournamentList mocks base method
-func (_m *MockService) AddUserToTournamentList(ctx context.Context, tournamentID string, userID string) error {
-	ret := _m.ctrl.Call(_m, "AddUserToTournamentList", ctx, tournamentID, userID)
+// SetTournamentWinner mocks base method.
+func (m *MockService) SetTournamentWinner(ctx context.Context, tournamentID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTournamentWinner", ctx, tournamentID, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// AddUserToTournamentList indicates an expected call of AddUserToTournamentList
-func (_mr *MockServiceMockRecorder) AddUserToTournamentList(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "AddUserToTournamentList", reflect.TypeOf((*MockService)(nil).AddUserToTournamentList), arg0, arg1, arg2)
+// SetTournamentWinner indicates an expected call of SetTournamentWinner.
+func (mr *MockServiceMockRecorder) SetTournamentWinner(ctx, tournamentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTournamentWinner", reflect.TypeOf((*MockService)(nil).SetTournamentWinner), ctx, tournamentID, userID)
 }
 
-// JoinTournament mocks base method
-func (_m *MockService) JoinTournament(ctx context.Context, tournamentID string, userID string) error {
-	ret := _m.ctrl.Call(_m, "JoinTournament", ctx, tournamentID, userID)
+// SetUserRole mocks base method.
+func (m *MockService) SetUserRole(ctx context.Context, id string, role Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, id, role)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// JoinTournament indicates an expected call of JoinTournament
-func (_mr *MockServiceMockRecorder) JoinTournament(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "JoinTournament", reflect.TypeOf((*MockService)(nil).JoinTournament), arg0, arg1, arg2)
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockServiceMockRecorder) SetUserRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockService)(nil).SetUserRole), ctx, id, role)
 }
 
-// FinishTournament mocks base method
-func (_m *MockService) FinishTournament(ctx context.Context, tournamentID string, winnerUserID string) error {
-	ret := _m.ctrl.Call(_m, "FinishTournament", ctx, tournamentID, winnerUserID)
+// TakeUserBalance mocks base method.
+func (m *MockService) TakeUserBalance(ctx context.Context, id string, points float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeUserBalance", ctx, id, points)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// FinishTournament indicates an expected call of FinishTournament
-func (_mr *MockServiceMockRecorder) FinishTournament(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "FinishTournament", reflect.TypeOf((*MockService)(nil).FinishTournament), arg0, arg1, arg2)
+// TakeUserBalance indicates an expected call of TakeUserBalance.
+func (mr *MockServiceMockRecorder) TakeUserBalance(ctx, id, points interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeUserBalance", reflect.TypeOf((*MockService)(nil).TakeUserBalance), ctx, id, points)
 }
@@ -17,10 +17,23 @@ import (
 )
 
 var (
-	client      *mongo.Client
-	db          *DB
-	users       *mongo.Collection
-	tournaments *mongo.Collection
+	client        *mongo.Client
+	db            *DB
+	users         *mongo.Collection
+	tournaments   *mongo.Collection
+	credentials   *mongo.Collection
+	sessions      *mongo.Collection
+	outbox        *mongo.Collection
+	counters      *mongo.Collection
+	cursors       *mongo.Collection
+	locks         *mongo.Collection
+	webhooks      *mongo.Collection
+	deliveries    *mongo.Collection
+	notifications *mongo.Collection
+	emails        *mongo.Collection
+	audit         *mongo.Collection
+	exports       *mongo.Collection
+	migrations    *mongo.Collection
 )
 
 const (
@@ -145,6 +158,22 @@ func setupDB() (*Container, error) {
 		db = CreateNew(client.Database(dbName))
 		users = client.Database(dbName).Collection(usersCollectionName)
 		tournaments = client.Database(dbName).Collection(tournamentsCollectionName)
+		credentials = client.Database(dbName).Collection(credentialsCollectionName)
+		sessions = client.Database(dbName).Collection(sessionsCollectionName)
+		outbox = client.Database(dbName).Collection(outboxCollectionName)
+		counters = client.Database(dbName).Collection(countersCollectionName)
+		cursors = client.Database(dbName).Collection(cursorsCollectionName)
+		locks = client.Database(dbName).Collection(locksCollectionName)
+		webhooks = client.Database(dbName).Collection(webhooksCollectionName)
+		deliveries = client.Database(dbName).Collection(deliveriesCollectionName)
+		notifications = client.Database(dbName).Collection(notificationsCollectionName)
+		emails = client.Database(dbName).Collection(emailsCollectionName)
+		audit = client.Database(dbName).Collection(auditCollectionName)
+		exports = client.Database(dbName).Collection(exportsCollectionName)
+		migrations = client.Database(dbName).Collection(migrationsCollectionName)
+		if err = db.CreateCollections(context.TODO()); err != nil {
+			return nil, errors.Wrap(err, "create collections")
+		}
 
 		break
 	}
@@ -158,4 +187,52 @@ func cleanUp(t *testing.T) {
 
 	err = tournaments.Drop(context.TODO())
 	require.NoError(t, err)
+
+	err = credentials.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = sessions.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = outbox.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = counters.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = cursors.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = locks.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = webhooks.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = deliveries.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = notifications.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = emails.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = audit.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = exports.Drop(context.TODO())
+	require.NoError(t, err)
+
+	err = migrations.Drop(context.TODO())
+	require.NoError(t, err)
+
+	bucket, err := db.exportsBucket()
+	require.NoError(t, err)
+	err = bucket.Drop()
+	require.NoError(t, err)
+
+	// MongoDB 4.0 used in tests can't create collections inside transactions
+	err = db.CreateCollections(context.TODO())
+	require.NoError(t, err)
 }
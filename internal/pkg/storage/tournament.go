@@ -2,24 +2,33 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Tournament represents a competition between players
 // with deposit to enter and prize as a product of number
 // of all players by deposit for winner.
 type Tournament struct {
-	ID      primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
-	Name    string               `json:"name" bson:"name"`
-	Deposit float64              `json:"deposit" bson:"deposit"`
-	Status  TournamentStatus     `json:"status" bson:"status"`
-	Prize   float64              `json:"prize" bson:"prize"`
-	Users   []primitive.ObjectID `json:"users" bson:"users"`
-	Winner  primitive.ObjectID   `json:"winner" bson:"winner"`
+	ID        primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Name      string               `json:"name" bson:"name"`
+	Deposit   float64              `json:"deposit" bson:"deposit"`
+	Status    TournamentStatus     `json:"status" bson:"status"`
+	Prize     float64              `json:"prize" bson:"prize"`
+	Users     []primitive.ObjectID `json:"users" bson:"users"`
+	Winner    primitive.ObjectID   `json:"winner" bson:"winner"`
+	Organizer primitive.ObjectID   `json:"organizer" bson:"organizer"`
+
+	// StartsAt is when organizer plans to start tournament. Players are
+	// reminded by email shortly before it.
+	StartsAt   *time.Time `json:"startsAt,omitempty" bson:"startsAt,omitempty"`
+	RemindedAt *time.Time `json:"-" bson:"remindedAt,omitempty"`
 }
 
 type TournamentStatus string
@@ -30,31 +39,82 @@ const (
 	StatusSignIn   TournamentStatus = "signIn"
 )
 
-// AddTournament func fills tournament info with provided name, provided deposit
+// ErrTournamentStatus is returned when tournament status doesn't allow
+// requested change, e.g. finished tournament is started again.
+var ErrTournamentStatus = errors.New("tournament status doesn't allow this change")
+
+// ErrWinnerNotJoined is returned when winner of tournament isn't its player.
+var ErrWinnerNotJoined = errors.New("winner hasn't joined tournament")
+
+// previousStatuses lists statuses tournament can be moved to status from.
+var previousStatuses = map[TournamentStatus]bson.A{
+	StatusStarted:  {StatusSignIn},
+	StatusFinished: {StatusStarted},
+}
+
+// Valid reports whether status is one of the known statuses.
+func (s TournamentStatus) Valid() bool {
+	switch s {
+	case StatusSignIn, StatusStarted, StatusFinished:
+		return true
+	}
+	return false
+}
+
+// AddTournament func fills tournament info with provided name, provided deposit, organizer
 // and with automatically generated id, then adds generated tournament info to database.
 // It returns added tournamentID in string format if succeed and null string and err if smth wrong.
-func (db *DB) AddTournament(ctx context.Context, name string, deposit float64) (string, error) {
-	insertResult, err := db.conn.Collection(tournamentsCollectionName).InsertOne(ctx, Tournament{
-		Name:    name,
-		Deposit: deposit,
-		Users:   []primitive.ObjectID{},
-	})
+func (db *DB) AddTournament(ctx context.Context, name string, deposit float64,
+	organizerID string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AddTournament")
+	defer func() { endSpan(span, err) }()
+
+	primOrganizerID, err := primitive.ObjectIDFromHex(organizerID)
 	if err != nil {
-		return "", errors.Wrap(err, "insert doc to collection")
+		return "", errors.Wrapf(err, "convert string %s to primitive.ObjectID type", organizerID)
 	}
 
-	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return "", errors.New("convert inserted id to primitive.ObjectID")
+	var tournamentID string
+	err = db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		insertResult, err := db.conn.Collection(tournamentsCollectionName).InsertOne(sc, Tournament{
+			Name:      name,
+			Deposit:   deposit,
+			Status:    StatusSignIn,
+			Users:     []primitive.ObjectID{},
+			Organizer: primOrganizerID,
+		})
+		if err != nil {
+			return errors.Wrap(err, "insert doc to collection")
+		}
+
+		insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+		if !ok {
+			return errors.New("convert inserted id to primitive.ObjectID")
+		}
+		tournamentID = insertedID.Hex()
+
+		return db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventTournamentCreated,
+			TournamentID: tournamentID,
+			UserID:       organizerID,
+			Name:         name,
+			Amount:       deposit,
+		})
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return insertedID.Hex(), nil
+	return tournamentID, nil
 }
 
 // GetTournament func tries to find tournament with provided id string.
 // If succeed it returns *Tournament and nil error. If smth wrong it
 // returns nil *Tournament and corresponding error.
-func (db *DB) GetTournament(ctx context.Context, id string) (*Tournament, error) {
+func (db *DB) GetTournament(ctx context.Context, id string) (_ *Tournament, err error) {
+	ctx, span := startSpan(ctx, "GetTournament")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
@@ -74,29 +134,95 @@ func (db *DB) GetTournament(ctx context.Context, id string) (*Tournament, error)
 }
 
 // DeleteTournament func tries to delete tournament with provided id string.
+// Finished tournament is kept for history of prizes, ErrTournamentStatus is returned for it.
 // If smth wrong it returns corresponding error, and nil error otherwise.
-func (db *DB) DeleteTournament(ctx context.Context, id string) error {
+func (db *DB) DeleteTournament(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteTournament")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
 	}
 
-	docDeleted, err := db.conn.Collection(tournamentsCollectionName).DeleteOne(ctx, bson.M{"_id": primID})
+	entry := AuditEntry{Action: AuditTournamentCancelled, TargetIDs: []string{id}}
+	return db.inAuditedTransaction(ctx, entry, tournamentsCollectionName, func(sc context.Context) error {
+		docDeleted := db.conn.Collection(tournamentsCollectionName).FindOneAndDelete(sc,
+			bson.M{"_id": primID, "status": bson.M{"$ne": StatusFinished}})
+		if errors.Is(docDeleted.Err(), mongo.ErrNoDocuments) {
+			count, err := db.conn.Collection(tournamentsCollectionName).CountDocuments(sc, bson.M{"_id": primID})
+			if err != nil {
+				return errors.Wrap(err, "count docs in collection")
+			}
+			if count > 0 {
+				return errors.Wrap(ErrTournamentStatus, "cancel finished tournament")
+			}
+			return errors.Wrap(mongo.ErrNoDocuments, "delete doc from collection")
+		}
+		if err := docDeleted.Err(); err != nil {
+			return errors.Wrap(err, "delete doc from collection")
+		}
+
+		var tournament Tournament
+		if err := docDeleted.Decode(&tournament); err != nil {
+			return errors.Wrap(err, "decode deleted doc")
+		}
+
+		return db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventTournamentCancelled,
+			TournamentID: id,
+			UserIDs:      hexIDs(tournament.Users),
+		})
+	})
+}
+
+// AddUserToTournamentList func adds user with provided id to tournament users list with provided id.
+// Return error if smth wrong and nil if everything is ok.
+// userID and tournamentID should be correct ObjectID according to MongoDB docs.
+func (db *DB) AddUserToTournamentList(ctx context.Context, tournamentID, userID string) (err error) {
+	ctx, span := startSpan(ctx, "AddUserToTournamentList")
+	defer func() { endSpan(span, err) }()
+
+	primTournamentID, err := primitive.ObjectIDFromHex(tournamentID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", tournamentID)
+	}
+
+	primUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", userID)
+	}
+
+	update := bson.D{
+		{"$addToSet", bson.D{
+			{"users", primUserID},
+		}},
+	}
+	// players may join only while sign in is open
+	updateResult, err := db.conn.Collection(tournamentsCollectionName).UpdateOne(ctx,
+		bson.M{"_id": primTournamentID, "status": StatusSignIn}, update)
 	if err != nil {
-		return errors.Wrap(err, "delete doc from collection")
+		return errors.Wrap(err, "update doc in collection")
 	}
 
-	if docDeleted.DeletedCount != 1 {
-		return errors.New("delete doc from collection: DeletedCount != 1")
+	if updateResult.MatchedCount != 1 {
+		return db.unmatchedTournament(ctx, primTournamentID,
+			errors.Wrap(ErrTournamentStatus, "join tournament which isn't in signIn"))
+	}
+	if updateResult.ModifiedCount != 1 {
+		return errors.New("update doc in collection: user is already in tournament users list")
 	}
 
 	return nil
 }
 
-// AddUserToTournamentList func adds user with provided id to tournament users list with provided id.
+// RemoveUserFromTournamentList func removes user with provided id from tournament users list with provided id.
 // Return error if smth wrong and nil if everything is ok.
 // userID and tournamentID should be correct ObjectID according to MongoDB docs.
-func (db *DB) AddUserToTournamentList(ctx context.Context, tournamentID, userID string) error {
+func (db *DB) RemoveUserFromTournamentList(ctx context.Context, tournamentID, userID string) (err error) {
+	ctx, span := startSpan(ctx, "RemoveUserFromTournamentList")
+	defer func() { endSpan(span, err) }()
+
 	primTournamentID, err := primitive.ObjectIDFromHex(tournamentID)
 	if err != nil {
 		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", tournamentID)
@@ -108,7 +234,7 @@ func (db *DB) AddUserToTournamentList(ctx context.Context, tournamentID, userID
 	}
 
 	update := bson.D{
-		{"$addToSet", bson.D{
+		{"$pull", bson.D{
 			{"users", primUserID},
 		}},
 	}
@@ -119,16 +245,20 @@ func (db *DB) AddUserToTournamentList(ctx context.Context, tournamentID, userID
 	}
 
 	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
 	}
 
 	return nil
 }
 
 // SetTournamentWinner func sets winner of tournament found by tournamentID to user with userID.
+// Returns ErrWinnerNotJoined if user isn't in tournament users list.
 // Return error if smth wrong and nil if everything is ok.
 // userID and tournamentID should be correct ObjectID according to MongoDB docs.
-func (db *DB) SetTournamentWinner(ctx context.Context, tournamentID, userID string) error {
+func (db *DB) SetTournamentWinner(ctx context.Context, tournamentID, userID string) (err error) {
+	ctx, span := startSpan(ctx, "SetTournamentWinner")
+	defer func() { endSpan(span, err) }()
+
 	primTournamentID, err := primitive.ObjectIDFromHex(tournamentID)
 	if err != nil {
 		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", tournamentID)
@@ -145,13 +275,13 @@ func (db *DB) SetTournamentWinner(ctx context.Context, tournamentID, userID stri
 		}},
 	}
 	updateResult, err := db.conn.Collection(tournamentsCollectionName).UpdateOne(ctx,
-		bson.M{"_id": primTournamentID}, update)
+		bson.M{"_id": primTournamentID, "users": primUserID}, update)
 	if err != nil {
 		return errors.Wrap(err, "update doc in collection")
 	}
 
-	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+	if updateResult.MatchedCount != 1 {
+		return db.unmatchedTournament(ctx, primTournamentID, ErrWinnerNotJoined)
 	}
 
 	return nil
@@ -160,7 +290,10 @@ func (db *DB) SetTournamentWinner(ctx context.Context, tournamentID, userID stri
 // IncreaseTournamentPrize func increase tournament's with provided id prize by provided amount.
 // Return error if smth wrong and nil if everything is ok.
 // id should be correct ObjectID according to MongoDB docs.
-func (db *DB) IncreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
+func (db *DB) IncreaseTournamentPrize(ctx context.Context, id string, amount float64) (err error) {
+	ctx, span := startSpan(ctx, "IncreaseTournamentPrize")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", id)
@@ -177,7 +310,7 @@ func (db *DB) IncreaseTournamentPrize(ctx context.Context, id string, amount flo
 	}
 
 	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
 	}
 
 	return nil
@@ -186,7 +319,10 @@ func (db *DB) IncreaseTournamentPrize(ctx context.Context, id string, amount flo
 // DecreaseTournamentPrize func decrease tournament's with provided id prize by provided amount.
 // Return error if smth wrong and nil if everything is ok.
 // id should be correct ObjectID according to MongoDB docs.
-func (db *DB) DecreaseTournamentPrize(ctx context.Context, id string, amount float64) error {
+func (db *DB) DecreaseTournamentPrize(ctx context.Context, id string, amount float64) (err error) {
+	ctx, span := startSpan(ctx, "DecreaseTournamentPrize")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", id)
@@ -203,34 +339,226 @@ func (db *DB) DecreaseTournamentPrize(ctx context.Context, id string, amount flo
 	}
 
 	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
 	}
 
 	return nil
 }
 
-// SetTournamentStatus func sets tournament's with "id" status to "status"
+// SetTournamentStatus func sets tournament's with "id" status to "status".
+// Tournament is only moved forward from signIn to started and from started
+// to finished, ErrTournamentStatus is returned otherwise.
 // Return error if smth wrong and nil if everything is ok.
 // tournamentID should be correct ObjectID according to MongoDB docs.
-func (db *DB) SetTournamentStatus(ctx context.Context, tournamentID string, status TournamentStatus) error {
+func (db *DB) SetTournamentStatus(ctx context.Context, tournamentID string, status TournamentStatus) (err error) {
+	ctx, span := startSpan(ctx, "SetTournamentStatus")
+	defer func() { endSpan(span, err) }()
+
 	primTournamentID, err := primitive.ObjectIDFromHex(tournamentID)
 	if err != nil {
 		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", tournamentID)
 	}
 
+	previous, ok := previousStatuses[status]
+	if !ok {
+		return errors.Wrapf(ErrTournamentStatus, "set status %q", status)
+	}
+
 	update := bson.D{
 		{"$set", bson.D{
 			{"status", status},
 		}},
 	}
+
+	// finish is recorded by FinishTournament together with winner
+	entry := AuditEntry{Action: AuditTournamentStarted, TargetIDs: []string{tournamentID}}
+	if status != StatusStarted {
+		ctx = context.WithValue(ctx, auditKey{}, nil)
+	}
+
+	return db.inAuditedTransaction(ctx, entry, tournamentsCollectionName, func(sc context.Context) error {
+		docUpdated := db.conn.Collection(tournamentsCollectionName).FindOneAndUpdate(sc,
+			bson.M{"_id": primTournamentID, "status": bson.M{"$in": previous}}, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After))
+		if errors.Is(docUpdated.Err(), mongo.ErrNoDocuments) {
+			return db.unmatchedTournament(sc, primTournamentID,
+				errors.Wrapf(ErrTournamentStatus, "set status %q", status))
+		}
+		if err := docUpdated.Err(); err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+
+		if status != StatusStarted {
+			return nil
+		}
+
+		var tournament Tournament
+		if err := docUpdated.Decode(&tournament); err != nil {
+			return errors.Wrap(err, "decode updated doc")
+		}
+
+		return db.addOutboxEvent(sc, OutboxEvent{
+			Type:         EventTournamentStarted,
+			TournamentID: tournamentID,
+			UserIDs:      hexIDs(tournament.Users),
+		})
+	})
+}
+
+// unmatchedTournament tells apart missing tournament and tournament which
+// doesn't satisfy precondition of update that matched no doc. It returns
+// precondition error if tournament with id exists.
+func (db *DB) unmatchedTournament(ctx context.Context, id primitive.ObjectID, precondition error) error {
+	count, err := db.conn.Collection(tournamentsCollectionName).CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return errors.Wrap(err, "count docs in collection")
+	}
+	if count == 0 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return precondition
+}
+
+// CountTournamentsByStatus func returns number of tournaments per status.
+func (db *DB) CountTournamentsByStatus(ctx context.Context) (_ map[TournamentStatus]int64, err error) {
+	ctx, span := startSpan(ctx, "CountTournamentsByStatus")
+	defer func() { endSpan(span, err) }()
+
+	pipeline := mongo.Pipeline{
+		{{"$group", bson.D{
+			{"_id", "$status"},
+			{"count", bson.D{{"$sum", 1}}},
+		}}},
+	}
+	cursor, err := db.conn.Collection(tournamentsCollectionName).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, "aggregate docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Status TournamentStatus `bson:"_id"`
+		Count  int64            `bson:"count"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	counts := make(map[TournamentStatus]int64, len(groups))
+	for _, g := range groups {
+		counts[g.Status] = g.Count
+	}
+
+	return counts, nil
+}
+
+// ListTournaments func returns up to limit tournaments with provided status,
+// newest first. Empty status matches all tournaments.
+func (db *DB) ListTournaments(ctx context.Context, status TournamentStatus,
+	limit int64) (_ []Tournament, err error) {
+	ctx, span := startSpan(ctx, "ListTournaments")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(tournamentsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	tournaments := []Tournament{}
+	if err = cursor.All(ctx, &tournaments); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return tournaments, nil
+}
+
+// ScheduleTournament func sets planned start of tournament with provided id
+// string. Reminder is sent again for new time if it was sent for previous
+// one. Only tournament which is signing in can be scheduled, ErrTournamentStatus
+// is returned otherwise.
+func (db *DB) ScheduleTournament(ctx context.Context, tournamentID string, startsAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "ScheduleTournament")
+	defer func() { endSpan(span, err) }()
+
+	primTournamentID, err := primitive.ObjectIDFromHex(tournamentID)
+	if err != nil {
+		return errors.Wrapf(err, "convert string %s to primitive.ObjectID type", tournamentID)
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"startsAt", startsAt.UTC()},
+		}},
+		{"$unset", bson.D{
+			{"remindedAt", ""},
+		}},
+	}
 	updateResult, err := db.conn.Collection(tournamentsCollectionName).UpdateOne(ctx,
-		bson.M{"_id": primTournamentID}, update)
+		bson.M{"_id": primTournamentID, "status": StatusSignIn}, update)
 	if err != nil {
 		return errors.Wrap(err, "update doc in collection")
 	}
 
-	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+	if updateResult.MatchedCount != 1 {
+		return db.unmatchedTournament(ctx, primTournamentID,
+			errors.Wrap(ErrTournamentStatus, "schedule tournament"))
+	}
+
+	return nil
+}
+
+// DueTournamentReminders func returns up to limit tournaments which are
+// signing in, start from now to provided time and whose players aren't
+// reminded yet, earliest first.
+func (db *DB) DueTournamentReminders(ctx context.Context, now, before time.Time,
+	limit int64) (_ []Tournament, err error) {
+	ctx, span := startSpan(ctx, "DueTournamentReminders")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{
+		"status":     StatusSignIn,
+		"startsAt":   bson.M{"$gt": now, "$lte": before},
+		"remindedAt": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{"startsAt", 1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(tournamentsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	var due []Tournament
+	if err = cursor.All(ctx, &due); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return due, nil
+}
+
+// MarkTournamentReminded func records that players of tournament with provided
+// id were reminded about start at provided time. Tournament rescheduled in the
+// meantime is left as is, so it's reminded about new time.
+func (db *DB) MarkTournamentReminded(ctx context.Context, id primitive.ObjectID, startsAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "MarkTournamentReminded")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"remindedAt", time.Now().UTC()},
+		}},
+	}
+	_, err = db.conn.Collection(tournamentsCollectionName).UpdateOne(ctx,
+		bson.M{"_id": id, "startsAt": startsAt}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
 	}
 
 	return nil
@@ -4,16 +4,20 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/stretchr/testify/require"
 )
 
+var organizerID = primitive.NewObjectID()
+
 func TestAddTournament(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -24,10 +28,12 @@ func TestAddTournament(t *testing.T) {
 	require.NoError(err)
 
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{},
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -38,7 +44,7 @@ func TestAddTournament(t *testing.T) {
 func TestGetTournament(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -49,10 +55,12 @@ func TestGetTournament(t *testing.T) {
 	require.NoError(err)
 
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{},
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -73,7 +81,7 @@ func TestGetTournament(t *testing.T) {
 func TestDeleteTournament(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -86,7 +94,16 @@ func TestDeleteTournament(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.DeleteTournament(context.TODO(), notExistTournamentID)
-	require.EqualError(err, "delete doc from collection: DeletedCount != 1")
+	require.EqualError(err, "delete doc from collection: mongo: no documents in result")
+
+	finishedID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.SetTournamentStatus(context.TODO(), finishedID, StatusStarted))
+	require.NoError(db.SetTournamentStatus(context.TODO(), finishedID, StatusFinished))
+	err = db.DeleteTournament(context.TODO(), finishedID)
+	require.ErrorIs(err, ErrTournamentStatus, "Finished tournament should not be cancelled")
+	_, err = db.GetTournament(context.TODO(), finishedID)
+	require.NoError(err, "Finished tournament should be kept")
 
 	cleanUp(t)
 }
@@ -94,7 +111,7 @@ func TestDeleteTournament(t *testing.T) {
 func TestAddUserToTournamentList(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -109,10 +126,12 @@ func TestAddUserToTournamentList(t *testing.T) {
 	require.NoError(err)
 
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{userID},
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{userID},
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -127,7 +146,7 @@ func TestAddUserToTournamentList(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.AddUserToTournamentList(context.TODO(), notExistTournamentID, userID.Hex())
-	require.EqualError(err, "update doc in collection: ModifiedCount != 1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
 
 	cleanUp(t)
 }
@@ -135,12 +154,17 @@ func TestAddUserToTournamentList(t *testing.T) {
 func TestSetTournamentWinner(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
 	userWinnerID := primitive.NewObjectID()
 	err = db.SetTournamentWinner(context.TODO(), expectedTournamentID, userWinnerID.Hex())
+	require.ErrorIs(err, ErrWinnerNotJoined, "Winner should be player of tournament")
+
+	err = db.AddUserToTournamentList(context.TODO(), expectedTournamentID, userWinnerID.Hex())
+	require.NoError(err)
+	err = db.SetTournamentWinner(context.TODO(), expectedTournamentID, userWinnerID.Hex())
 	require.NoError(err)
 
 	actualTournament, err := db.GetTournament(context.TODO(), expectedTournamentID)
@@ -150,11 +174,13 @@ func TestSetTournamentWinner(t *testing.T) {
 	require.NoError(err)
 
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
-		Winner:  userWinnerID,
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{userWinnerID},
+		Winner:    userWinnerID,
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -169,7 +195,7 @@ func TestSetTournamentWinner(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.SetTournamentWinner(context.TODO(), notExistTournamentID, userWinnerID.Hex())
-	require.EqualError(err, "update doc in collection: ModifiedCount != 1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
 
 	cleanUp(t)
 }
@@ -177,7 +203,7 @@ func TestSetTournamentWinner(t *testing.T) {
 func TestIncreaseTournamentPrize(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -193,11 +219,13 @@ func TestIncreaseTournamentPrize(t *testing.T) {
 
 	expectedTournamentPrize := 1000.0
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
-		Prize:   expectedTournamentPrize,
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{},
+		Prize:     expectedTournamentPrize,
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -208,7 +236,7 @@ func TestIncreaseTournamentPrize(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.IncreaseTournamentPrize(context.TODO(), notExistTournamentID, incAmount)
-	require.EqualError(err, "update doc in collection: ModifiedCount != 1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
 
 	cleanUp(t)
 }
@@ -216,7 +244,7 @@ func TestIncreaseTournamentPrize(t *testing.T) {
 func TestDecreaseTournamentPrize(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -236,11 +264,13 @@ func TestDecreaseTournamentPrize(t *testing.T) {
 
 	expectedTournamentPrize := 750.0
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
-		Prize:   expectedTournamentPrize,
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{},
+		Prize:     expectedTournamentPrize,
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -251,7 +281,7 @@ func TestDecreaseTournamentPrize(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.DecreaseTournamentPrize(context.TODO(), notExistTournamentID, decAmount)
-	require.EqualError(err, "update doc in collection: ModifiedCount != 1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
 
 	cleanUp(t)
 }
@@ -259,13 +289,22 @@ func TestDecreaseTournamentPrize(t *testing.T) {
 func TestSetTournamentStatus(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
 	expectedStatus := StatusFinished
 	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, expectedStatus)
+	require.ErrorIs(err, ErrTournamentStatus, "Tournament should not be finished before start")
+
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusStarted)
+	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, expectedStatus)
 	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusStarted)
+	require.ErrorIs(err, ErrTournamentStatus, "Finished tournament should not be started again")
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusSignIn)
+	require.ErrorIs(err, ErrTournamentStatus)
 
 	actualTournament, err := db.GetTournament(context.TODO(), expectedTournamentID)
 	require.NoError(err)
@@ -274,11 +313,12 @@ func TestSetTournamentStatus(t *testing.T) {
 	require.NoError(err)
 
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{},
-		Status:  expectedStatus,
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Users:     []primitive.ObjectID{},
+		Status:    expectedStatus,
+		Organizer: organizerID,
 	}
 
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
@@ -289,7 +329,7 @@ func TestSetTournamentStatus(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	err = db.SetTournamentStatus(context.TODO(), notExistTournamentID, expectedStatus)
-	require.EqualError(err, "update doc in collection: ModifiedCount != 1")
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
 
 	cleanUp(t)
 }
@@ -297,11 +337,14 @@ func TestSetTournamentStatus(t *testing.T) {
 func TestJoinTournament(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
-	userJoinTorneyID := primitive.NewObjectID()
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	userJoinTorneyID, err := primitive.ObjectIDFromHex(userID)
+	require.NoError(err)
 	err = db.JoinTournament(context.TODO(), expectedTournamentID, userJoinTorneyID.Hex())
 	require.NoError(err)
 
@@ -313,11 +356,13 @@ func TestJoinTournament(t *testing.T) {
 
 	expectedTournamentPrize := expectedTournamentDeposit
 	expectedTournament := Tournament{
-		ID:      expectedTournamentObjID,
-		Name:    expectedTournamentName,
-		Deposit: expectedTournamentDeposit,
-		Users:   []primitive.ObjectID{userJoinTorneyID},
-		Prize:   expectedTournamentPrize,
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{userJoinTorneyID},
+		Prize:     expectedTournamentPrize,
+		Organizer: organizerID,
 	}
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
 
@@ -333,16 +378,51 @@ func TestJoinTournament(t *testing.T) {
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	actualErr = db.JoinTournament(context.TODO(), notExistTournamentID, userJoinTorneyID.Hex())
-	expectedErr = "error processing transaction: AddUserToTournamentList: update doc in collection: ModifiedCount != 1"
+	expectedErr = "error processing transaction: AddUserToTournamentList: update doc in collection: mongo: no documents in result"
 	require.EqualError(actualErr, expectedErr, "The two errors should be the same")
 
+	actualErr = db.JoinTournament(context.TODO(), expectedTournamentID, primitive.NewObjectID().Hex())
+	require.ErrorIs(actualErr, mongo.ErrNoDocuments, "Not existing user should not join")
+
+	cleanUp(t)
+}
+
+func TestJoinTournament_Status(t *testing.T) {
+	require := require.New(t)
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	otherID, err := db.AddUser(context.TODO(), "Petya")
+	require.NoError(err)
+
+	startedID, err := db.AddTournament(context.TODO(), "tournament-1", 1000, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.JoinTournament(context.TODO(), startedID, userID))
+	require.NoError(db.SetTournamentStatus(context.TODO(), startedID, StatusStarted))
+	err = db.JoinTournament(context.TODO(), startedID, otherID)
+	require.ErrorIs(err, ErrTournamentStatus, "Player should not join started tournament")
+
+	finishedID, err := db.AddTournament(context.TODO(), "tournament-2", 1000, organizerID.Hex())
+	require.NoError(err)
+	require.NoError(db.JoinTournament(context.TODO(), finishedID, userID))
+	require.NoError(db.SetTournamentStatus(context.TODO(), finishedID, StatusStarted))
+	require.NoError(db.FinishTournament(context.TODO(), finishedID, userID))
+	err = db.JoinTournament(context.TODO(), finishedID, otherID)
+	require.ErrorIs(err, ErrTournamentStatus, "Player should not join finished tournament")
+
+	for _, id := range []string{startedID, finishedID} {
+		tournament, err := db.GetTournament(context.TODO(), id)
+		require.NoError(err)
+		require.Len(tournament.Users, 1, "Rejected player should not be added")
+		require.Equal(1000.0, tournament.Prize, "Prize should not grow after sign in")
+	}
+
 	cleanUp(t)
 }
 
 func TestFinishTournament(t *testing.T) {
 	expectedTournamentName := "tournament-1"
 	expectedTournamentDeposit := 1000.0
-	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit)
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
 	require := require.New(t)
 	require.NoError(err)
 
@@ -353,6 +433,15 @@ func TestFinishTournament(t *testing.T) {
 	err = db.JoinTournament(context.TODO(), expectedTournamentID, expectedUserID)
 	require.NoError(err)
 
+	err = db.FinishTournament(context.TODO(), expectedTournamentID, expectedUserID)
+	require.ErrorIs(err, ErrTournamentStatus, "Tournament should not be finished before start")
+
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusStarted)
+	require.NoError(err)
+
+	err = db.FinishTournament(context.TODO(), expectedTournamentID, primitive.NewObjectID().Hex())
+	require.ErrorIs(err, ErrWinnerNotJoined, "Winner should be player of tournament")
+
 	err = db.FinishTournament(context.TODO(), expectedTournamentID, expectedUserID)
 	require.NoError(err)
 
@@ -370,9 +459,10 @@ func TestFinishTournament(t *testing.T) {
 		Users: []primitive.ObjectID{
 			expectedUsrID,
 		},
-		Status: StatusFinished,
-		Winner: expectedUsrID,
-		Prize:  expectedTournamentDeposit,
+		Status:    StatusFinished,
+		Winner:    expectedUsrID,
+		Prize:     expectedTournamentDeposit,
+		Organizer: organizerID,
 	}
 	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
 
@@ -383,6 +473,7 @@ func TestFinishTournament(t *testing.T) {
 		ID:      expectedUsrID,
 		Name:    expectedUserName,
 		Balance: expectedTournamentDeposit,
+		Role:    RolePlayer,
 	}
 	require.Equal(expectedUser, *actualUser, "The two user objects should be the same")
 
@@ -391,18 +482,185 @@ func TestFinishTournament(t *testing.T) {
 	expectedErr := fmt.Sprintf("error processing transaction: SetTournamentStatus: convert string %s to primitive.ObjectID type: encoding/hex: invalid byte: U+005F '_'", badTournamentID)
 	require.EqualError(actualErr, expectedErr, "The two errors should be the same")
 
-	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusStarted)
+	actualErr = db.FinishTournament(context.TODO(), expectedTournamentID, expectedUserID)
+	require.ErrorIs(actualErr, ErrTournamentStatus, "Prize should not be paid twice")
+
+	startedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
+	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), startedTournamentID, StatusStarted)
 	require.NoError(err)
 
 	badUserID := "bad_user_id"
-	actualErr = db.FinishTournament(context.TODO(), expectedTournamentID, badUserID)
+	actualErr = db.FinishTournament(context.TODO(), startedTournamentID, badUserID)
 	expectedErr = fmt.Sprintf("error processing transaction: SetTournamentWinner: convert string %s to primitive.ObjectID type: encoding/hex: invalid byte: U+005F '_'", badUserID)
 	require.EqualError(actualErr, expectedErr, "The two errors should be the same")
 
 	notExistTournamentID := primitive.NewObjectID().Hex()
 	actualErr = db.FinishTournament(context.TODO(), notExistTournamentID, expectedUserID)
-	expectedErr = "error processing transaction: SetTournamentStatus: update doc in collection: ModifiedCount != 1"
+	expectedErr = "error processing transaction: SetTournamentStatus: update doc in collection: mongo: no documents in result"
 	require.EqualError(actualErr, expectedErr, "The two errors should be the same")
 
 	cleanUp(t)
 }
+
+func TestRemoveUserFromTournamentList(t *testing.T) {
+	expectedTournamentName := "tournament-1"
+	expectedTournamentDeposit := 1000.0
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
+	require := require.New(t)
+	require.NoError(err)
+
+	userID := primitive.NewObjectID()
+	err = db.AddUserToTournamentList(context.TODO(), expectedTournamentID, userID.Hex())
+	require.NoError(err)
+
+	err = db.RemoveUserFromTournamentList(context.TODO(), expectedTournamentID, userID.Hex())
+	require.NoError(err)
+
+	actualTournament, err := db.GetTournament(context.TODO(), expectedTournamentID)
+	require.NoError(err)
+	require.Empty(actualTournament.Users, "Tournament users list should be empty")
+
+	err = db.RemoveUserFromTournamentList(context.TODO(), expectedTournamentID, userID.Hex())
+	require.EqualError(err, "update doc in collection: mongo: no documents in result")
+
+	badUserID := "bad_user_id"
+	err = db.RemoveUserFromTournamentList(context.TODO(), expectedTournamentID, badUserID)
+	require.EqualError(err, fmt.Sprintf("convert string %s to primitive.ObjectID type: encoding/hex: invalid byte: U+005F '_'", badUserID))
+
+	cleanUp(t)
+}
+
+func TestLeaveTournament(t *testing.T) {
+	expectedTournamentName := "tournament-1"
+	expectedTournamentDeposit := 1000.0
+	expectedTournamentID, err := db.AddTournament(context.TODO(), expectedTournamentName, expectedTournamentDeposit, organizerID.Hex())
+	require := require.New(t)
+	require.NoError(err)
+
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	userLeaveTorneyID, err := primitive.ObjectIDFromHex(userID)
+	require.NoError(err)
+	err = db.JoinTournament(context.TODO(), expectedTournamentID, userLeaveTorneyID.Hex())
+	require.NoError(err)
+
+	err = db.LeaveTournament(context.TODO(), expectedTournamentID, userLeaveTorneyID.Hex())
+	require.NoError(err)
+
+	actualTournament, err := db.GetTournament(context.TODO(), expectedTournamentID)
+	require.NoError(err)
+
+	expectedTournamentObjID, err := primitive.ObjectIDFromHex(expectedTournamentID)
+	require.NoError(err)
+
+	expectedTournament := Tournament{
+		ID:        expectedTournamentObjID,
+		Name:      expectedTournamentName,
+		Deposit:   expectedTournamentDeposit,
+		Status:    StatusSignIn,
+		Users:     []primitive.ObjectID{},
+		Organizer: organizerID,
+	}
+	require.Equal(expectedTournament, *actualTournament, "The two tournament objects should be the same")
+
+	actualErr := db.LeaveTournament(context.TODO(), expectedTournamentID, userLeaveTorneyID.Hex())
+	expectedErr := "error processing transaction: update doc in collection: user isn't in tournament users list"
+	require.EqualError(actualErr, expectedErr, "The two errors should be the same")
+
+	err = db.JoinTournament(context.TODO(), expectedTournamentID, userLeaveTorneyID.Hex())
+	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), expectedTournamentID, StatusStarted)
+	require.NoError(err)
+	actualErr = db.LeaveTournament(context.TODO(), expectedTournamentID, userLeaveTorneyID.Hex())
+	require.ErrorIs(actualErr, ErrTournamentStatus, "Player should not leave started tournament")
+
+	actualTournament, err = db.GetTournament(context.TODO(), expectedTournamentID)
+	require.NoError(err)
+	require.Equal([]primitive.ObjectID{userLeaveTorneyID}, actualTournament.Users)
+	require.Equal(expectedTournamentDeposit, actualTournament.Prize, "Prize should be kept")
+
+	cleanUp(t)
+}
+
+func TestCountTournamentsByStatus(t *testing.T) {
+	require := require.New(t)
+	for i := 0; i < 2; i++ {
+		_, err := db.AddTournament(context.TODO(), "tournament", 1000.0, organizerID.Hex())
+		require.NoError(err)
+	}
+	startedTournamentID, err := db.AddTournament(context.TODO(), "started tournament", 1000.0, organizerID.Hex())
+	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), startedTournamentID, StatusStarted)
+	require.NoError(err)
+
+	counts, err := db.CountTournamentsByStatus(context.TODO())
+	require.NoError(err)
+	require.Equal(map[TournamentStatus]int64{StatusSignIn: 2, StatusStarted: 1}, counts, "The two maps should be the same")
+
+	cleanUp(t)
+}
+
+func TestListTournaments(t *testing.T) {
+	require := require.New(t)
+	_, err := db.AddTournament(context.TODO(), "first", 1000.0, organizerID.Hex())
+	require.NoError(err)
+	startedTournamentID, err := db.AddTournament(context.TODO(), "second", 1000.0, organizerID.Hex())
+	require.NoError(err)
+	err = db.SetTournamentStatus(context.TODO(), startedTournamentID, StatusStarted)
+	require.NoError(err)
+	_, err = db.AddTournament(context.TODO(), "third", 1000.0, organizerID.Hex())
+	require.NoError(err)
+
+	tournaments, err := db.ListTournaments(context.TODO(), "", 2)
+	require.NoError(err)
+	require.Len(tournaments, 2)
+	require.Equal("third", tournaments[0].Name, "Newest tournament should be listed first")
+
+	tournaments, err = db.ListTournaments(context.TODO(), StatusSignIn, 10)
+	require.NoError(err)
+	require.Len(tournaments, 2)
+
+	tournaments, err = db.ListTournaments(context.TODO(), StatusFinished, 10)
+	require.NoError(err)
+	require.Empty(tournaments)
+
+	cleanUp(t)
+}
+
+func TestScheduleTournament(t *testing.T) {
+	require := require.New(t)
+	soonID, err := db.AddTournament(context.TODO(), "soon", 10, organizerID.Hex())
+	require.NoError(err)
+	laterID, err := db.AddTournament(context.TODO(), "later", 10, organizerID.Hex())
+	require.NoError(err)
+	startedID, err := db.AddTournament(context.TODO(), "started", 10, organizerID.Hex())
+	require.NoError(err)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(db.ScheduleTournament(context.TODO(), soonID, now.Add(10*time.Minute)))
+	require.NoError(db.ScheduleTournament(context.TODO(), laterID, now.Add(time.Hour)))
+	require.NoError(db.ScheduleTournament(context.TODO(), startedID, now.Add(5*time.Minute)))
+	require.NoError(db.SetTournamentStatus(context.TODO(), startedID, StatusStarted))
+	err = db.ScheduleTournament(context.TODO(), startedID, now.Add(time.Hour))
+	require.ErrorIs(err, ErrTournamentStatus, "Started tournament should not be scheduled")
+	require.Error(db.ScheduleTournament(context.TODO(), primitive.NewObjectID().Hex(), now))
+
+	due, err := db.DueTournamentReminders(context.TODO(), now, now.Add(15*time.Minute), 10)
+	require.NoError(err)
+	require.Len(due, 1, "Only signing in tournament starting within lead should be due")
+	require.Equal(soonID, due[0].ID.Hex())
+	require.True(now.Add(10 * time.Minute).Equal(*due[0].StartsAt))
+
+	require.NoError(db.MarkTournamentReminded(context.TODO(), due[0].ID, *due[0].StartsAt))
+	due, err = db.DueTournamentReminders(context.TODO(), now, now.Add(15*time.Minute), 10)
+	require.NoError(err)
+	require.Empty(due, "Reminded tournament should not be due")
+
+	require.NoError(db.ScheduleTournament(context.TODO(), soonID, now.Add(5*time.Minute)))
+	due, err = db.DueTournamentReminders(context.TODO(), now, now.Add(15*time.Minute), 10)
+	require.NoError(err)
+	require.Len(due, 1, "Rescheduled tournament should be reminded about again")
+
+	cleanUp(t)
+}
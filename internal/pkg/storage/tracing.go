@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+
+// startSpan starts child span for storage method. Context returned keeps
+// mongo session if ctx is mongo.SessionContext, so nested calls made
+// inside transaction become children of transaction span.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "storage."+method)
+}
+
+// endSpan records err in span if any and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
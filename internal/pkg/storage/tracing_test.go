@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestJoinTournament_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(defaultProvider)
+
+	tournamentID, err := db.AddTournament(context.TODO(), "tournament-1", 1000, organizerID.Hex())
+	require := require.New(t)
+	require.NoError(err)
+
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(err)
+	err = db.JoinTournament(context.TODO(), tournamentID, userID)
+	require.NoError(err)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	parent, ok := spans["storage.JoinTournament"]
+	require.True(ok, "Transaction span should be recorded")
+	for _, name := range []string{
+		"storage.AddUserToTournamentList",
+		"storage.GetTournament",
+		"storage.IncreaseTournamentPrize",
+	} {
+		child, ok := spans[name]
+		require.True(ok, "Span %s should be recorded", name)
+		require.Equal(parent.SpanContext().SpanID(), child.Parent().SpanID(),
+			"Span %s should be child of transaction span", name)
+	}
+
+	cleanUp(t)
+}
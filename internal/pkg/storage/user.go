@@ -2,43 +2,109 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// User represents a player with id, name
+// User represents a player with id, name, role
 // and certain amount of points as a balance
 type User struct {
 	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name    string             `json:"name" bson:"name"`
 	Balance float64            `json:"balance" bson:"balance"`
+	Role    Role               `json:"role" bson:"role"`
+
+	// Email is optional address emails are sent to once it's verified.
+	Email         string `json:"email,omitempty" bson:"email,omitempty"`
+	EmailVerified bool   `json:"emailVerified,omitempty" bson:"emailVerified,omitempty"`
+	// EmailOptOut lists kinds of notifications user doesn't want to get by email.
+	EmailOptOut  []NotificationKind `json:"emailOptOut,omitempty" bson:"emailOptOut,omitempty"`
+	Verification *EmailVerification `json:"-" bson:"emailVerification,omitempty"`
+
+	// DeletedAt is set once user is deleted. Document is kept, so tournaments
+	// still refer to existing user, but it can't be funded, charged or join.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+// AnonymizedName replaces name of user deleted with anonymization.
+const AnonymizedName = "Deleted user"
+
+// ErrUserActive is returned when user to be deleted without force
+// still takes part in tournament or has points on his balance.
+var ErrUserActive = errors.New("user has active tournaments or non-zero balance")
+
+// DeleteUserOptions control how DeleteUser treats user still taking part in the game.
+type DeleteUserOptions struct {
+	// Force deletes user despite active tournaments and balance. User leaves
+	// tournaments which aren't started yet and remaining balance is forfeited.
+	Force bool
+	// Anonymize scrubs personal data: name is replaced, email, credentials,
+	// notifications, queued emails and exports are removed and name is cleared
+	// from events and webhook deliveries. Id and history are kept.
+	Anonymize bool
+}
+
+// Role defines what operations user is allowed to perform.
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleOrganizer Role = "organizer"
+	RolePlayer    Role = "player"
+)
+
+// Valid reports whether role is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleOrganizer, RolePlayer:
+		return true
+	}
+	return false
 }
 
-// AddUser func fills user info with provided name, zero balance by default
+// AddUser func fills user info with provided name, zero balance and player role by default
 // and with automatically generated id, then adds generated user info to database.
 // It returns added userID in string format if succeed and null string and err if smth wrong.
-func (db *DB) AddUser(ctx context.Context, name string) (string, error) {
-	insertResult, err := db.conn.Collection(usersCollectionName).InsertOne(ctx, User{
-		Name: name,
+func (db *DB) AddUser(ctx context.Context, name string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AddUser")
+	defer func() { endSpan(span, err) }()
+
+	var userID string
+	err = db.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		insertResult, err := db.conn.Collection(usersCollectionName).InsertOne(sc, User{
+			Name: name,
+			Role: RolePlayer,
+		})
+		if err != nil {
+			return errors.Wrap(err, "insert doc to collection")
+		}
+
+		insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+		if !ok {
+			return errors.New("convert inserted id to primitive.ObjectID")
+		}
+		userID = insertedID.Hex()
+
+		return db.addOutboxEvent(sc, OutboxEvent{Type: EventUserCreated, UserID: userID, Name: name})
 	})
 	if err != nil {
-		return "", errors.Wrap(err, "insert doc to collection")
-	}
-
-	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return "", errors.New("convert inserted id to primitive.ObjectID")
+		return "", err
 	}
 
-	return insertedID.Hex(), nil
+	return userID, nil
 }
 
 // GetUser func tries to find user with provided id string.
 // If succeed it returns *User and nil error. If smth wrong it
 // returns nil *User and corresponding error.
-func (db *DB) GetUser(ctx context.Context, id string) (*User, error) {
+func (db *DB) GetUser(ctx context.Context, id string) (_ *User, err error) {
+	ctx, span := startSpan(ctx, "GetUser")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
@@ -57,29 +123,130 @@ func (db *DB) GetUser(ctx context.Context, id string) (*User, error) {
 	return &user, nil
 }
 
-// DeleteUser func tries to delete user with provided id string.
-// If smth wrong it returns corresponding error, and nil error otherwise.
-func (db *DB) DeleteUser(ctx context.Context, id string) error {
+// DeleteUser func soft deletes user with provided id string: user is marked
+// deleted, his credentials are removed and sessions revoked. Unless forced it
+// refuses with ErrUserActive to delete user who joined tournament which isn't
+// finished or has non-zero balance. If smth wrong it returns corresponding error.
+func (db *DB) DeleteUser(ctx context.Context, id string, opts DeleteUserOptions) (err error) {
+	ctx, span := startSpan(ctx, "DeleteUser")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
 	}
 
-	deleteResult, err := db.conn.Collection(usersCollectionName).DeleteOne(ctx, bson.M{"_id": primID})
-	if err != nil {
-		return errors.Wrap(err, "delete doc from collection")
-	}
+	entry := AuditEntry{Action: AuditUserDeleted, TargetIDs: []string{id}}
+	return db.inAuditedTransaction(ctx, entry, usersCollectionName, func(sc context.Context) error {
+		var user User
+		err := db.conn.Collection(usersCollectionName).FindOne(sc, notDeleted(primID)).Decode(&user)
+		if err != nil {
+			return errors.Wrap(err, "get doc from collection")
+		}
 
-	if deleteResult.DeletedCount != 1 {
-		return errors.New("delete doc from collection: DeletedCount != 1")
-	}
+		cursor, err := db.conn.Collection(tournamentsCollectionName).Find(sc,
+			bson.M{"users": primID, "status": bson.M{"$ne": StatusFinished}})
+		if err != nil {
+			return errors.Wrap(err, "find docs in collection")
+		}
+		var tournaments []Tournament
+		if err := cursor.All(sc, &tournaments); err != nil {
+			return errors.Wrap(err, "decode returned docs")
+		}
+
+		if !opts.Force && (len(tournaments) > 0 || user.Balance != 0) {
+			return errors.Wrapf(ErrUserActive, "%d active tournaments, balance %v", len(tournaments), user.Balance)
+		}
+
+		for _, tournament := range tournaments {
+			// player of started tournament stays in it, so tournament can be finished as usual
+			if tournament.Status == StatusStarted {
+				continue
+			}
+			update := bson.D{
+				{"$pull", bson.D{{"users", primID}}},
+				{"$inc", bson.D{{"prize", -tournament.Deposit}}},
+			}
+			_, err := db.conn.Collection(tournamentsCollectionName).UpdateOne(sc, bson.M{"_id": tournament.ID}, update)
+			if err != nil {
+				return errors.Wrap(err, "update doc in collection")
+			}
+			err = db.addOutboxEvent(sc, OutboxEvent{
+				Type:         EventPlayerRemoved,
+				TournamentID: tournament.ID.Hex(),
+				UserID:       id,
+				Amount:       tournament.Deposit,
+			})
+			if err != nil {
+				return errors.Wrap(err, "addOutboxEvent")
+			}
+		}
+
+		set := bson.D{
+			{"deletedAt", time.Now().UTC()},
+			{"balance", 0.0},
+		}
+		if opts.Anonymize {
+			set = append(set, bson.E{Key: "name", Value: AnonymizedName})
+		}
+		update := bson.D{{"$set", set}}
+		if opts.Anonymize {
+			update = append(update, bson.E{Key: "$unset", Value: bson.D{
+				{"email", ""},
+				{"emailVerified", ""},
+				{"emailOptOut", ""},
+				{"emailVerification", ""},
+			}})
+		}
+		if _, err := db.conn.Collection(usersCollectionName).UpdateOne(sc, bson.M{"_id": primID}, update); err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+
+		if _, err := db.conn.Collection(credentialsCollectionName).DeleteOne(sc, bson.M{"user_id": primID}); err != nil {
+			return errors.Wrap(err, "delete doc from collection")
+		}
+		if err := db.RevokeUserSessions(sc, id); err != nil {
+			return errors.Wrap(err, "RevokeUserSessions")
+		}
 
-	return nil
+		if opts.Anonymize {
+			for _, name := range []string{notificationsCollectionName, emailsCollectionName} {
+				if _, err := db.conn.Collection(name).DeleteMany(sc, bson.M{"userId": id}); err != nil {
+					return errors.Wrapf(err, "delete docs from collection %s", name)
+				}
+			}
+			if err := db.deleteUserExports(sc, id); err != nil {
+				return errors.Wrap(err, "deleteUserExports")
+			}
+
+			// name of user is kept only by events about his creation
+			_, err := db.conn.Collection(outboxCollectionName).UpdateMany(sc,
+				bson.M{"type": EventUserCreated, "userId": id}, bson.M{"$unset": bson.M{"name": ""}})
+			if err != nil {
+				return errors.Wrap(err, "update docs in collection")
+			}
+			_, err = db.conn.Collection(deliveriesCollectionName).UpdateMany(sc,
+				bson.M{"event.type": EventUserCreated, "event.userId": id}, bson.M{"$unset": bson.M{"event.name": ""}})
+			if err != nil {
+				return errors.Wrap(err, "update docs in collection")
+			}
+		}
+
+		return db.addOutboxEvent(sc, OutboxEvent{Type: EventUserDeleted, UserID: id, Amount: user.Balance})
+	})
+}
+
+// notDeleted returns filter matching user with provided id unless he is deleted.
+func notDeleted(id primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}
 }
 
 // TakeUserBalance func tries to decrease user balance with provided id string.
 // If smth wrong it returns corresponding error, and nil error otherwise.
-func (db *DB) TakeUserBalance(ctx context.Context, id string, points float64) error {
+func (db *DB) TakeUserBalance(ctx context.Context, id string, points float64) (err error) {
+	ctx, span := startSpan(ctx, "TakeUserBalance")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
@@ -90,21 +257,28 @@ func (db *DB) TakeUserBalance(ctx context.Context, id string, points float64) er
 			{"balance", -points},
 		}},
 	}
-	updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(ctx, bson.M{"_id": primID}, update)
-	if err != nil {
-		return errors.Wrap(err, "update doc in collection")
-	}
 
-	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
-	}
+	entry := AuditEntry{Action: AuditBalanceTaken, TargetIDs: []string{id}, Amount: points}
+	return db.inAuditedTransaction(ctx, entry, usersCollectionName, func(sc context.Context) error {
+		updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(sc, notDeleted(primID), update)
+		if err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+
+		if updateResult.ModifiedCount != 1 {
+			return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+		}
 
-	return nil
+		return db.addOutboxEvent(sc, OutboxEvent{Type: EventBalanceTaken, UserID: id, Amount: points})
+	})
 }
 
 // FundUserBalance func tries to increase user balance with provided id string.
 // If smth wrong it returns corresponding error, and nil error otherwise
-func (db *DB) FundUserBalance(ctx context.Context, id string, points float64) error {
+func (db *DB) FundUserBalance(ctx context.Context, id string, points float64) (err error) {
+	ctx, span := startSpan(ctx, "FundUserBalance")
+	defer func() { endSpan(span, err) }()
+
 	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
@@ -115,14 +289,49 @@ func (db *DB) FundUserBalance(ctx context.Context, id string, points float64) er
 			{"balance", points},
 		}},
 	}
-	updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(ctx, bson.M{"_id": primID}, update)
+
+	entry := AuditEntry{Action: AuditBalanceFunded, TargetIDs: []string{id}, Amount: points}
+	return db.inAuditedTransaction(ctx, entry, usersCollectionName, func(sc context.Context) error {
+		updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(sc, notDeleted(primID), update)
+		if err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+
+		if updateResult.ModifiedCount != 1 {
+			return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+		}
+
+		return db.addOutboxEvent(sc, OutboxEvent{Type: EventBalanceFunded, UserID: id, Amount: points})
+	})
+}
+
+// SetUserRole func sets role of user with provided id string.
+// If smth wrong it returns corresponding error, and nil error otherwise.
+func (db *DB) SetUserRole(ctx context.Context, id string, role Role) (err error) {
+	ctx, span := startSpan(ctx, "SetUserRole")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.Wrap(err, "update doc in collection")
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
 	}
 
-	if updateResult.ModifiedCount != 1 {
-		return errors.New("update doc in collection: ModifiedCount != 1")
+	update := bson.D{
+		{"$set", bson.D{
+			{"role", role},
+		}},
 	}
+	entry := AuditEntry{Action: AuditRoleChanged, TargetIDs: []string{id}}
+	return db.inAuditedTransaction(ctx, entry, usersCollectionName, func(sc context.Context) error {
+		updateResult, err := db.conn.Collection(usersCollectionName).UpdateOne(sc, notDeleted(primID), update)
+		if err != nil {
+			return errors.Wrap(err, "update doc in collection")
+		}
+
+		if updateResult.MatchedCount != 1 {
+			return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+		}
 
-	return nil
+		return db.addOutboxEvent(sc, OutboxEvent{Type: EventUserRoleChanged, UserID: id, Role: role})
+	})
 }
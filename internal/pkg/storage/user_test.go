@@ -2,10 +2,13 @@ package storage
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -23,7 +26,7 @@ func TestAddUser(t *testing.T) {
 	userIDExpected2, err := primitive.ObjectIDFromHex(userIDExpected)
 	require.NoError(t, err, "ObjectIDFromHex func should return nil error")
 
-	assert.Equal(&User{ID: userIDExpected2, Name: "gennadiy"}, actualUser, "The two users should be the same.")
+	assert.Equal(&User{ID: userIDExpected2, Name: "gennadiy", Role: RolePlayer}, actualUser, "The two users should be the same.")
 
 	cleanUp(t)
 }
@@ -41,7 +44,7 @@ func TestGetUser(t *testing.T) {
 	userIDExpected2, err := primitive.ObjectIDFromHex(userIDExpected)
 	require.NoError(t, err, "ObjectIDFromHex func should return nil error")
 
-	assert.Equal(&User{ID: userIDExpected2, Name: "Vasya"}, actualUser, "The two users should be the same.")
+	assert.Equal(&User{ID: userIDExpected2, Name: "Vasya", Role: RolePlayer}, actualUser, "The two users should be the same.")
 
 	badUserID := "safasf2412"
 	_, err = db.GetUser(context.TODO(), badUserID)
@@ -57,21 +60,94 @@ func TestGetUser(t *testing.T) {
 }
 
 func TestDeleteUser(t *testing.T) {
-	userIDExpected, err := db.AddUser(context.TODO(), "Vasya")
-	require.NoError(t, err, "AddUser func should return nil error")
+	userIDExpected, err := db.RegisterUser(context.TODO(), "Vasya", "vasya", []byte("hash"))
+	require.NoError(t, err, "RegisterUser func should return nil error")
 
-	err = db.DeleteUser(context.TODO(), userIDExpected)
+	err = db.DeleteUser(context.TODO(), userIDExpected, DeleteUserOptions{})
 	require.NoError(t, err, "DeleteUser func should return nil error")
 
-	badUserID := "safasf2412"
-	err = db.DeleteUser(context.TODO(), badUserID)
+	user, err := db.GetUser(context.TODO(), userIDExpected)
+	require.NoError(t, err, "Deleted user should be kept")
 	assert := assert.New(t)
+	assert.NotNil(user.DeletedAt)
+	assert.Equal("Vasya", user.Name)
+	_, err = db.GetCredentialsByLogin(context.TODO(), "vasya")
+	assert.ErrorIs(err, mongo.ErrNoDocuments, "Deleted user should not be able to log in")
+	assert.Error(db.FundUserBalance(context.TODO(), userIDExpected, 10), "Deleted user should not be funded")
+
+	badUserID := "safasf2412"
+	err = db.DeleteUser(context.TODO(), badUserID, DeleteUserOptions{})
 	assert.EqualError(err,
 		"convert string value to primitive.ObjectID type: encoding/hex: invalid byte: U+0073 's'",
 		"The error should contain text")
 
-	err = db.DeleteUser(context.TODO(), userIDExpected)
-	assert.EqualError(err, "delete doc from collection: DeletedCount != 1", "The two errors should be the same")
+	err = db.DeleteUser(context.TODO(), userIDExpected, DeleteUserOptions{})
+	assert.ErrorIs(err, mongo.ErrNoDocuments, "User should be deleted only once")
+
+	cleanUp(t)
+}
+
+func TestDeleteUser_Active(t *testing.T) {
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(t, err, "AddUser func should return nil error")
+	require.NoError(t, db.SetUserEmail(context.TODO(), userID, "vasya@example.com",
+		EmailVerification{TokenHash: "hash"}))
+	organizerID := primitive.NewObjectID().Hex()
+	signInID, err := db.AddTournament(context.TODO(), "Cup", 10, organizerID)
+	require.NoError(t, err, "AddTournament func should return nil error")
+	startedID, err := db.AddTournament(context.TODO(), "League", 20, organizerID)
+	require.NoError(t, err, "AddTournament func should return nil error")
+	require.NoError(t, db.JoinTournament(context.TODO(), signInID, userID))
+	require.NoError(t, db.JoinTournament(context.TODO(), startedID, userID))
+	require.NoError(t, db.SetTournamentStatus(context.TODO(), startedID, StatusStarted))
+
+	err = db.DeleteUser(context.TODO(), userID, DeleteUserOptions{})
+	assert := assert.New(t)
+	assert.ErrorIs(err, ErrUserActive, "User in active tournaments should not be deleted without force")
+
+	err = db.DeleteUser(context.TODO(), userID, DeleteUserOptions{Force: true, Anonymize: true})
+	require.NoError(t, err, "DeleteUser func should return nil error")
+
+	user, err := db.GetUser(context.TODO(), userID)
+	require.NoError(t, err, "GetUser func should return nil error")
+	assert.Equal(AnonymizedName, user.Name)
+	assert.Empty(user.Email)
+	assert.Nil(user.Verification)
+
+	signIn, err := db.GetTournament(context.TODO(), signInID)
+	require.NoError(t, err, "GetTournament func should return nil error")
+	assert.Empty(signIn.Users, "User should leave tournament which isn't started")
+	assert.Equal(0.0, signIn.Prize)
+	started, err := db.GetTournament(context.TODO(), startedID)
+	require.NoError(t, err, "GetTournament func should return nil error")
+	assert.Len(started.Users, 1, "User should stay in started tournament")
+
+	cleanUp(t)
+}
+
+func TestDeleteUser_AnonymizeHistory(t *testing.T) {
+	userID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(t, err, "AddUser func should return nil error")
+	require.NoError(t, db.AddWebhookDelivery(context.TODO(), primitive.NewObjectID(),
+		OutboxEvent{Type: EventUserCreated, UserID: userID, Name: "Vasya"}))
+	export, err := db.RequestExport(context.TODO(), userID, time.Now())
+	require.NoError(t, err, "RequestExport func should return nil error")
+	require.NoError(t, db.SaveExportArchive(context.TODO(), export.ID, strings.NewReader("vasya@example.com")))
+
+	err = db.DeleteUser(context.TODO(), userID, DeleteUserOptions{Anonymize: true})
+	require.NoError(t, err, "DeleteUser func should return nil error")
+
+	assert := assert.New(t)
+	for _, name := range []string{outboxCollectionName, deliveriesCollectionName} {
+		count, err := db.conn.Collection(name).CountDocuments(context.TODO(),
+			bson.M{"$or": bson.A{bson.M{"name": "Vasya"}, bson.M{"event.name": "Vasya"}}})
+		require.NoError(t, err)
+		assert.Zero(count, "Name should be scrubbed from %s", name)
+	}
+	_, err = db.GetExport(context.TODO(), userID, export.ID.Hex())
+	assert.ErrorIs(err, mongo.ErrNoDocuments, "Export should be removed")
+	_, err = db.OpenExportArchive(context.TODO(), export.ID)
+	assert.ErrorIs(err, mongo.ErrNoDocuments, "Archive should be removed")
 
 	cleanUp(t)
 }
@@ -83,7 +159,7 @@ func TestTakeUserBalance(t *testing.T) {
 	err := db.TakeUserBalance(context.TODO(), generatedUserID.Hex(), amount)
 	assert := assert.New(t)
 	assert.EqualError(err,
-		"update doc in collection: ModifiedCount != 1",
+		"update doc in collection: mongo: no documents in result",
 		"The error should contain text")
 
 	badUserID := "safasf2412"
@@ -104,7 +180,7 @@ func TestTakeUserBalance(t *testing.T) {
 	addedUserObjectID, err := primitive.ObjectIDFromHex(addedUserID)
 	require.NoError(t, err, "ObjectIDFromHex func should return nil error")
 
-	assert.Equal(&User{ID: addedUserObjectID, Name: "Vasya", Balance: -100.0}, addedUser,
+	assert.Equal(&User{ID: addedUserObjectID, Name: "Vasya", Balance: -100.0, Role: RolePlayer}, addedUser,
 		"The two users should be the same.")
 
 	cleanUp(t)
@@ -117,7 +193,7 @@ func TestFundUserBalance(t *testing.T) {
 	err := db.FundUserBalance(context.TODO(), generatedUserID.Hex(), amount)
 	assert := assert.New(t)
 	assert.EqualError(err,
-		"update doc in collection: ModifiedCount != 1",
+		"update doc in collection: mongo: no documents in result",
 		"The error should contain text")
 
 	badUserID := "safasf2412"
@@ -138,8 +214,32 @@ func TestFundUserBalance(t *testing.T) {
 	addedUserObjectID, err := primitive.ObjectIDFromHex(addedUserID)
 	require.NoError(t, err, "ObjectIDFromHex func should return nil error")
 
-	assert.Equal(&User{ID: addedUserObjectID, Name: "Vasya", Balance: 100.0}, addedUser,
+	assert.Equal(&User{ID: addedUserObjectID, Name: "Vasya", Balance: 100.0, Role: RolePlayer}, addedUser,
 		"The two users should be the same.")
 
 	cleanUp(t)
 }
+
+func TestSetUserRole(t *testing.T) {
+	badUserID := "safasf2412"
+	err := db.SetUserRole(context.TODO(), badUserID, RoleAdmin)
+	assert := assert.New(t)
+	assert.EqualError(err,
+		"convert string value to primitive.ObjectID type: encoding/hex: invalid byte: U+0073 's'",
+		"The error should contain text")
+
+	err = db.SetUserRole(context.TODO(), primitive.NewObjectID().Hex(), RoleAdmin)
+	assert.EqualError(err, "update doc in collection: mongo: no documents in result", "The two errors should be the same")
+
+	addedUserID, err := db.AddUser(context.TODO(), "Vasya")
+	require.NoError(t, err, "AddUser func should return nil error")
+
+	err = db.SetUserRole(context.TODO(), addedUserID, RoleOrganizer)
+	require.NoError(t, err, "SetUserRole func should return nil error")
+
+	addedUser, err := db.GetUser(context.TODO(), addedUserID)
+	require.NoError(t, err, "GetUser func should return nil error")
+	assert.Equal(RoleOrganizer, addedUser.Role, "The two roles should be the same.")
+
+	cleanUp(t)
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Webhook is partner subscription to domain events delivered to URL.
+type Webhook struct {
+	ID  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	URL string             `json:"url" bson:"url"`
+
+	// EventTypes lists events delivered to URL. Empty list means all events.
+	EventTypes []EventType `json:"eventTypes" bson:"eventTypes"`
+
+	// Secret is key deliveries are signed with. It's never returned in responses.
+	Secret    string    `json:"-" bson:"secret"`
+	CreatedBy string    `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Accepts reports whether events of provided type are delivered to webhook.
+func (w *Webhook) Accepts(typ EventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeliveryStatus is state of webhook delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is single event to be sent to webhook together with
+// result of the last attempt. It keeps copy of event, so deliveries
+// outlive events purged from outbox.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	Event         OutboxEvent        `json:"event" bson:"event"`
+	Status        DeliveryStatus     `json:"status" bson:"status"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LastStatus    int                `json:"lastStatus,omitempty" bson:"lastStatus,omitempty"`
+	LastError     string             `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	DeliveredAt   *time.Time         `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+// AddWebhook func adds webhook with generated id and creation time.
+// It returns added webhook id in string format if succeed.
+func (db *DB) AddWebhook(ctx context.Context, webhook Webhook) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AddWebhook")
+	defer func() { endSpan(span, err) }()
+
+	webhook.ID = primitive.NilObjectID
+	webhook.CreatedAt = time.Now().UTC()
+	insertResult, err := db.conn.Collection(webhooksCollectionName).InsertOne(ctx, webhook)
+	if err != nil {
+		return "", errors.Wrap(err, "insert doc to collection")
+	}
+
+	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", errors.New("convert inserted id to primitive.ObjectID")
+	}
+
+	return insertedID.Hex(), nil
+}
+
+// GetWebhook func tries to find webhook with provided id string.
+func (db *DB) GetWebhook(ctx context.Context, id string) (_ *Webhook, err error) {
+	ctx, span := startSpan(ctx, "GetWebhook")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	docReturned := db.conn.Collection(webhooksCollectionName).FindOne(ctx, bson.M{"_id": primID})
+	if err = docReturned.Err(); err != nil {
+		return nil, errors.Wrap(err, "get doc from collection")
+	}
+
+	var webhook Webhook
+	if err = docReturned.Decode(&webhook); err != nil {
+		return nil, errors.Wrap(err, "decode returned doc")
+	}
+
+	return &webhook, nil
+}
+
+// ListWebhooks func returns all webhooks in order they were added.
+func (db *DB) ListWebhooks(ctx context.Context) (_ []Webhook, err error) {
+	ctx, span := startSpan(ctx, "ListWebhooks")
+	defer func() { endSpan(span, err) }()
+
+	cursor, err := db.conn.Collection(webhooksCollectionName).Find(ctx, bson.D{},
+		options.Find().SetSort(bson.D{{"_id", 1}}))
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []Webhook{}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook func deletes webhook with provided id string. Its pending
+// deliveries are dropped by delivery worker, delivery log is kept.
+func (db *DB) DeleteWebhook(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteWebhook")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	docDeleted, err := db.conn.Collection(webhooksCollectionName).DeleteOne(ctx, bson.M{"_id": primID})
+	if err != nil {
+		return errors.Wrap(err, "delete doc from collection")
+	}
+
+	if docDeleted.DeletedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "delete doc from collection")
+	}
+
+	return nil
+}
+
+// AddWebhookDelivery func queues event for delivery to webhook. Event
+// already queued for the same webhook is left as is, so outbox event
+// handled more than once is delivered once.
+func (db *DB) AddWebhookDelivery(ctx context.Context, webhookID primitive.ObjectID, e OutboxEvent) (err error) {
+	ctx, span := startSpan(ctx, "AddWebhookDelivery")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now().UTC()
+	filter := bson.M{"webhookId": webhookID, "event._id": e.ID}
+	update := bson.D{
+		{"$setOnInsert", bson.D{
+			{"webhookId", webhookID},
+			{"event", e},
+			{"status", DeliveryPending},
+			{"attempts", 0},
+			{"nextAttemptAt", now},
+			{"createdAt", now},
+		}},
+	}
+	_, err = db.conn.Collection(deliveriesCollectionName).UpdateOne(ctx, filter, update,
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.Wrap(err, "upsert doc in collection")
+	}
+
+	return nil
+}
+
+// DueWebhookDeliveries func returns up to limit pending deliveries
+// which next attempt is due at provided time, oldest first.
+func (db *DB) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int64) (_ []WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "DueWebhookDeliveries")
+	defer func() { endSpan(span, err) }()
+
+	filter := bson.M{"status": DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{"nextAttemptAt", 1}, {"_id", 1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(deliveriesCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	var due []WebhookDelivery
+	if err = cursor.All(ctx, &due); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return due, nil
+}
+
+// UpdateWebhookDelivery func saves status and result of the last attempt of delivery.
+func (db *DB) UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) (err error) {
+	ctx, span := startSpan(ctx, "UpdateWebhookDelivery")
+	defer func() { endSpan(span, err) }()
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", d.Status},
+			{"attempts", d.Attempts},
+			{"nextAttemptAt", d.NextAttemptAt},
+			{"lastStatus", d.LastStatus},
+			{"lastError", d.LastError},
+			{"deliveredAt", d.DeliveredAt},
+		}},
+	}
+	updateResult, err := db.conn.Collection(deliveriesCollectionName).UpdateOne(ctx, bson.M{"_id": d.ID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries func returns up to limit latest deliveries to webhook
+// with provided id string, newest first. Empty status matches any status.
+func (db *DB) ListWebhookDeliveries(ctx context.Context, webhookID string, status DeliveryStatus,
+	limit int64) (_ []WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "ListWebhookDeliveries")
+	defer func() { endSpan(span, err) }()
+
+	primID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	filter := bson.M{"webhookId": primID}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(limit)
+	cursor, err := db.conn.Collection(deliveriesCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "find docs in collection")
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []WebhookDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, errors.Wrap(err, "decode returned docs")
+	}
+
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery func queues delivery of webhook with provided ids
+// for immediate attempt with reset attempt counter, whatever its status is.
+func (db *DB) ReplayWebhookDelivery(ctx context.Context, webhookID, id string) (err error) {
+	ctx, span := startSpan(ctx, "ReplayWebhookDelivery")
+	defer func() { endSpan(span, err) }()
+
+	primWebhookID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+	primID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(err, "convert string value to primitive.ObjectID type")
+	}
+
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", DeliveryPending},
+			{"attempts", 0},
+			{"nextAttemptAt", time.Now().UTC()},
+		}},
+		{"$unset", bson.D{
+			{"deliveredAt", ""},
+		}},
+	}
+	updateResult, err := db.conn.Collection(deliveriesCollectionName).UpdateOne(ctx,
+		bson.M{"_id": primID, "webhookId": primWebhookID}, update)
+	if err != nil {
+		return errors.Wrap(err, "update doc in collection")
+	}
+
+	if updateResult.MatchedCount != 1 {
+		return errors.Wrap(mongo.ErrNoDocuments, "update doc in collection")
+	}
+
+	return nil
+}
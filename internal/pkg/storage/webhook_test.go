@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWebhooks(t *testing.T) {
+	require := require.New(t)
+
+	webhookID, err := db.AddWebhook(context.TODO(), Webhook{
+		URL:        "https://partner.example/hook",
+		EventTypes: []EventType{EventTournamentFinished},
+		Secret:     "secret",
+		CreatedBy:  organizerID.Hex(),
+	})
+	require.NoError(err)
+
+	webhook, err := db.GetWebhook(context.TODO(), webhookID)
+	require.NoError(err)
+	require.Equal("https://partner.example/hook", webhook.URL)
+	require.Equal("secret", webhook.Secret)
+	require.True(webhook.Accepts(EventTournamentFinished))
+	require.False(webhook.Accepts(EventBalanceFunded))
+
+	list, err := db.ListWebhooks(context.TODO())
+	require.NoError(err)
+	require.Len(list, 1)
+
+	event := OutboxEvent{ID: primitive.NewObjectID(), Type: EventTournamentFinished, Amount: 50}
+	require.NoError(db.AddWebhookDelivery(context.TODO(), webhook.ID, event))
+	require.NoError(db.AddWebhookDelivery(context.TODO(), webhook.ID, event), "Repeated event should be ignored")
+
+	due, err := db.DueWebhookDeliveries(context.TODO(), time.Now().Add(time.Second), 10)
+	require.NoError(err)
+	require.Len(due, 1)
+	require.Equal(DeliveryPending, due[0].Status)
+	require.Equal(event.ID, due[0].Event.ID)
+
+	delivery := due[0]
+	delivery.Status = DeliveryDead
+	delivery.Attempts = 5
+	delivery.LastStatus = 500
+	delivery.LastError = "unexpected status 500"
+	require.NoError(db.UpdateWebhookDelivery(context.TODO(), &delivery))
+
+	due, err = db.DueWebhookDeliveries(context.TODO(), time.Now().Add(time.Second), 10)
+	require.NoError(err)
+	require.Empty(due, "Dead delivery should not be due")
+
+	log, err := db.ListWebhookDeliveries(context.TODO(), webhookID, DeliveryDead, 10)
+	require.NoError(err)
+	require.Len(log, 1)
+	require.Equal(5, log[0].Attempts)
+	require.Equal("unexpected status 500", log[0].LastError)
+
+	require.NoError(db.ReplayWebhookDelivery(context.TODO(), webhookID, delivery.ID.Hex()))
+	due, err = db.DueWebhookDeliveries(context.TODO(), time.Now().Add(time.Second), 10)
+	require.NoError(err)
+	require.Len(due, 1, "Replayed delivery should be due")
+	require.Zero(due[0].Attempts)
+
+	err = db.ReplayWebhookDelivery(context.TODO(), primitive.NewObjectID().Hex(), delivery.ID.Hex())
+	require.True(errors.Is(err, mongo.ErrNoDocuments), "Delivery of other webhook should not be replayed")
+
+	require.NoError(db.DeleteWebhook(context.TODO(), webhookID))
+	err = db.DeleteWebhook(context.TODO(), webhookID)
+	require.True(errors.Is(err, mongo.ErrNoDocuments))
+	_, err = db.GetWebhook(context.TODO(), webhookID)
+	require.True(errors.Is(err, mongo.ErrNoDocuments))
+
+	cleanUp(t)
+}
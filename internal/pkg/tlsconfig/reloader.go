@@ -0,0 +1,103 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCheckInterval is how often Reloader looks for changed files.
+const DefaultCheckInterval = 10 * time.Second
+
+// Reloader serves certificate loaded from files and reloads it after
+// files are modified, so certificates can be rotated without restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewReloader loads certificate from files and returns reloader serving it.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: DefaultCheckInterval,
+		now:      time.Now,
+	}
+
+	modTime, err := r.modified()
+	if err != nil {
+		return nil, err
+	}
+	if err = r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. Files are checked at most
+// once per interval. If changed files can't be loaded, previous certificate
+// is kept and reload is retried on next check.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checked) < r.interval {
+		return r.cert, nil
+	}
+	r.checked = now
+
+	modTime, err := r.modified()
+	if err == nil && modTime.After(r.modTime) {
+		err = r.load(modTime)
+		if err == nil {
+			slog.Info("TLS certificate reloaded", "cert_file", r.certFile)
+		}
+	}
+	if err != nil {
+		slog.Warn("error reloading TLS certificate", "cert_file", r.certFile, "err", err)
+	}
+
+	return r.cert, nil
+}
+
+// load must be called with mu held or before r is shared.
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load certificate")
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// modified returns latest modification time of certificate and key files.
+func (r *Reloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "stat certificate file")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,165 @@
+// Package tlsconfig builds TLS configuration for servers and clients
+// from certificate files, reloading server certificate when files change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Client authentication modes.
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// Config describes server side TLS. TLS is disabled if CertFile is empty.
+type Config struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile is PEM bundle of CAs client certificates are verified
+	// against. Setting it enables mutual TLS.
+	ClientCAFile string `yaml:"client_ca_file"`
+
+	// ClientAuth is one of none, optional, require. Defaults to require
+	// if ClientCAFile is set and to none otherwise.
+	ClientAuth string `yaml:"client_auth"`
+}
+
+// Enabled reports if TLS is configured.
+func (c Config) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate checks that files and client authentication mode are consistent.
+func (c Config) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+	if c.ClientCAFile != "" && !c.Enabled() {
+		return errors.New("client_ca_file requires cert_file")
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			return errors.Errorf("client_auth %s requires client_ca_file", c.ClientAuth)
+		}
+	default:
+		return errors.Errorf("unknown client_auth %q", c.ClientAuth)
+	}
+
+	return nil
+}
+
+// NewServer creates server TLS config which serves certificate reloaded
+// from disk on change. It returns nil config if TLS is not enabled.
+func NewServer(c Config) (*tls.Config, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	reloader, err := NewReloader(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if c.ClientCAFile == "" {
+		return conf, nil
+	}
+
+	if conf.ClientCAs, err = loadPool(c.ClientCAFile); err != nil {
+		return nil, err
+	}
+	switch c.ClientAuth {
+	case ClientAuthNone:
+		conf.ClientAuth = tls.NoClientCert
+	case ClientAuthOptional:
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+// ClientConfig describes client side TLS.
+type ClientConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// CAFile is PEM bundle of CAs server certificate is verified against.
+	// System pool is used if it is empty.
+	CAFile string `yaml:"ca_file"`
+
+	// CertFile and KeyFile hold client certificate for mutual TLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// Validate checks that client certificate files are set together.
+func (c ClientConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+
+	return nil
+}
+
+// NewClient creates client TLS config. It returns nil config if TLS is not enabled.
+func NewClient(c ClientConfig) (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// only meant for development setups with self-signed certificates
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	var err error
+	if c.CAFile != "" {
+		if conf.RootCAs, err = loadPool(c.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate")
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "read CA file")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no certificates found in %s", file)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,185 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type issuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newCA creates self-signed certificate authority.
+func newCA(t *testing.T) *issuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sts-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &issuer{cert: cert, key: key}
+}
+
+// issue writes certificate for commonName signed by ca and its key to dir.
+func (ca *issuer) issue(t *testing.T, dir, commonName string, serial int64) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func (ca *issuer) write(t *testing.T, dir string) string {
+	file := filepath.Join(dir, "ca.crt")
+	writePEM(t, file, "CERTIFICATE", ca.cert.Raw)
+	return file
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", 2)
+	require := require.New(t)
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(err)
+	require.Equal(int64(2), leafSerial(t, cert))
+
+	// rotated files are picked up only after check interval passes
+	_, _ = ca.issue(t, dir, "server", 3)
+	later := time.Now().Add(time.Minute)
+	require.NoError(os.Chtimes(certFile, later, later))
+	cert, err = r.GetCertificate(nil)
+	require.NoError(err)
+	require.Equal(int64(2), leafSerial(t, cert), "Certificate should be cached")
+
+	now = now.Add(DefaultCheckInterval)
+	cert, err = r.GetCertificate(nil)
+	require.NoError(err)
+	require.Equal(int64(3), leafSerial(t, cert), "Certificate should be reloaded")
+
+	// broken files keep previous certificate
+	require.NoError(os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	evenLater := later.Add(time.Minute)
+	require.NoError(os.Chtimes(keyFile, evenLater, evenLater))
+	now = now.Add(DefaultCheckInterval)
+	cert, err = r.GetCertificate(nil)
+	require.NoError(err)
+	require.Equal(int64(3), leafSerial(t, cert), "Previous certificate should be kept")
+}
+
+func leafSerial(t *testing.T, cert *tls.Certificate) int64 {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.SerialNumber.Int64()
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+	caFile := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "localhost", 2)
+	clientCert, clientKey := ca.issue(t, dir, "billing", 3)
+	require := require.New(t)
+
+	serverTLS, err := NewServer(Config{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: caFile})
+	require.NoError(err)
+	require.Equal(tls.RequireAndVerifyClientCert, serverTLS.ClientAuth)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+		}),
+		TLSConfig:         serverTLS,
+		ReadHeaderTimeout: time.Second,
+		ErrorLog:          log.New(io.Discard, "", 0),
+	}
+	go func() { _ = srv.ServeTLS(l, "", "") }()
+	defer srv.Close()
+	url := "https://localhost:" + strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	clientTLS, err := NewClient(ClientConfig{Enabled: true, CAFile: caFile, CertFile: clientCert, KeyFile: clientKey})
+	require.NoError(err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	resp, err := client.Get(url)
+	require.NoError(err)
+	defer resp.Body.Close()
+	body := make([]byte, 16)
+	n, _ := resp.Body.Read(body)
+	require.Equal("billing", string(body[:n]), "Server should see client certificate")
+
+	anonymousTLS, err := NewClient(ClientConfig{Enabled: true, CAFile: caFile})
+	require.NoError(err)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: anonymousTLS}}
+	_, err = anonymous.Get(url)
+	require.Error(err, "Client without certificate should be rejected")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require := require.New(t)
+	require.NoError(Config{}.Validate())
+	require.Error(Config{CertFile: "tls.crt"}.Validate())
+	require.Error(Config{ClientCAFile: "ca.crt"}.Validate())
+	require.Error(Config{CertFile: "tls.crt", KeyFile: "tls.key", ClientAuth: ClientAuthRequire}.Validate())
+	require.Error(Config{CertFile: "tls.crt", KeyFile: "tls.key", ClientAuth: "always"}.Validate())
+	require.Error(ClientConfig{Enabled: true, KeyFile: "client.key"}.Validate())
+}
@@ -0,0 +1,130 @@
+// Package tracing configures OpenTelemetry trace exporter and propagation.
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// DefaultServiceName is reported as service.name resource attribute
+// when Config.ServiceName is empty.
+const DefaultServiceName = "social-tournament-service"
+
+// Config describes where traces are exported.
+type Config struct {
+	// Exporter is one of none, stdout, otlp. Defaults to none.
+	Exporter string `yaml:"exporter"`
+
+	// Endpoint is host:port of OTLP gRPC collector. Used only by otlp exporter.
+	Endpoint string `yaml:"endpoint"`
+
+	// Insecure disables TLS for connection to OTLP collector.
+	Insecure bool `yaml:"insecure"`
+
+	// SampleRatio is fraction of traces sampled in range (0, 1]. Defaults to 1.
+	SampleRatio float64 `yaml:"sample_ratio"`
+
+	// ServiceName overrides DefaultServiceName.
+	ServiceName string `yaml:"service_name"`
+}
+
+// ShutdownFunc flushes pending spans and stops exporter.
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs global tracer provider and W3C trace context propagator
+// according to cfg. Returned function must be called on shutdown.
+// With none exporter global provider stays no-op.
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(cfg.Exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		if cfg.Endpoint == "" {
+			return nil, errors.New("otlp exporter requires endpoint")
+		}
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, errors.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "create %s exporter", cfg.Exporter)
+	}
+
+	provider := NewProvider(cfg, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates tracer provider with service resource and sampler from cfg.
+func NewProvider(cfg Config, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}, opts...)
+
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// CommandMonitor returns MongoDB command monitor which creates span for every
+// command and passes events further to provided monitors, e.g. logging one.
+func CommandMonitor(next ...*event.CommandMonitor) *event.CommandMonitor {
+	monitors := append([]*event.CommandMonitor{otelmongo.NewMonitor()}, next...)
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	require := require.New(t)
+
+	_, err := Setup(context.TODO(), Config{Exporter: "zipkin"})
+	require.EqualError(err, `unknown trace exporter "zipkin"`)
+
+	_, err = Setup(context.TODO(), Config{Exporter: "otlp"})
+	require.EqualError(err, "otlp exporter requires endpoint")
+
+	shutdown, err := Setup(context.TODO(), Config{})
+	require.NoError(err)
+	require.NoError(shutdown(context.TODO()))
+
+	defaultProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(defaultProvider)
+
+	shutdown, err = Setup(context.TODO(), Config{Exporter: "stdout"})
+	require.NoError(err)
+	require.NoError(shutdown(context.TODO()))
+}
+
+func TestCommandMonitor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := NewProvider(Config{}, sdktrace.WithSpanProcessor(recorder))
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(defaultProvider)
+
+	var started, succeeded int
+	monitor := CommandMonitor(&event.CommandMonitor{
+		Started: func(context.Context, *event.CommandStartedEvent) {
+			started++
+		},
+		Succeeded: func(context.Context, *event.CommandSucceededEvent) {
+			succeeded++
+		},
+	})
+
+	monitor.Started(context.TODO(), &event.CommandStartedEvent{
+		CommandName:  "find",
+		DatabaseName: "sts",
+		RequestID:    1,
+		ConnectionID: "localhost:27017[-1]",
+	})
+	monitor.Succeeded(context.TODO(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{
+			CommandName:  "find",
+			RequestID:    1,
+			ConnectionID: "localhost:27017[-1]",
+		},
+	})
+	monitor.Failed(context.TODO(), &event.CommandFailedEvent{})
+
+	require := require.New(t)
+	require.Equal(1, started, "Next monitor should receive started event")
+	require.Equal(1, succeeded, "Next monitor should receive succeeded event")
+	require.Len(recorder.Ended(), 1)
+	require.Equal("find", recorder.Ended()[0].Name())
+}
@@ -0,0 +1,172 @@
+// Package validation holds request validation rules shared by HTTP handlers and gRPC service.
+package validation
+
+import (
+	"math"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	MaxNameLength     = 64
+	MinLoginLength    = 3
+	MaxLoginLength    = 32
+	MinPasswordLength = 8
+	// MaxPasswordLength is counted in bytes, bcrypt ignores the rest of longer passwords.
+	MaxPasswordLength = 72
+	MaxURLLength      = 2048
+	MaxEmailLength    = 254
+)
+
+// FieldError describes why value of single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors. It implements error interface.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// GRPCStatus converts field errors to InvalidArgument status with BadRequest details.
+func (e Errors) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, e.Error())
+	br := &errdetails.BadRequest{}
+	for _, fe := range e {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+		})
+	}
+
+	detailed, err := st.WithDetails(br)
+	if err != nil {
+		return st
+	}
+
+	return detailed
+}
+
+// Validator collects field errors. Zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Err returns collected errors or nil if all checks passed.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs
+}
+
+// Add records error for provided field.
+func (v *Validator) Add(field, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: message})
+}
+
+// Name checks that value is non-empty display name of limited length
+// consisting of letters, digits, spaces and "-_.'" characters.
+func (v *Validator) Name(field, value string) {
+	switch {
+	case value == "":
+		v.Add(field, "must not be empty")
+	case utf8.RuneCountInString(value) > MaxNameLength:
+		v.Add(field, "must be at most 64 characters long")
+	case strings.TrimSpace(value) != value:
+		v.Add(field, "must not start or end with space")
+	case strings.IndexFunc(value, invalidNameRune) >= 0:
+		v.Add(field, "must contain only letters, digits, spaces and -_.' characters")
+	}
+}
+
+// Login checks that value is latin letters, digits and "-_." characters of limited length.
+func (v *Validator) Login(field, value string) {
+	switch {
+	case len(value) < MinLoginLength || len(value) > MaxLoginLength:
+		v.Add(field, "must be from 3 to 32 characters long")
+	case strings.IndexFunc(value, invalidLoginRune) >= 0:
+		v.Add(field, "must contain only latin letters, digits and -_. characters")
+	}
+}
+
+// Password checks password length. Maximum length is checked in bytes,
+// since only that many of them are hashed.
+func (v *Validator) Password(field, value string) {
+	if utf8.RuneCountInString(value) < MinPasswordLength || len(value) > MaxPasswordLength {
+		v.Add(field, "must be from 8 characters to 72 bytes long")
+	}
+}
+
+// Amount checks that value is positive finite number.
+func (v *Validator) Amount(field string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value <= 0 {
+		v.Add(field, "must be positive finite number")
+	}
+}
+
+// Deposit checks that value is non-negative finite number.
+func (v *Validator) Deposit(field string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		v.Add(field, "must be non-negative finite number")
+	}
+}
+
+// ObjectID checks that value is hex representation of MongoDB ObjectID.
+func (v *Validator) ObjectID(field, value string) {
+	if !primitive.IsValidObjectID(value) {
+		v.Add(field, "must be 24 characters hex string")
+	}
+}
+
+// URL checks that value is absolute http or https URL of limited length.
+func (v *Validator) URL(field, value string) {
+	if len(value) > MaxURLLength {
+		v.Add(field, "must be at most 2048 characters long")
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Add(field, "must be absolute http or https URL")
+	}
+}
+
+// Email checks that value is bare email address like user@example.com
+// without display name or comments.
+func (v *Validator) Email(field, value string) {
+	if len(value) > MaxEmailLength {
+		v.Add(field, "must be at most 254 characters long")
+		return
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || addr.Name != "" {
+		v.Add(field, "must be email address")
+	}
+}
+
+func invalidNameRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.'", r)
+}
+
+func invalidLoginRune(r rune) bool {
+	return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r))
+}
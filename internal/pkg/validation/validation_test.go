@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func TestValidator(t *testing.T) {
+	require := require.New(t)
+
+	var v Validator
+	v.Name("name", "Gennadiy O'Neil-2")
+	v.Login("login", "gena_2.0")
+	v.Password("password", "secret-password")
+	v.Amount("points", 100)
+	v.Deposit("deposit", 0)
+	v.ObjectID("id", primitive.NewObjectID().Hex())
+	v.URL("url", "https://partner.example/hook?key=1")
+	v.Email("email", "player@example.com")
+	require.NoError(v.Err())
+
+	v = Validator{}
+	v.Name("name", "")
+	v.Name("nick", strings.Repeat("a", MaxNameLength+1))
+	v.Name("title", " padded")
+	v.Name("alias", "<script>")
+	v.Login("login", "ge")
+	v.Login("login2", "гена")
+	v.Password("password", "short")
+	v.Password("password2", strings.Repeat("я", 37))
+	v.Amount("points", -1)
+	v.Amount("nan", math.NaN())
+	v.Amount("inf", math.Inf(1))
+	v.Deposit("deposit", -1)
+	v.ObjectID("id", "garbage")
+	v.URL("url", "ftp://partner.example")
+	v.URL("path", "/hook")
+	v.Email("email", "Player <player@example.com>")
+	v.Email("email2", "player")
+
+	errs, ok := v.Err().(Errors)
+	require.True(ok, "Err should return Errors")
+
+	fields := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, fe.Field)
+	}
+	require.Equal([]string{"name", "nick", "title", "alias", "login", "login2", "password",
+		"password2", "points", "nan", "inf", "deposit", "id", "url", "path", "email", "email2"}, fields, "The two field lists should be the same")
+}
+
+func TestErrors_GRPCStatus(t *testing.T) {
+	errs := Errors{{Field: "points", Message: "must be positive finite number"}}
+	st := errs.GRPCStatus()
+
+	require := require.New(t)
+	require.Equal(codes.InvalidArgument, st.Code())
+	require.Len(st.Details(), 1)
+
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(ok, "Detail should be BadRequest")
+	require.Equal("points", br.FieldViolations[0].Field)
+}
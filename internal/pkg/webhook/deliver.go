@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// lockName is name of lease which lets single instance send deliveries.
+	lockName = "webhooks"
+
+	// maxResponseSize limits part of response body read before connection is reused.
+	maxResponseSize = 4 << 10
+)
+
+// Config configures delivery worker.
+type Config struct {
+	// PollInterval is delay between checks for due deliveries.
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// BatchSize is number of deliveries read at once.
+	BatchSize int64 `yaml:"batch_size"`
+
+	// Timeout limits single delivery attempt.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxAttempts is number of failed attempts after which
+	// delivery is moved to dead letters until replayed by hand.
+	MaxAttempts int `yaml:"max_attempts"`
+
+	// RetryBackoff is delay after the first failed attempt.
+	// It doubles with every next failure up to MaxBackoff.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	MaxBackoff   time.Duration `yaml:"max_backoff"`
+
+	// LockTTL is lease duration. It's renewed after every attempt, so it must exceed Timeout.
+	LockTTL time.Duration `yaml:"lock_ttl"`
+
+	// AllowedHosts are hosts, with or without port, webhooks may be delivered to
+	// over http and at loopback or private addresses, e.g. localhost:9000 in
+	// development. Other webhooks must be https URLs of public hosts.
+	AllowedHosts []string `yaml:"allowed_hosts"`
+}
+
+// DefaultConfig returns config used when webhooks section is omitted.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		RetryBackoff: 30 * time.Second,
+		MaxBackoff:   time.Hour,
+		LockTTL:      30 * time.Second,
+	}
+}
+
+// Deliverer posts due deliveries to webhook URLs. Failed delivery is retried
+// with exponential backoff and becomes dead after MaxAttempts failures.
+// Deliveries of the same event are independent, so one failing partner
+// doesn't hold up the others.
+type Deliverer struct {
+	store  Store
+	conf   Config
+	policy *URLPolicy
+	client *http.Client
+	owner  string
+	now    func() time.Time
+}
+
+// NewDeliverer creates worker sending deliveries of store. Redirects
+// aren't followed, receiver must answer with 2xx status itself. Deliveries
+// are sent only to URLs allowed by policy built from conf.AllowedHosts.
+func NewDeliverer(store Store, conf Config) *Deliverer {
+	defaults := DefaultConfig()
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaults.PollInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaults.BatchSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaults.Timeout
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = defaults.MaxAttempts
+	}
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = defaults.RetryBackoff
+	}
+	if conf.MaxBackoff < conf.RetryBackoff {
+		conf.MaxBackoff = conf.RetryBackoff
+	}
+	if conf.LockTTL <= conf.Timeout {
+		conf.LockTTL = conf.Timeout + defaults.LockTTL
+	}
+
+	policy := NewURLPolicy(conf.AllowedHosts)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// proxy would hide address of receiver from policy
+	transport.Proxy = nil
+	transport.DialContext = policy.DialContext
+
+	host, _ := os.Hostname()
+	return &Deliverer{
+		store:  store,
+		conf:   conf,
+		policy: policy,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   conf.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		owner: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+		now:   time.Now,
+	}
+}
+
+// Run sends deliveries until ctx is done. Only instance holding lease
+// sends them, others wait for it to expire.
+func (d *Deliverer) Run(ctx context.Context) {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), d.conf.PollInterval)
+		defer cancel()
+		if err := d.store.ReleaseLock(ctx, lockName, d.owner); err != nil {
+			slog.Error("error releasing webhooks lock", "err", err)
+		}
+	}()
+
+	ticker := time.NewTicker(d.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.Deliver(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error sending webhook deliveries", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver makes attempt of every due delivery and returns number of attempts.
+// Failed attempts are rescheduled, so error is returned only if store fails.
+// Nothing is sent if lease is held by other instance.
+func (d *Deliverer) Deliver(ctx context.Context) (int, error) {
+	attempts := 0
+	webhooks := make(map[primitive.ObjectID]*storage.Webhook)
+	for {
+		ok, err := d.store.AcquireLock(ctx, lockName, d.owner, d.conf.LockTTL)
+		if err != nil {
+			return attempts, errors.Wrap(err, "acquire lock")
+		}
+		if !ok {
+			return attempts, nil
+		}
+
+		due, err := d.store.DueWebhookDeliveries(ctx, d.now(), d.conf.BatchSize)
+		if err != nil {
+			return attempts, errors.Wrap(err, "read due deliveries")
+		}
+
+		for i := range due {
+			delivery := &due[i]
+			webhook, ok := webhooks[delivery.WebhookID]
+			if !ok {
+				webhook, err = d.store.GetWebhook(ctx, delivery.WebhookID.Hex())
+				if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+					return attempts, errors.Wrapf(err, "get webhook %s", delivery.WebhookID.Hex())
+				}
+				webhooks[delivery.WebhookID] = webhook
+			}
+
+			if webhook == nil {
+				delivery.Status = storage.DeliveryDead
+				delivery.LastStatus = 0
+				delivery.LastError = "webhook is deleted"
+			} else {
+				d.attempt(ctx, webhook, delivery)
+				attempts++
+			}
+			if err = d.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+				return attempts, errors.Wrapf(err, "update delivery %s", delivery.ID.Hex())
+			}
+			// lease lost in the middle of batch means other instance took over
+			if ok, err = d.store.AcquireLock(ctx, lockName, d.owner, d.conf.LockTTL); err != nil || !ok {
+				return attempts, errors.Wrap(err, "renew lock")
+			}
+		}
+
+		if int64(len(due)) < d.conf.BatchSize {
+			return attempts, nil
+		}
+	}
+}
+
+// attempt posts delivery to webhook and updates delivery with result.
+func (d *Deliverer) attempt(ctx context.Context, webhook *storage.Webhook, delivery *storage.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.LastStatus, delivery.LastError = 0, ""
+
+	err := d.post(ctx, webhook, delivery)
+	now := d.now()
+	switch {
+	case err == nil:
+		delivery.Status = storage.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		return
+	case delivery.Attempts >= d.conf.MaxAttempts:
+		delivery.Status = storage.DeliveryDead
+	default:
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+	delivery.LastError = err.Error()
+	slog.WarnContext(ctx, "webhook delivery failed", "webhook", webhook.ID.Hex(),
+		"delivery", delivery.ID.Hex(), "attempts", delivery.Attempts, "status", delivery.Status, "err", err)
+}
+
+func (d *Deliverer) post(ctx context.Context, webhook *storage.Webhook, delivery *storage.WebhookDelivery) error {
+	// webhook may be registered before policy was tightened
+	if err := d.policy.Check(ctx, webhook.URL); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return errors.Wrap(err, "encode event")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	delivery.LastStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// backoff returns delay after provided number of failed attempts.
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	delay := d.conf.RetryBackoff
+	for i := 1; i < attempts && delay < d.conf.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.conf.MaxBackoff {
+		delay = d.conf.MaxBackoff
+	}
+
+	return delay
+}
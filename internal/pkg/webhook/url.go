@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ErrForbiddenURL is returned for webhook URL which isn't https or whose host
+// resolves to loopback, private or link-local address.
+var ErrForbiddenURL = errors.New("webhook URL must be https and resolve to public address")
+
+// URLPolicy decides which URLs deliveries may be sent to, so that webhooks
+// can't be used to reach the service itself or internal network.
+type URLPolicy struct {
+	allowed map[string]bool
+	lookup  func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewURLPolicy creates policy allowing https URLs of public hosts. Hosts
+// listed in allowedHosts, as host or host:port, are allowed over http and
+// at any address, e.g. localhost:9000 in development.
+func NewURLPolicy(allowedHosts []string) *URLPolicy {
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, host := range allowedHosts {
+		allowed[host] = true
+	}
+
+	return &URLPolicy{allowed: allowed, lookup: net.DefaultResolver.LookupIPAddr}
+}
+
+// Check returns error wrapping ErrForbiddenURL if deliveries must not be sent to rawURL.
+func (p *URLPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(ErrForbiddenURL, err.Error())
+	}
+	if p.allowedHost(u.Host) {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return errors.Wrapf(ErrForbiddenURL, "scheme %s", u.Scheme)
+	}
+
+	addrs, err := p.lookup(ctx, u.Hostname())
+	if err != nil {
+		return errors.Wrapf(ErrForbiddenURL, "resolve %s: %s", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return errors.Wrapf(ErrForbiddenURL, "%s resolves to %s", u.Hostname(), addr.IP)
+		}
+	}
+
+	return nil
+}
+
+// DialContext dials addr like net.Dialer, but refuses to connect to
+// non-public addresses unless host of addr is allowed. Address is checked
+// after resolution, so host can't be pointed to internal network after Check.
+func (p *URLPolicy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	if !p.allowedHost(addr) {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errors.Wrapf(ErrForbiddenURL, "connect to %s", host)
+			}
+			return nil
+		}
+	}
+
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// allowedHost reports whether hostport, with or without port, is allowed.
+func (p *URLPolicy) allowedHost(hostport string) bool {
+	if p.allowed[hostport] {
+		return true
+	}
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+
+	return p.allowed[host]
+}
+
+// publicIP reports whether ip is routable address outside of service network.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,159 @@
+// Package webhook delivers domain events to partner URLs. Every delivery is
+// signed with HMAC-SHA256 of subscription secret, so partners can check that
+// callback comes from the service.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+const (
+	// SignatureHeader carries delivery signature in "t=<unix time>,v1=<hex HMAC>" format.
+	// HMAC-SHA256 is computed over "<unix time>.<body>" with subscription secret.
+	SignatureHeader = "X-Webhook-Signature"
+
+	// EventHeader carries type of delivered event.
+	EventHeader = "X-Webhook-Event"
+
+	// DeliveryHeader carries delivery id. It's the same for all attempts,
+	// so receivers can drop duplicates.
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	// secretSize is number of random bytes in generated secret.
+	secretSize = 32
+)
+
+// ErrInvalidSignature is returned by Verify if signature doesn't match body or is too old.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Store is part of storage.DB webhooks work with.
+type Store interface {
+	AddWebhook(ctx context.Context, webhook storage.Webhook) (string, error)
+	GetWebhook(ctx context.Context, id string) (*storage.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	AddWebhookDelivery(ctx context.Context, webhookID primitive.ObjectID, e storage.OutboxEvent) error
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int64) ([]storage.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d *storage.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, status storage.DeliveryStatus,
+		limit int64) ([]storage.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, webhookID, id string) error
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// Webhooks manages subscriptions and queues deliveries of their events.
+type Webhooks struct {
+	Store
+	policy *URLPolicy
+}
+
+// New creates webhooks kept in store. Only URLs allowed by policy are registered.
+func New(store Store, policy *URLPolicy) *Webhooks {
+	return &Webhooks{Store: store, policy: policy}
+}
+
+// Register adds subscription of url to events of provided types, all events
+// if types are empty. Random secret is generated if secret is empty.
+// It returns error wrapping ErrForbiddenURL if url isn't allowed by policy.
+func (w *Webhooks) Register(ctx context.Context, url string, types []storage.EventType,
+	secret, createdBy string) (*storage.Webhook, error) {
+	if err := w.policy.Check(ctx, url); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		buf := make([]byte, secretSize)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, errors.Wrap(err, "generate secret")
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := storage.Webhook{
+		URL:        url,
+		EventTypes: types,
+		Secret:     secret,
+		CreatedBy:  createdBy,
+	}
+	id, err := w.AddWebhook(ctx, webhook)
+	if err != nil {
+		return nil, errors.Wrap(err, "add webhook")
+	}
+
+	return w.GetWebhook(ctx, id)
+}
+
+// Enqueue queues delivery of event to every subscription accepting it.
+// It's outbox.Handler, so events are queued in order they happened.
+func (w *Webhooks) Enqueue(ctx context.Context, e storage.OutboxEvent) error {
+	webhooks, err := w.ListWebhooks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list webhooks")
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Accepts(e.Type) {
+			continue
+		}
+		if err = w.AddWebhookDelivery(ctx, webhook.ID, e); err != nil {
+			return errors.Wrapf(err, "add delivery to webhook %s", webhook.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// Sign returns SignatureHeader value for body sent at provided time.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify checks SignatureHeader value of body received at provided time.
+// Signatures made more than tolerance ago are rejected to limit replays.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	sum, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,439 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/HarlamovBuldog/social-tournament-service/internal/pkg/storage"
+)
+
+// memoryStore keeps webhooks, deliveries and single lease in memory.
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   []storage.Webhook
+	deliveries []storage.WebhookDelivery
+	lockOwner  string
+}
+
+func (s *memoryStore) AddWebhook(_ context.Context, webhook storage.Webhook) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook.ID = primitive.NewObjectID()
+	s.webhooks = append(s.webhooks, webhook)
+	return webhook.ID.Hex(), nil
+}
+
+func (s *memoryStore) GetWebhook(_ context.Context, id string) (*storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, webhook := range s.webhooks {
+		if webhook.ID.Hex() == id {
+			return &webhook, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) ListWebhooks(context.Context) ([]storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]storage.Webhook{}, s.webhooks...), nil
+}
+
+func (s *memoryStore) DeleteWebhook(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, webhook := range s.webhooks {
+		if webhook.ID.Hex() == id {
+			s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) AddWebhookDelivery(_ context.Context, webhookID primitive.ObjectID, e storage.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID && d.Event.ID == e.ID {
+			return nil
+		}
+	}
+	s.deliveries = append(s.deliveries, storage.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: webhookID,
+		Event:     e,
+		Status:    storage.DeliveryPending,
+	})
+	return nil
+}
+
+func (s *memoryStore) DueWebhookDeliveries(_ context.Context, now time.Time, limit int64) ([]storage.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []storage.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(now) && int64(len(due)) < limit {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	return due, nil
+}
+
+func (s *memoryStore) UpdateWebhookDelivery(_ context.Context, d *storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == d.ID {
+			s.deliveries[i] = *d
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) ListWebhookDeliveries(_ context.Context, webhookID string, status storage.DeliveryStatus,
+	limit int64) ([]storage.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []storage.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0 && int64(len(deliveries)) < limit; i-- {
+		d := s.deliveries[i]
+		if d.WebhookID.Hex() == webhookID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) ReplayWebhookDelivery(_ context.Context, webhookID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if d.ID.Hex() == id && d.WebhookID.Hex() == webhookID {
+			d.Status, d.Attempts, d.NextAttemptAt, d.DeliveredAt = storage.DeliveryPending, 0, time.Time{}, nil
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (s *memoryStore) AcquireLock(_ context.Context, _, owner string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockOwner != "" && s.lockOwner != owner {
+		return false, nil
+	}
+	s.lockOwner = owner
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseLock(_ context.Context, _, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockOwner == owner {
+		s.lockOwner = ""
+	}
+	return nil
+}
+
+// receiver is httptest server verifying signatures of received events.
+type receiver struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	events []storage.OutboxEvent
+	ids    []string
+	errs   []error
+}
+
+// testHosts are allowed to receive deliveries in tests.
+var testHosts = []string{"127.0.0.1", "partner.example"}
+
+func newReceiver(secret string) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		body, err := io.ReadAll(req.Body)
+		if err == nil {
+			err = Verify(secret, req.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+		}
+		var e storage.OutboxEvent
+		if err == nil {
+			err = json.Unmarshal(body, &e)
+		}
+		if err == nil && req.Header.Get(EventHeader) != string(e.Type) {
+			err = errors.New("event header doesn't match body")
+		}
+		if err != nil {
+			r.errs = append(r.errs, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		r.events = append(r.events, e)
+		r.ids = append(r.ids, req.Header.Get(DeliveryHeader))
+		w.WriteHeader(r.status)
+	}))
+
+	return r
+}
+
+func (r *receiver) respond(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status = status
+}
+
+func TestSignature(t *testing.T) {
+	require := require.New(t)
+	body := []byte(`{"type":"TournamentFinished"}`)
+	now := time.Now()
+
+	header := Sign("secret", now, body)
+	require.NoError(Verify("secret", header, body, time.Minute, now.Add(30*time.Second)))
+	require.ErrorIs(Verify("other", header, body, time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(Verify("secret", header, []byte(`{}`), time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(Verify("secret", header, body, time.Minute, now.Add(2*time.Minute)), ErrInvalidSignature,
+		"Old signature should be rejected")
+	require.ErrorIs(Verify("secret", "garbage", body, time.Minute, now), ErrInvalidSignature)
+}
+
+func TestWebhooks_Enqueue(t *testing.T) {
+	store := &memoryStore{}
+	webhooks := New(store, NewURLPolicy(testHosts))
+	require := require.New(t)
+
+	finished, err := webhooks.Register(context.TODO(), "https://partner.example/finished",
+		[]storage.EventType{storage.EventTournamentFinished}, "", "admin")
+	require.NoError(err)
+	require.Len(finished.Secret, 2*secretSize, "Secret should be generated")
+	all, err := webhooks.Register(context.TODO(), "https://partner.example/all", nil, "secret", "admin")
+	require.NoError(err)
+	require.Equal("secret", all.Secret)
+
+	funded := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventBalanceFunded}
+	won := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentFinished}
+	require.NoError(webhooks.Enqueue(context.TODO(), funded))
+	require.NoError(webhooks.Enqueue(context.TODO(), won))
+	require.NoError(webhooks.Enqueue(context.TODO(), won), "Redelivered event should be queued once")
+
+	log, err := webhooks.ListWebhookDeliveries(context.TODO(), finished.ID.Hex(), "", 10)
+	require.NoError(err)
+	require.Len(log, 1)
+	require.Equal(won.ID, log[0].Event.ID)
+
+	log, err = webhooks.ListWebhookDeliveries(context.TODO(), all.ID.Hex(), "", 10)
+	require.NoError(err)
+	require.Len(log, 2)
+}
+
+func TestDeliverer(t *testing.T) {
+	store := &memoryStore{}
+	webhooks := New(store, NewURLPolicy(testHosts))
+	require := require.New(t)
+
+	r := newReceiver("secret")
+	defer r.Close()
+	webhook, err := webhooks.Register(context.TODO(), r.URL, nil, "secret", "admin")
+	require.NoError(err)
+	event := storage.OutboxEvent{ID: primitive.NewObjectID(), Type: storage.EventTournamentFinished, Amount: 50}
+	require.NoError(webhooks.Enqueue(context.TODO(), event))
+
+	now := time.Now()
+	d := NewDeliverer(store, Config{MaxAttempts: 3, RetryBackoff: time.Minute, MaxBackoff: 90 * time.Second,
+		AllowedHosts: testHosts})
+	d.now = func() time.Time { return now }
+
+	// failing receiver is retried with growing delay and then given up
+	r.respond(http.StatusInternalServerError)
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		n, err := d.Deliver(context.TODO())
+		require.NoError(err)
+		require.Equal(1, n)
+
+		n, err = d.Deliver(context.TODO())
+		require.NoError(err)
+		require.Zero(n, "Failed delivery should wait for backoff")
+
+		log, err := webhooks.ListWebhookDeliveries(context.TODO(), webhook.ID.Hex(), "", 10)
+		require.NoError(err)
+		require.Equal(i+1, log[0].Attempts)
+		require.Equal(http.StatusInternalServerError, log[0].LastStatus)
+		require.Equal("unexpected status 500", log[0].LastError)
+		delays = append(delays, log[0].NextAttemptAt.Sub(now))
+		now = log[0].NextAttemptAt
+	}
+	require.Equal([]time.Duration{time.Minute, 90 * time.Second}, delays[:2])
+
+	dead, err := webhooks.ListWebhookDeliveries(context.TODO(), webhook.ID.Hex(), storage.DeliveryDead, 10)
+	require.NoError(err)
+	require.Len(dead, 1, "Delivery should be dead after max attempts")
+	n, err := d.Deliver(context.TODO())
+	require.NoError(err)
+	require.Zero(n)
+
+	// replayed delivery is sent again with the same id
+	r.respond(http.StatusNoContent)
+	require.NoError(webhooks.ReplayWebhookDelivery(context.TODO(), webhook.ID.Hex(), dead[0].ID.Hex()))
+	n, err = d.Deliver(context.TODO())
+	require.NoError(err)
+	require.Equal(1, n)
+
+	log, err := webhooks.ListWebhookDeliveries(context.TODO(), webhook.ID.Hex(), "", 10)
+	require.NoError(err)
+	require.Equal(storage.DeliveryDelivered, log[0].Status)
+	require.Equal(1, log[0].Attempts)
+	require.NotNil(log[0].DeliveredAt)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Empty(r.errs, "Every delivery should be signed")
+	require.Len(r.events, 4)
+	require.Equal(event, r.events[3])
+	require.Equal([]string{dead[0].ID.Hex(), dead[0].ID.Hex(), dead[0].ID.Hex(), dead[0].ID.Hex()}, r.ids)
+}
+
+func TestDeliverer_DeletedWebhook(t *testing.T) {
+	store := &memoryStore{}
+	webhooks := New(store, NewURLPolicy(testHosts))
+	require := require.New(t)
+
+	webhook, err := webhooks.Register(context.TODO(), "http://127.0.0.1:1/hook", nil, "secret", "admin")
+	require.NoError(err)
+	require.NoError(webhooks.Enqueue(context.TODO(), storage.OutboxEvent{ID: primitive.NewObjectID()}))
+	require.NoError(webhooks.DeleteWebhook(context.TODO(), webhook.ID.Hex()))
+
+	d := NewDeliverer(store, Config{AllowedHosts: testHosts})
+	n, err := d.Deliver(context.TODO())
+	require.NoError(err)
+	require.Zero(n, "Delivery to deleted webhook should not be sent")
+
+	log, err := webhooks.ListWebhookDeliveries(context.TODO(), webhook.ID.Hex(), storage.DeliveryDead, 10)
+	require.NoError(err)
+	require.Len(log, 1)
+	require.Equal("webhook is deleted", log[0].LastError)
+}
+
+func TestDeliverer_Lock(t *testing.T) {
+	store := &memoryStore{}
+	webhooks := New(store, NewURLPolicy(testHosts))
+	require := require.New(t)
+
+	r := newReceiver("secret")
+	defer r.Close()
+	_, err := webhooks.Register(context.TODO(), r.URL, nil, "secret", "admin")
+	require.NoError(err)
+	require.NoError(webhooks.Enqueue(context.TODO(), storage.OutboxEvent{ID: primitive.NewObjectID()}))
+
+	first := NewDeliverer(store, Config{PollInterval: 10 * time.Millisecond, AllowedHosts: testHosts})
+	second := NewDeliverer(store, Config{AllowedHosts: testHosts})
+	ok, err := store.AcquireLock(context.TODO(), lockName, first.owner, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+
+	n, err := second.Deliver(context.TODO())
+	require.NoError(err)
+	require.Zero(n, "Instance without lease should not deliver")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		first.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.events) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	require.Empty(store.lockOwner, "Lease should be released on stop")
+}
+
+func TestURLPolicy(t *testing.T) {
+	policy := NewURLPolicy([]string{"localhost:9000"})
+	policy.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if host == "internal.example" {
+			return []net.IPAddr{{IP: net.ParseIP("203.0.113.7")}, {IP: net.ParseIP("10.0.0.7")}}, nil
+		}
+		return net.DefaultResolver.LookupIPAddr(context.TODO(), host)
+	}
+	require := require.New(t)
+
+	require.NoError(policy.Check(context.TODO(), "https://203.0.113.7/hook"))
+	require.NoError(policy.Check(context.TODO(), "http://localhost:9000/hook"), "Allowed host should be accepted")
+	for _, u := range []string{
+		"http://203.0.113.7/hook",
+		"https://127.0.0.1/hook",
+		"https://[::1]/hook",
+		"https://10.0.0.1/hook",
+		"https://192.168.1.1/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://0.0.0.0/hook",
+		"https://internal.example/hook",
+		"https://localhost:9001/hook",
+	} {
+		require.ErrorIs(policy.Check(context.TODO(), u), ErrForbiddenURL, u)
+	}
+}
+
+func TestDeliverer_ForbiddenAddress(t *testing.T) {
+	store := &memoryStore{}
+	require := require.New(t)
+
+	r := newReceiver("secret")
+	defer r.Close()
+	// webhook registered before its host was forbidden
+	id, err := store.AddWebhook(context.TODO(), storage.Webhook{URL: r.URL, Secret: "secret"})
+	require.NoError(err)
+	require.NoError(New(store, NewURLPolicy(testHosts)).Enqueue(context.TODO(),
+		storage.OutboxEvent{ID: primitive.NewObjectID()}))
+
+	d := NewDeliverer(store, Config{})
+	n, err := d.Deliver(context.TODO())
+	require.NoError(err)
+	require.Equal(1, n)
+
+	log, err := store.ListWebhookDeliveries(context.TODO(), id, "", 10)
+	require.NoError(err)
+	require.Contains(log[0].LastError, ErrForbiddenURL.Error())
+	require.Empty(r.events, "Delivery should not reach forbidden address")
+
+	_, err = d.policy.DialContext(context.TODO(), "tcp", r.Listener.Addr().String())
+	require.ErrorIs(err, ErrForbiddenURL, "Connection to forbidden address should be refused")
+}